@@ -2,6 +2,7 @@ package ast
 
 import (
 	"go/token"
+	"strings"
 )
 
 // Node is the interface for all AST node types.
@@ -10,6 +11,64 @@ type Node interface {
 	End() token.Pos // position of first character immediately after the node
 }
 
+// Comment node, a single # comment
+type Comment struct {
+	Hash token.Pos // position of "#"
+	Text string    // comment text including "#", excluding the line terminator
+}
+
+// Pos returns position of first character belong to the node
+func (c *Comment) Pos() token.Pos {
+	return c.Hash
+}
+
+// End returns position of first character immediately after the node
+func (c *Comment) End() token.Pos {
+	return token.Pos(int(c.Hash) + len(c.Text))
+}
+
+// CommentGroup node, a sequence of comments on adjacent lines with no other
+// tokens in between
+type CommentGroup struct {
+	List []*Comment // len(List) > 0
+}
+
+// Pos returns position of first character belong to the node
+func (g *CommentGroup) Pos() token.Pos {
+	return g.List[0].Pos()
+}
+
+// End returns position of first character immediately after the node
+func (g *CommentGroup) End() token.Pos {
+	return g.List[len(g.List)-1].End()
+}
+
+// Text returns the text of the comment group. The "#" markers, the first space
+// of each comment, trailing spaces and leading and trailing empty lines are
+// removed. Lines are terminated by a newline.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+
+	lines := make([]string, 0, len(g.List))
+	for _, c := range g.List {
+		line := strings.TrimPrefix(c.Text, "#")
+		line = strings.TrimPrefix(line, " ")
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
 // Query Document related-------------------------------------------------------
 
 // Definition is the interface for all definition node types:
@@ -42,7 +101,8 @@ type Type interface {
 
 // Document node
 type Document struct {
-	Defs []Definition
+	Defs     []Definition
+	Comments []*CommentGroup // all comments in source if parsed with ParseComments
 }
 
 // Pos returns position of first character belong to the node
@@ -59,6 +119,7 @@ func (d *Document) End() token.Pos {
 
 // OperationDefinition node
 type OperationDefinition struct {
+	Doc      *CommentGroup // leading comments
 	OperType Token
 	OperPos  token.Pos
 	Name     Token
@@ -66,6 +127,7 @@ type OperationDefinition struct {
 	VarDefns *VariableDefinitions
 	Directs  *Directives
 	SelSet   *SelectionSet
+	Comment  *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
@@ -85,12 +147,14 @@ func (o *OperationDefinition) defnNode() {}
 
 // FragmentDefinition node
 type FragmentDefinition struct {
+	Doc      *CommentGroup // leading comments
 	Fragment token.Pos
 	Name     Token
 	NamePos  token.Pos
 	TypeCond *TypeCondition
 	Directs  *Directives
 	SelSet   *SelectionSet
+	Comment  *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
@@ -109,12 +173,14 @@ func (f *FragmentDefinition) defnNode() {}
 
 // Field node
 type Field struct {
+	Doc     *CommentGroup // leading comments
 	Als     *Alias
 	Name    Token
 	NamePos token.Pos
 	Args    *Arguments
 	Directs *Directives
 	SelSet  *SelectionSet
+	Comment *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
@@ -143,10 +209,12 @@ func (f *Field) selectNode() {}
 
 // FragmentSpread node
 type FragmentSpread struct {
+	Doc     *CommentGroup // leading comments
 	Spread  token.Pos
 	Name    Token
 	NamePos token.Pos
 	Directs *Directives
+	Comment *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
@@ -166,10 +234,12 @@ func (f *FragmentSpread) selectNode() {}
 
 // InlineFragment node
 type InlineFragment struct {
+	Doc      *CommentGroup // leading comments
 	Spread   token.Pos
 	TypeCond *TypeCondition
 	Directs  *Directives
 	SelSet   *SelectionSet
+	Comment  *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
@@ -411,6 +481,7 @@ type VariableDefinition struct {
 	Colon   token.Pos
 	Typ     Type
 	DeflVal *DefaultValue
+	Directs *Directives
 }
 
 // Pos returns position of first character belong to the node
@@ -420,6 +491,9 @@ func (v *VariableDefinition) Pos() token.Pos {
 
 // End returns position of first character immediately after the node
 func (v *VariableDefinition) End() token.Pos {
+	if v.Directs != nil {
+		return v.Directs.End()
+	}
 	if v.DeflVal != nil {
 		return v.DeflVal.End()
 	}
@@ -529,16 +603,20 @@ func (d *Directive) End() token.Pos {
 
 // Schema node contains all definition nodes
 type Schema struct {
+	Comments     []*CommentGroup // all comments in source if parsed with ParseComments
 	Interfaces   []*InterfaceDefinition
 	Scalars      []*ScalarDefinition
 	InputObjects []*InputObjectDefinition
 	Types        []*TypeDefinition
-	Extends      []*ExtendDefinition
+	Extends      []*ExtendDefinition // type extensions
 	Directives   []*DirectiveDefinition
 	Schemas      []*SchemaDefinition
 	Enums        []*EnumDefinition
 	Unions       []*UnionDefinition
-	pos, end     token.Pos
+
+	TypeSystemExtends []*TypeSystemExtension // extensions other than type ones
+
+	pos, end token.Pos
 }
 
 // Pos returns position of first character belong to the node
@@ -551,39 +629,75 @@ func (s *Schema) End() token.Pos {
 	return token.Pos(int(s.end))
 }
 
+// Description node, the string or block string describing a definition
+type Description struct {
+	Val    Token
+	ValPos token.Pos
+	ValEnd token.Pos
+}
+
+// Pos returns position of first character belong to the node
+func (d *Description) Pos() token.Pos {
+	return d.ValPos
+}
+
+// End returns position of first character immediately after the node
+func (d *Description) End() token.Pos {
+	return d.ValEnd
+}
+
 // InterfaceDefinition node
 type InterfaceDefinition struct {
+	Doc        *CommentGroup // leading comments
+	Desc       *Description
 	Interface  token.Pos
 	Name       Token
 	NamePos    token.Pos
+	Implements *ImplementsInterfaces
 	Directs    *Directives
 	Lbrace     token.Pos
 	FieldDefns []*FieldDefinition
 	Rbrace     token.Pos
+	Comment    *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (i *InterfaceDefinition) Pos() token.Pos {
+	if i.Desc != nil {
+		return i.Desc.Pos()
+	}
 	return i.Interface
 }
 
 // End returns position of first character immediately after the node
 func (i *InterfaceDefinition) End() token.Pos {
+	if !i.Rbrace.IsValid() { // extension without fields
+		if i.Directs != nil {
+			return i.Directs.End()
+		}
+		return i.Implements.End()
+	}
 	return token.Pos(int(i.Rbrace) + 1)
 }
 
 // FieldDefinition node
 type FieldDefinition struct {
+	Doc      *CommentGroup // leading comments
+	Desc     *Description
 	Name     Token
 	NamePos  token.Pos
 	ArgDefns *ArgumentsDefinition
 	Colon    token.Pos
 	Typ      Type
 	Directs  *Directives
+	Comment  *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (f *FieldDefinition) Pos() token.Pos {
+	if f.Desc != nil {
+		return f.Desc.Pos()
+	}
 	return f.NamePos
 }
 
@@ -614,16 +728,22 @@ func (a *ArgumentsDefinition) End() token.Pos {
 
 // InputValueDefinition node
 type InputValueDefinition struct {
+	Doc     *CommentGroup // leading comments
+	Desc    *Description
 	Name    Token
 	NamePos token.Pos
 	Colon   token.Pos
 	Typ     Type
 	DeflVal *DefaultValue
 	Directs *Directives
+	Comment *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (i *InputValueDefinition) Pos() token.Pos {
+	if i.Desc != nil {
+		return i.Desc.Pos()
+	}
 	return i.NamePos
 }
 
@@ -640,14 +760,20 @@ func (i *InputValueDefinition) End() token.Pos {
 
 // ScalarDefinition node
 type ScalarDefinition struct {
+	Doc     *CommentGroup // leading comments
+	Desc    *Description
 	Scalar  token.Pos
 	Name    Token
 	NamePos token.Pos
 	Directs *Directives
+	Comment *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (s *ScalarDefinition) Pos() token.Pos {
+	if s.Desc != nil {
+		return s.Desc.Pos()
+	}
 	return s.Scalar
 }
 
@@ -661,6 +787,8 @@ func (s *ScalarDefinition) End() token.Pos {
 
 // InputObjectDefinition node
 type InputObjectDefinition struct {
+	Doc           *CommentGroup // leading comments
+	Desc          *Description
 	Input         token.Pos
 	Name          Token
 	NamePos       token.Pos
@@ -668,20 +796,29 @@ type InputObjectDefinition struct {
 	Lbrace        token.Pos
 	InputValDefns []*InputValueDefinition
 	Rbrace        token.Pos
+	Comment       *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (i *InputObjectDefinition) Pos() token.Pos {
+	if i.Desc != nil {
+		return i.Desc.Pos()
+	}
 	return i.Input
 }
 
 // End returns position of first character immediately after the node
 func (i *InputObjectDefinition) End() token.Pos {
+	if !i.Rbrace.IsValid() { // extension without fields
+		return i.Directs.End()
+	}
 	return token.Pos(int(i.Rbrace) + 1)
 }
 
 // TypeDefinition node
 type TypeDefinition struct {
+	Doc        *CommentGroup // leading comments
+	Desc       *Description
 	Typ        token.Pos
 	Name       Token
 	NamePos    token.Pos
@@ -690,21 +827,32 @@ type TypeDefinition struct {
 	Lbrace     token.Pos
 	FieldDefns []*FieldDefinition
 	Rbrace     token.Pos
+	Comment    *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (t *TypeDefinition) Pos() token.Pos {
+	if t.Desc != nil {
+		return t.Desc.Pos()
+	}
 	return t.Typ
 }
 
 // End returns position of first character immediately after the node
 func (t *TypeDefinition) End() token.Pos {
+	if !t.Rbrace.IsValid() { // extension without fields
+		if t.Directs != nil {
+			return t.Directs.End()
+		}
+		return t.Implements.End()
+	}
 	return token.Pos(int(t.Rbrace) + 1)
 }
 
 // ImplementsInterfaces node
 type ImplementsInterfaces struct {
 	Implements token.Pos
+	Amps       []token.Pos // positions of "&" separating the types, if any
 	NamedTyps  []*NamedType
 }
 
@@ -720,8 +868,10 @@ func (i *ImplementsInterfaces) End() token.Pos {
 
 // ExtendDefinition node
 type ExtendDefinition struct {
+	Doc     *CommentGroup // leading comments
 	Extend  token.Pos
 	TypDefn *TypeDefinition
+	Comment *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
@@ -734,19 +884,46 @@ func (e *ExtendDefinition) End() token.Pos {
 	return e.TypDefn.End()
 }
 
+// TypeSystemExtension node extends the definition Defn, which is one of
+// *SchemaDefinition, *ScalarDefinition, *InterfaceDefinition, *UnionDefinition,
+// *EnumDefinition and *InputObjectDefinition, parsed with its body optional.
+type TypeSystemExtension struct {
+	Doc     *CommentGroup // leading comments
+	Extend  token.Pos
+	Defn    Node
+	Comment *CommentGroup // trailing comments on the same line
+}
+
+// Pos returns position of first character belong to the node
+func (e *TypeSystemExtension) Pos() token.Pos {
+	return e.Extend
+}
+
+// End returns position of first character immediately after the node
+func (e *TypeSystemExtension) End() token.Pos {
+	return e.Defn.End()
+}
+
 //DirectiveDefinition node
 type DirectiveDefinition struct {
+	Doc     *CommentGroup // leading comments
+	Desc    *Description
 	Direct  token.Pos
 	At      token.Pos
 	Name    Token
 	NamePos token.Pos
 	Args    *ArgumentsDefinition
+	Repeat  token.Pos // position of "repeatable", invalid if not repeatable
 	On      token.Pos
 	Locs    *DirectiveLocations
+	Comment *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (d *DirectiveDefinition) Pos() token.Pos {
+	if d.Desc != nil {
+		return d.Desc.Pos()
+	}
 	return d.Direct
 }
 
@@ -794,20 +971,29 @@ func (d *DirectiveLocation) End() token.Pos {
 
 // SchemaDefinition node
 type SchemaDefinition struct {
+	Doc       *CommentGroup // leading comments
+	Desc      *Description
 	Schema    token.Pos
 	Directs   *Directives
 	Lbrace    token.Pos
 	OperDefns []*OperationTypeDefinition
 	Rbrace    token.Pos
+	Comment   *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (s *SchemaDefinition) Pos() token.Pos {
+	if s.Desc != nil {
+		return s.Desc.Pos()
+	}
 	return s.Schema
 }
 
 // End returns position of first character immediately after the node
 func (s *SchemaDefinition) End() token.Pos {
+	if !s.Rbrace.IsValid() { // extension without operation types
+		return s.Directs.End()
+	}
 	return token.Pos(int(s.Rbrace) + 1)
 }
 
@@ -831,6 +1017,8 @@ func (o *OperationTypeDefinition) End() token.Pos {
 
 // EnumDefinition node
 type EnumDefinition struct {
+	Doc      *CommentGroup // leading comments
+	Desc     *Description
 	Enum     token.Pos
 	Name     Token
 	NamePos  token.Pos
@@ -838,27 +1026,40 @@ type EnumDefinition struct {
 	Lbrace   token.Pos
 	EnumVals []*EnumValue
 	Rbrace   token.Pos
+	Comment  *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (e *EnumDefinition) Pos() token.Pos {
+	if e.Desc != nil {
+		return e.Desc.Pos()
+	}
 	return e.Enum
 }
 
 // End returns position of first character immediately after the node
 func (e *EnumDefinition) End() token.Pos {
+	if !e.Rbrace.IsValid() { // extension without values
+		return e.Directs.End()
+	}
 	return token.Pos(int(e.Rbrace) + 1)
 }
 
 // EnumValue node
 type EnumValue struct {
+	Doc     *CommentGroup // leading comments
+	Desc    *Description
 	Name    Token
 	NamePos token.Pos
 	Directs *Directives
+	Comment *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (e *EnumValue) Pos() token.Pos {
+	if e.Desc != nil {
+		return e.Desc.Pos()
+	}
 	return e.NamePos
 }
 
@@ -872,21 +1073,30 @@ func (e *EnumValue) End() token.Pos {
 
 // UnionDefinition node
 type UnionDefinition struct {
+	Doc     *CommentGroup // leading comments
+	Desc    *Description
 	Union   token.Pos
 	Name    Token
 	NamePos token.Pos
 	Directs *Directives
 	Eq      token.Pos
 	Members *UnionMembers
+	Comment *CommentGroup // trailing comments on the same line
 }
 
 // Pos returns position of first character belong to the node
 func (u *UnionDefinition) Pos() token.Pos {
+	if u.Desc != nil {
+		return u.Desc.Pos()
+	}
 	return u.Union
 }
 
 // End returns position of first character immediately after the node
 func (u *UnionDefinition) End() token.Pos {
+	if u.Members == nil { // extension without members
+		return u.Directs.End()
+	}
 	return u.Members.End()
 }
 
@@ -28,7 +28,7 @@ func assertFalse(t *testing.T, v bool) {
 func TestDirectives(t *testing.T) {
 	directives := "@include(if: $withFriends) @onInputObjectType @onType"
 	fset := token.NewFileSet()
-	p := newParser([]byte(directives), "", fset)
+	p := newParser([]byte(directives), "", fset, 0)
 	d, err := p.directives()
 	if err != nil {
 		t.Error("unexpected error")
@@ -59,7 +59,7 @@ Type2!
 [ [ Type5! ] ]
 `
 	fset := token.NewFileSet()
-	p := newParser([]byte(types), "", fset)
+	p := newParser([]byte(types), "", fset, 0)
 	var typ Type
 	var err error
 
@@ -137,7 +137,7 @@ Type2!
 func TestVariableDefinitions(t *testing.T) {
 	varDefns := `($episode: Episode = "JEDI", $withFriends: Boolean!, $ep: Episode! $review: ReviewInput!)`
 	fset := token.NewFileSet()
-	p := newParser([]byte(varDefns), "", fset)
+	p := newParser([]byte(varDefns), "", fset, 0)
 	defns, err := p.variableDefinitions()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -210,7 +210,7 @@ func TestValues(t *testing.T) {
 }`
 	fset := token.NewFileSet()
 	fname := "value.graphql"
-	p := newParser([]byte(values), fname, fset)
+	p := newParser([]byte(values), fname, fset, 0)
 	val, err := p.value()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -361,7 +361,7 @@ func TestValues(t *testing.T) {
 func TestConstValues(t *testing.T) {
 	values := `[ [] ] {  } { a: 1, b: 2 }`
 	fset := token.NewFileSet()
-	p := newParser([]byte(values), "", fset)
+	p := newParser([]byte(values), "", fset, 0)
 
 	val, err := p.valueConst()
 	if err != nil {
@@ -394,7 +394,7 @@ fragment comparisonFields on Character {
 }
 `
 	fset := token.NewFileSet()
-	p := newParser([]byte(frag), "", fset)
+	p := newParser([]byte(frag), "", fset, 0)
 
 	def, err := p.fragmentDefinition()
 	if err != nil {
@@ -427,7 +427,7 @@ query HeroForEpisode($ep: Episode!) {
 }`
 
 	fset := token.NewFileSet()
-	p := newParser([]byte(oper), "", fset)
+	p := newParser([]byte(oper), "", fset, 0)
 	op, err := p.operationDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -479,7 +479,7 @@ fragment friendFields on User {
 }`
 
 	fset := token.NewFileSet()
-	p := newParser([]byte(doc), "", fset)
+	p := newParser([]byte(doc), "", fset, 0)
 
 	document, err := p.parseDocument()
 	if err != nil {
@@ -506,7 +506,7 @@ union AnnotatedUnion @onUnion = A | B
 union AnnotatedUnionTwo @onUnion = A | B`
 
 	fset := token.NewFileSet()
-	p := newParser([]byte(unions), "", fset)
+	p := newParser([]byte(unions), "", fset, 0)
 	def, err := p.unionDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -552,7 +552,7 @@ enum AnnotatedEnum @onEnum {
 }`
 
 	fset := token.NewFileSet()
-	p := newParser([]byte(enums), "", fset)
+	p := newParser([]byte(enums), "", fset, 0)
 	def, err := p.enumDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -586,7 +586,7 @@ schema {
 	mutation: MutationType
 }`
 	fset := token.NewFileSet()
-	p := newParser([]byte(schema), "", fset)
+	p := newParser([]byte(schema), "", fset, 0)
 	def, err := p.schemaDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -611,7 +611,7 @@ directive @include(if: Boolean!)
 
 directive @include2(if: Boolean!) on FIELD`
 	fset := token.NewFileSet()
-	p := newParser([]byte(directive), "", fset)
+	p := newParser([]byte(directive), "", fset, 0)
 	def, err := p.directiveDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -640,7 +640,7 @@ extend type Foo @onType {
 	seven(argument: [String]): Type
 }`
 	fset := token.NewFileSet()
-	p := newParser([]byte(extend), "", fset)
+	p := newParser([]byte(extend), "", fset, 0)
 	def, err := p.extendDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -664,7 +664,7 @@ func TestTypeDefinition(t *testing.T) {
 	totalCredits: Int @onField
 }`
 	fset := token.NewFileSet()
-	p := newParser([]byte(d), "", fset)
+	p := newParser([]byte(d), "", fset, 0)
 	def, err := p.typeDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -701,7 +701,7 @@ input AnnotatedInput @onInputObjectType {
 }`
 
 	fset := token.NewFileSet()
-	p := newParser([]byte(definition), "", fset)
+	p := newParser([]byte(definition), "", fset, 0)
 	def, err := p.inputObjectDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -718,7 +718,7 @@ func TestScalarDefinition(t *testing.T) {
 	scalar := `scalar CustomScalar @onScalar
 scalar Date`
 	fset := token.NewFileSet()
-	p := newParser([]byte(scalar), "", fset)
+	p := newParser([]byte(scalar), "", fset, 0)
 	def, err := p.scalarDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -745,7 +745,7 @@ interface AnnotatedInterface @onInterface {
 	annotatedField(arg: Type @onArg): Type @onField
 }`
 	fset := token.NewFileSet()
-	p := newParser([]byte(iface), "", fset)
+	p := newParser([]byte(iface), "", fset, 0)
 	def, err := p.interfaceDefinition()
 	if err != nil {
 		t.Error("unexpected error", err)
@@ -788,8 +788,8 @@ schema {
 }`
 
 	fset := token.NewFileSet()
-	p := newParser([]byte(schemas), "", fset)
-	def, err := p.schema()
+	p := newParser([]byte(schemas), "", fset, 0)
+	def, err := p.parseSchema()
 	if err != nil {
 		t.Error("unexpected error", err)
 	}
@@ -4,31 +4,107 @@ import (
 	"errors"
 	"fmt"
 	"go/token"
+	"sort"
 )
 
 // ErrBadParse for invalid parse.
 type ErrBadParse struct {
-	pos    token.Position
-	expect string
-	found  string
+	Pos      token.Position
+	Expected string
+	Found    string
 }
 
 func (e ErrBadParse) Error() string {
-	return fmt.Sprintf("%s: expecting %s, found '%s'", e.pos, e.expect, e.found)
+	return fmt.Sprintf("%s: expecting %s, found '%s'", e.Pos, e.Expected, e.Found)
 }
 
-// ParseDocument returns ast.Document.
-func ParseDocument(document []byte, filename string, fset *token.FileSet) (*Document, error) {
+// ErrorList is a list of *ErrBadParse, the parser recovers from a syntax
+// error and goes on to report the following ones.
+type ErrorList []*ErrBadParse
+
+// Add adds an ErrBadParse with pos, expected and found to the list.
+func (l *ErrorList) Add(pos token.Position, expected, found string) {
+	*l = append(*l, &ErrBadParse{Pos: pos, Expected: expected, Found: found})
+}
+
+// Reset resets the list to no errors.
+func (l *ErrorList) Reset() {
+	*l = (*l)[0:0]
+}
+
+// ErrorList implements sort.Interface.
+func (l ErrorList) Len() int      { return len(l) }
+func (l ErrorList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+func (l ErrorList) Less(i, j int) bool {
+	e, f := &l[i].Pos, &l[j].Pos
+	if e.Filename != f.Filename {
+		return e.Filename < f.Filename
+	}
+	if e.Line != f.Line {
+		return e.Line < f.Line
+	}
+	if e.Column != f.Column {
+		return e.Column < f.Column
+	}
+	return l[i].Expected < l[j].Expected
+}
+
+// Sort sorts the list by position.
+func (l ErrorList) Sort() {
+	sort.Sort(l)
+}
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns an error equivalent to the list, nil if it is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Mode controls the optional features of parsing.
+type Mode uint
+
+// Parse modes
+const (
+	ParseComments Mode = 1 << iota // attach comments to nodes and keep them in Comments
+)
+
+// ParseDocument returns ast.Document. If there are syntax errors, the error is
+// an ErrorList of them sorted by position, and the document holds the
+// definitions parsed without error. Comments are skipped unless mode includes
+// ParseComments.
+func ParseDocument(document []byte, filename string, fset *token.FileSet, mode ...Mode) (*Document, error) {
 	if fset == nil {
 		return nil, errors.New("no token.FileSet provided (fset == nil)")
 	}
-	return newParser(document, filename, fset).parseDocument()
+	return newParser(document, filename, fset, modeOf(mode)).parseDocument()
 }
 
-// ParseSchema returns ast.Schema.
-func ParseSchema(schema []byte, filename string, fset *token.FileSet) (*Schema, error) {
+// ParseSchema returns ast.Schema, syntax errors and comments are handled as
+// with ParseDocument.
+func ParseSchema(schema []byte, filename string, fset *token.FileSet, mode ...Mode) (*Schema, error) {
 	if fset == nil {
 		return nil, errors.New("no token.FileSet provided (fset == nil)")
 	}
-	return newParser(schema, filename, fset).parseSchema()
+	return newParser(schema, filename, fset, modeOf(mode)).parseSchema()
+}
+
+func modeOf(modes []Mode) Mode {
+	var mode Mode
+	for _, m := range modes {
+		mode |= m
+	}
+	return mode
 }
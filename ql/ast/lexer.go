@@ -6,12 +6,16 @@ import (
 	"go/token"
 	"io"
 	"strconv"
+	"strings"
 )
 
 type lexer struct {
-	input     *bytes.Reader
-	lookAhead rune
-	file      *token.File
+	input         *bytes.Reader
+	lookAhead     rune
+	lookAheadOffs int // offset of lookAhead, the end of the token read
+	file          *token.File
+	keepComments  bool       // record comments instead of skipping them
+	comments      []*Comment // comments read since the last takeComments
 }
 
 func newLexer(source []byte, file *token.File) *lexer {
@@ -35,6 +39,7 @@ func newLexer(source []byte, file *token.File) *lexer {
 }
 
 func (l *lexer) consume() {
+	l.lookAheadOffs = l.offset()
 	r, _, err := l.input.ReadRune()
 	if err != nil {
 		if err == io.EOF {
@@ -97,7 +102,7 @@ func (l *lexer) read() (Token, int) {
 		case '\uFEFF', '\u0009', '\u0020', '\u000A', '\u000D', ',': // ignored
 			l.readIgnored()
 			continue
-		case '!', '$', '(', ')', ':', '=', '@', '[', ']', '{', '|', '}':
+		case '!', '$', '(', ')', ':', '=', '@', '[', ']', '{', '|', '}', '&':
 			offs = l.offset()
 			tok = l.readPunct()
 			return tok, offs
@@ -164,11 +169,26 @@ func (l *lexer) readSpread() Token {
 }
 
 func (l *lexer) readComment() {
+	offs := l.lookAheadOffs
+	var b bytes.Buffer
+	b.WriteRune(l.lookAhead)
 	l.consume()
 	for l.lookAhead != rune(EOF) &&
 		(l.lookAhead >= '\u0020' || l.lookAhead == '\u0009') { // SourceCharacter but not LineTerminator
+		b.WriteRune(l.lookAhead)
 		l.consume()
 	}
+
+	if l.keepComments {
+		l.comments = append(l.comments, &Comment{Hash: l.pos(offs), Text: b.String()})
+	}
+}
+
+// takeComments returns the comments read since the last call.
+func (l *lexer) takeComments() []*Comment {
+	comments := l.comments
+	l.comments = nil
+	return comments
 }
 
 // IntValue : '-'? IntegerPart
@@ -279,6 +299,15 @@ func (l *lexer) readString() Token {
 	b.WriteRune('"')
 	l.consume()
 
+	if l.lookAhead == '"' { // empty string or block string
+		l.consume()
+		if l.lookAhead != '"' {
+			return Token{Kind: STRING, Text: ""}
+		}
+		l.consume()
+		return l.readBlockString()
+	}
+
 	for l.lookAhead != rune(EOF) && l.lookAhead != '"' && l.lookAhead != '\u000A' && l.lookAhead != '\u000D' {
 
 		// SourceCharacter
@@ -357,3 +386,126 @@ func (l *lexer) readString() Token {
 	strVal := b.String()
 	return Token{Kind: STRING, Text: strVal[1 : len(strVal)-1]}
 }
+
+// '"""' (SourceCharacter but not '"""' or '\"""' | '\"""')* '"""'
+// the opening quotes are consumed already.
+func (l *lexer) readBlockString() Token {
+	var b bytes.Buffer
+	for l.lookAhead != rune(EOF) {
+		switch l.lookAhead {
+		case '"':
+			n := l.readQuotes()
+			if n == 3 {
+				return Token{Kind: STRING, Text: blockStringValue(b.String())}
+			}
+			b.WriteString(strings.Repeat(`"`, n))
+		case '\\':
+			l.consume()
+			n := l.readQuotes()
+			if n < 3 { // not escaped triple quotes
+				b.WriteRune('\\')
+			}
+			b.WriteString(strings.Repeat(`"`, n))
+		default:
+			if l.lookAhead < '\u0020' && l.lookAhead != '\u0009' && l.lookAhead != '\u000A' && l.lookAhead != '\u000D' {
+				b.WriteRune(l.lookAhead)
+				l.consume()
+				return Token{Kind: ILLEGAL, Text: `"""` + b.String()}
+			}
+			if l.lookAhead == '\u000A' { // new line
+				l.file.AddLine(l.offset())
+			}
+			b.WriteRune(l.lookAhead)
+			l.consume()
+		}
+	}
+	return Token{Kind: ILLEGAL, Text: `"""` + b.String()}
+}
+
+// readQuotes consumes up to three double quotes, it returns how many.
+func (l *lexer) readQuotes() int {
+	n := 0
+	for n < 3 && l.lookAhead == '"' {
+		l.consume()
+		n++
+	}
+	return n
+}
+
+// blockStringValue returns the value of a block string with raw content, the
+// common indentation and the leading and trailing blank lines are removed.
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(raw), "\n")
+
+	commonIndent := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (commonIndent < 0 || indent < commonIndent) {
+			commonIndent = indent
+		}
+	}
+	if commonIndent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) < commonIndent {
+				lines[i] = ""
+			} else {
+				lines[i] = lines[i][commonIndent:]
+			}
+		}
+	}
+
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// QuoteString returns s quoted as a GraphQL string value.
+func QuoteString(s string) string {
+	var b bytes.Buffer
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// QuoteBlockString returns s quoted as a GraphQL block string, the quotes and
+// every non-empty line of s are on lines of their own prefixed by indent.
+func QuoteBlockString(s, indent string) string {
+	var b bytes.Buffer
+	b.WriteString(`"""`)
+	for _, line := range strings.Split(s, "\n") {
+		b.WriteByte('\n')
+		if line != "" {
+			b.WriteString(indent + strings.Replace(line, `"""`, `\"""`, -1))
+		}
+	}
+	b.WriteString("\n" + indent + `"""`)
+	return b.String()
+}
@@ -266,6 +266,52 @@ func TestLexesStrings(t *testing.T) {
 	}
 }
 
+func TestLexesBlockStrings(t *testing.T) {
+	tests := []struct {
+		source string
+		text   string
+	}{
+		{`""`, ""},
+		{`""""""`, ""},
+		{`"""simple"""`, "simple"},
+		{`""" white space """`, " white space "},
+		{`"""contains " quote"""`, `contains " quote`},
+		{`"""contains \""" triple quote"""`, `contains """ triple quote`},
+		{"\"\"\"multi\nline\"\"\"", "multi\nline"},
+		{"\"\"\"multi\rline\r\nnormalized\"\"\"", "multi\nline\nnormalized"},
+		{`"""unescaped \n\r\b\t\f\u1234"""`, `unescaped \n\r\b\t\f\u1234`},
+		{`"""slashes \\ \/"""`, `slashes \\ \/`},
+		{"\"\"\"\n\n    spans\n      multiple\n        lines\n\n    \"\"\"", "spans\n  multiple\n    lines"},
+		{"\"\"\"  first line\n    indented\n    \"\"\"", "  first line\nindented"},
+	}
+
+	for _, test := range tests {
+		tok, _ := newLexer([]byte(test.source), nil).read()
+		expected := Token{Kind: STRING, Text: test.text}
+		if tok != expected {
+			t.Errorf("%q returned: %v, expected: %v", test.source, tok, expected)
+		}
+	}
+
+	lexer := newLexer([]byte("\"\"\"no end quote"), nil)
+	if tok, _ := lexer.read(); tok.Kind != ILLEGAL {
+		t.Errorf("returned: %v, expected ILLEGAL", tok)
+	}
+
+	lexer = newLexer([]byte("\"\"\"contains unescaped \u0007 control char\"\"\""), nil)
+	if tok, _ := lexer.read(); tok.Kind != ILLEGAL {
+		t.Errorf("returned: %v, expected ILLEGAL", tok)
+	}
+
+	// lines in block strings are counted
+	lexer = newLexer([]byte("\"\"\"\n\n\"\"\" after"), nil)
+	lexer.read()
+	_, offs := lexer.read()
+	if pos := lexer.positionFor(offs - 1); pos.Line != 3 || pos.Column != 5 {
+		t.Errorf("expecting 3:5, found %s", pos)
+	}
+}
+
 func TestInvalidStrings(t *testing.T) {
 	lexer := newLexer([]byte("\""), nil)
 	tok, _ := lexer.read()
@@ -551,3 +597,13 @@ func TestInvalidNumbers(t *testing.T) {
 		t.Errorf("returned: %v, expected: %v", tok, expected)
 	}
 }
+
+func TestQuoteStrings(t *testing.T) {
+	if quoted := QuoteString("a \"b\"\\\n\x01"); quoted != `"a \"b\"\\\n\u0001"` {
+		t.Errorf("unexpected quoted string %s", quoted)
+	}
+	expected := "\"\"\"\n  a\n\n  b \\\"\"\"\n  \"\"\""
+	if quoted := QuoteBlockString("a\n\nb \"\"\"", "  "); quoted != expected {
+		t.Errorf("expected %q, found %q", expected, quoted)
+	}
+}
@@ -11,21 +11,35 @@ type parser struct {
 	input        *lexer
 	lookAheads   []Token // LL(2), look two tokens ahead
 	tokenOffsets []int   // corresponding offset of two tokens
+	tokenEnds    []int   // corresponding end offset of two tokens
 	curr         int
+	nesting      []Kind // braces, parens and brackets consumed but not closed
+	extension    bool   // parsing the definition extended, its body is optional
+	errs         ErrorList
+
+	// comments, if parsed with ParseComments
+	leadComments []*CommentGroup // corresponding lead comment of two tokens
+	lineComments []*CommentGroup // corresponding line comment of two tokens
+	lineComment  *CommentGroup   // line comment of the last token consumed
+	comments     []*CommentGroup // all comment groups
 }
 
-func newParser(source []byte, filename string, fset *token.FileSet) *parser {
+func newParser(source []byte, filename string, fset *token.FileSet, mode Mode) *parser {
 	if source == nil {
 		return nil
 	}
 
 	f := fset.AddFile(filename, -1, len(source))
 	l := newLexer(source, f)
+	l.keepComments = mode&ParseComments != 0
 
 	p := &parser{
 		input:        l,
 		lookAheads:   make([]Token, 2),
 		tokenOffsets: make([]int, 2),
+		tokenEnds:    []int{-1, -1}, // no token before the first one
+		leadComments: make([]*CommentGroup, 2),
+		lineComments: make([]*CommentGroup, 2),
 	}
 
 	for i := 0; i < 2; i++ {
@@ -41,21 +55,40 @@ func (p *parser) parseDocument() (*Document, error) {
 	}
 
 	document := &Document{}
-
-	defn, err := p.definition()
-	if err != nil {
-		return document, err
-	}
-	document.Defs = append(document.Defs, defn)
-
-	for p.lookAhead(1) != TokenEOF {
-		defn, err = p.definition()
+	for {
+		start := p.tokenOffset(1)
+		defn, err := p.definition()
 		if err != nil {
-			return document, err
+			p.error(err)
+			p.sync(start, isDocumentStart)
+		} else {
+			document.Defs = append(document.Defs, defn)
+		}
+		if p.lookAhead(1) == TokenEOF {
+			break
 		}
-		document.Defs = append(document.Defs, defn)
 	}
-	return document, nil
+
+	document.Comments = p.comments
+	p.errs.Sort()
+	return document, p.errs.Err()
+}
+
+// isDocumentStart reports if tok following prev starts a definition of
+// document. A selection set starts one only right after the one of the previous
+// definition, not in the middle of the definition failing to parse.
+func isDocumentStart(prev, tok, next Token) bool {
+	if tok.Kind == LBRACE {
+		return prev.Kind == RBRACE
+	}
+	if tok.Kind != NAME {
+		return false
+	}
+	switch tok.Text {
+	case Stringify(QUERY), Stringify(MUTATION), Stringify(SUBSCRIPTION), Stringify(FRAGMENT):
+		return true
+	}
+	return false
 }
 
 func (p *parser) definition() (Definition, error) {
@@ -70,97 +103,143 @@ func (p *parser) parseSchema() (*Schema, error) {
 		return nil, errors.New("parser nil")
 	}
 
-	schema, err := p.schema()
-	if err != nil {
-		return nil, err
-	}
+	s := p.schema()
+	s.Comments = p.comments
+	p.errs.Sort()
+	return s, p.errs.Err()
+}
 
-	return schema, nil
+// isSchemaStart reports if tok followed by next starts a definition of schema,
+// a description starts one only if a definition keyword follows it.
+func isSchemaStart(prev, tok, next Token) bool {
+	if tok.Kind == STRING { // description
+		return next.Kind != STRING && isSchemaStart(tok, next, Token{})
+	}
+	if tok.Kind != NAME {
+		return false
+	}
+	switch tok.Text {
+	case Stringify(INTERFACE), Stringify(SCALAR), Stringify(INPUT), Stringify(TYPE),
+		Stringify(EXTEND), Stringify(DIRECTIVE), Stringify(SCHEMA), Stringify(ENUM), Stringify(UNION):
+		return true
+	}
+	return false
 }
 
-func (p *parser) schema() (*Schema, error) {
+func (p *parser) schema() *Schema {
 	s := &Schema{}
-	var node, first, last Node
-	var err error
-	isFirst := true
+	var first, last Node
 	for p.lookAhead(1) != TokenEOF {
-		switch p.lookAhead(1).Text {
+		start := p.tokenOffset(1)
+		var node Node
+		var err error
+		keyword := p.lookAhead(1)
+		if keyword.Kind == STRING { // description
+			keyword = p.lookAhead(2)
+		}
+		switch keyword.Text {
 		case Stringify(INTERFACE):
-			node, err = p.interfaceDefinition()
-			if err != nil {
-				return nil, err
+			var n *InterfaceDefinition
+			if n, err = p.interfaceDefinition(); err == nil {
+				s.Interfaces, node = append(s.Interfaces, n), n
 			}
-			s.Interfaces = append(s.Interfaces, node.(*InterfaceDefinition))
 		case Stringify(SCALAR):
-			node, err = p.scalarDefinition()
-			if err != nil {
-				return nil, err
+			var n *ScalarDefinition
+			if n, err = p.scalarDefinition(); err == nil {
+				s.Scalars, node = append(s.Scalars, n), n
 			}
-			s.Scalars = append(s.Scalars, node.(*ScalarDefinition))
 		case Stringify(INPUT):
-			node, err = p.inputObjectDefinition()
-			if err != nil {
-				return nil, err
+			var n *InputObjectDefinition
+			if n, err = p.inputObjectDefinition(); err == nil {
+				s.InputObjects, node = append(s.InputObjects, n), n
 			}
-			s.InputObjects = append(s.InputObjects, node.(*InputObjectDefinition))
 		case Stringify(TYPE):
-			node, err = p.typeDefinition()
-			if err != nil {
-				return nil, err
+			var n *TypeDefinition
+			if n, err = p.typeDefinition(); err == nil {
+				s.Types, node = append(s.Types, n), n
 			}
-			s.Types = append(s.Types, node.(*TypeDefinition))
 		case Stringify(EXTEND):
-			node, err = p.extendDefinition()
-			if err != nil {
-				return nil, err
-			}
-			s.Extends = append(s.Extends, node.(*ExtendDefinition))
+			node, err = p.typeSystemExtension(s)
 		case Stringify(DIRECTIVE):
-			node, err = p.directiveDefinition()
-			if err != nil {
-				return nil, err
+			var n *DirectiveDefinition
+			if n, err = p.directiveDefinition(); err == nil {
+				s.Directives, node = append(s.Directives, n), n
 			}
-			s.Directives = append(s.Directives, node.(*DirectiveDefinition))
 		case Stringify(SCHEMA):
-			node, err = p.schemaDefinition()
-			if err != nil {
-				return nil, err
+			var n *SchemaDefinition
+			if n, err = p.schemaDefinition(); err == nil {
+				s.Schemas, node = append(s.Schemas, n), n
 			}
-			s.Schemas = append(s.Schemas, node.(*SchemaDefinition))
 		case Stringify(ENUM):
-			node, err = p.enumDefinition()
-			if err != nil {
-				return nil, err
+			var n *EnumDefinition
+			if n, err = p.enumDefinition(); err == nil {
+				s.Enums, node = append(s.Enums, n), n
 			}
-			s.Enums = append(s.Enums, node.(*EnumDefinition))
 		default:
-			node, err = p.unionDefinition()
-			if err != nil {
-				return nil, err
+			var n *UnionDefinition
+			if n, err = p.unionDefinition(); err == nil {
+				s.Unions, node = append(s.Unions, n), n
 			}
-			s.Unions = append(s.Unions, node.(*UnionDefinition))
 		}
 
-		// keep recording the last node seen
-		last = node
+		if err != nil {
+			p.error(err)
+			p.sync(start, isSchemaStart)
+			continue
+		}
 
-		if isFirst {
-			isFirst = false
+		// keep recording the first and last node seen
+		if first == nil {
 			first = node
 		}
+		last = node
 	}
 
-	s.pos, s.end = first.Pos(), last.End()
-	return s, nil
+	if first != nil {
+		s.pos, s.end = first.Pos(), last.End()
+	}
+	return s
+}
+
+// typeSystemExtension parses the extension of a type system definition into s.
+func (p *parser) typeSystemExtension(s *Schema) (Node, error) {
+	var defn func() (Node, error)
+	switch p.lookAhead(2).Text {
+	case Stringify(SCHEMA):
+		defn = func() (Node, error) { return p.schemaDefinition() }
+	case Stringify(SCALAR):
+		defn = func() (Node, error) { return p.scalarDefinition() }
+	case Stringify(INTERFACE):
+		defn = func() (Node, error) { return p.interfaceDefinition() }
+	case Stringify(UNION):
+		defn = func() (Node, error) { return p.unionDefinition() }
+	case Stringify(ENUM):
+		defn = func() (Node, error) { return p.enumDefinition() }
+	case Stringify(INPUT):
+		defn = func() (Node, error) { return p.inputObjectDefinition() }
+	default:
+		n, err := p.extendDefinition()
+		if err == nil {
+			s.Extends = append(s.Extends, n)
+		}
+		return n, err
+	}
+	n, err := p.typeSystemExtensionOf(defn)
+	if err == nil {
+		s.TypeSystemExtends = append(s.TypeSystemExtends, n)
+	}
+	return n, err
 }
 
 func (p *parser) operationDefinition() (*OperationDefinition, error) {
 	var err error
 
 	operDefn := &OperationDefinition{}
+	operDefn.Doc = p.doc()
 
 	if p.lookAhead(1).Kind == LBRACE {
 		operDefn.SelSet, err = p.selectionSet()
+		operDefn.Comment = p.lineComment
 		return operDefn, err
 	}
 
@@ -199,6 +278,7 @@ func (p *parser) operationDefinition() (*OperationDefinition, error) {
 	}
 
 	operDefn.SelSet, err = p.selectionSet()
+	operDefn.Comment = p.lineComment
 	return operDefn, err
 }
 
@@ -260,6 +340,13 @@ func (p *parser) variableDefinition() (*VariableDefinition, error) {
 		}
 	}
 
+	if p.lookAhead(1).Kind == AT {
+		varDefn.Directs, err = p.directives()
+		if err != nil {
+			return varDefn, err
+		}
+	}
+
 	return varDefn, nil
 }
 
@@ -509,6 +596,7 @@ func (p *parser) selection() (Selection, error) {
 
 func (p *parser) field() (*Field, error) {
 	field := &Field{}
+	field.Doc = p.doc()
 
 	var err error
 	if p.lookAhead(1).Kind == NAME && p.lookAhead(2).Kind == COLON {
@@ -545,11 +633,13 @@ func (p *parser) field() (*Field, error) {
 		}
 	}
 
+	field.Comment = p.lineComment
 	return field, nil
 }
 
 func (p *parser) fragmentSpread() (*FragmentSpread, error) {
 	frag := &FragmentSpread{}
+	frag.Doc = p.doc()
 
 	frag.Spread = p.input.pos(p.tokenOffset(1))
 	err := p.match(SPREAD)
@@ -574,11 +664,13 @@ func (p *parser) fragmentSpread() (*FragmentSpread, error) {
 		}
 	}
 
+	frag.Comment = p.lineComment
 	return frag, nil
 }
 
 func (p *parser) inlineFragment() (*InlineFragment, error) {
 	frag := &InlineFragment{}
+	frag.Doc = p.doc()
 
 	frag.Spread = p.input.pos(p.tokenOffset(1))
 	err := p.match(SPREAD)
@@ -605,6 +697,7 @@ func (p *parser) inlineFragment() (*InlineFragment, error) {
 		return nil, err
 	}
 
+	frag.Comment = p.lineComment
 	return frag, nil
 }
 
@@ -753,10 +846,10 @@ func (p *parser) nameValue() (*NameValue, error) {
 }
 
 func (p *parser) enumValue() (*EnumValue, error) {
-	val := &EnumValue{
-		Name:    p.lookAhead(1),
-		NamePos: p.input.pos(p.tokenOffset(1)),
-	}
+	val := &EnumValue{Doc: p.doc()}
+	val.Desc = p.description()
+	val.Name = p.lookAhead(1)
+	val.NamePos = p.input.pos(p.tokenOffset(1))
 
 	err := p.match(NAME)
 	if err != nil {
@@ -770,6 +863,7 @@ func (p *parser) enumValue() (*EnumValue, error) {
 		}
 		val.Directs = directs
 	}
+	val.Comment = p.lineComment
 	return val, nil
 }
 
@@ -909,6 +1003,7 @@ func (p *parser) directive() (*Directive, error) {
 
 func (p *parser) fragmentDefinition() (*FragmentDefinition, error) {
 	frag := &FragmentDefinition{}
+	frag.Doc = p.doc()
 
 	frag.Fragment = p.input.pos(p.tokenOffset(1))
 	err := p.match(FRAGMENT)
@@ -943,6 +1038,7 @@ func (p *parser) fragmentDefinition() (*FragmentDefinition, error) {
 		return nil, err
 	}
 
+	frag.Comment = p.lineComment
 	return frag, nil
 }
 
@@ -954,6 +1050,10 @@ func (p *parser) tokenOffset(i int) int {
 	return p.tokenOffsets[(p.curr+i-1)%len(p.tokenOffsets)]
 }
 
+func (p *parser) tokenEnd(i int) int {
+	return p.tokenEnds[(p.curr+i-1)%len(p.tokenEnds)]
+}
+
 func (p *parser) match(k Kind) error {
 	// fmt.Println("DEBUG tok", p.lookAhead(1))
 	if IsReserved(k) {
@@ -972,25 +1072,132 @@ func (p *parser) match(k Kind) error {
 }
 
 func (p *parser) consume() {
+	switch kind := p.lookAhead(1).Kind; kind {
+	case LBRACE, LPAREN, LBRACK:
+		p.nesting = append(p.nesting, kind)
+	case RBRACE, RPAREN, RBRACK:
+		p.close(kind)
+	}
+
+	p.lineComment = p.lineComments[p.curr]
+
+	prevEnd := p.tokenEnd(2)
 	tok, offs := p.input.read()
 
 	// record the token and position of its first character
 	p.lookAheads[p.curr] = tok
 	p.tokenOffsets[p.curr] = offs - 1 // minus one to start from zero
+	p.tokenEnds[p.curr] = p.input.lookAheadOffs
+
+	// comments between the previous token and this one
+	prev := (p.curr + 1) % len(p.lookAheads)
+	p.lineComments[prev], p.leadComments[p.curr] = p.groupComments(p.input.takeComments(), prevEnd, offs-1)
+	p.lineComments[p.curr] = nil
 
 	p.curr = (p.curr + 1) % len(p.lookAheads)
 }
 
+// close pops the nesting up to the brace, paren or bracket closed by kind,
+// those opened after it are left unclosed by mistake. A closing token without
+// its opening one is ignored.
+func (p *parser) close(kind Kind) {
+	open := map[Kind]Kind{RBRACE: LBRACE, RPAREN: LPAREN, RBRACK: LBRACK}[kind]
+	for i := len(p.nesting) - 1; i >= 0; i-- {
+		if p.nesting[i] == open {
+			p.nesting = p.nesting[:i]
+			return
+		}
+	}
+}
+
+// groupComments groups comments on adjacent lines between the token ending at
+// prevEnd and the one at offs. The group on the line of the former is its line
+// comment, the group ending on the line before the latter is its lead comment.
+func (p *parser) groupComments(comments []*Comment, prevEnd, offs int) (line, lead *CommentGroup) {
+	lineOf := func(pos token.Pos) int {
+		return p.input.file.Line(pos)
+	}
+
+	i := 0
+	if len(comments) > 0 && prevEnd >= 0 && lineOf(comments[0].Hash) == lineOf(p.input.pos(prevEnd)) {
+		line = &CommentGroup{List: comments[:1]}
+		p.comments = append(p.comments, line)
+		i = 1
+	}
+
+	for i < len(comments) {
+		j := i + 1
+		for j < len(comments) && lineOf(comments[j].Hash) == lineOf(comments[j-1].Hash)+1 {
+			j++
+		}
+		group := &CommentGroup{List: comments[i:j]}
+		p.comments = append(p.comments, group)
+		if j == len(comments) && lineOf(comments[j-1].Hash)+1 == lineOf(p.input.pos(offs)) {
+			lead = group
+		}
+		i = j
+	}
+	return line, lead
+}
+
+// doc returns the lead comment of the next token, the documentation of the
+// node starting with it.
+func (p *parser) doc() *CommentGroup {
+	return p.leadComments[p.curr]
+}
+
 func (p *parser) parseError(expect string) error {
-	return ErrBadParse{
-		pos:    p.input.positionFor(p.tokenOffset(1)),
-		expect: expect,
-		found:  p.lookAhead(1).Text,
+	return &ErrBadParse{
+		Pos:      p.input.positionFor(p.tokenOffset(1)),
+		Expected: expect,
+		Found:    p.lookAhead(1).Text,
+	}
+}
+
+// error records err of parsing a definition, which is made by parseError. Any
+// other error is recorded as failing to parse a definition at the next token.
+func (p *parser) error(err error) {
+	var bad *ErrBadParse
+	if !errors.As(err, &bad) {
+		bad = p.parseError("definition").(*ErrBadParse)
+	}
+	p.errs = append(p.errs, bad)
+}
+
+// sync skips tokens after a parse error up to the next definition, one
+// starting outside of any braces, parens and brackets as reported by isStart
+// given the token skipped before and the one after, or EOF. It makes progress
+// unless the definition failed at its first token.
+func (p *parser) sync(start int, isStart func(prev, tok, next Token) bool) {
+	var prev Token
+	for p.lookAhead(1) != TokenEOF {
+		if len(p.nesting) == 0 && isStart(prev, p.lookAhead(1), p.lookAhead(2)) && p.tokenOffset(1) != start {
+			return
+		}
+		prev = p.lookAhead(1)
+		p.consume()
+	}
+}
+
+// description parses the description preceding a definition, nil if there is
+// none.
+func (p *parser) description() *Description {
+	if p.lookAhead(1).Kind != STRING {
+		return nil
+	}
+	desc := &Description{
+		Val:    p.lookAhead(1),
+		ValPos: p.input.pos(p.tokenOffset(1)),
+		ValEnd: p.input.pos(p.tokenEnd(1)),
 	}
+	p.consume()
+	return desc
 }
 
 func (p *parser) interfaceDefinition() (*InterfaceDefinition, error) {
 	inter := &InterfaceDefinition{}
+	inter.Doc = p.doc()
+	inter.Desc = p.description()
 
 	inter.Interface = p.input.pos(p.tokenOffset(1))
 	err := p.match(INTERFACE)
@@ -1004,6 +1211,13 @@ func (p *parser) interfaceDefinition() (*InterfaceDefinition, error) {
 		return nil, err
 	}
 
+	if p.lookAhead(1).Text == Stringify(IMPLEMENTS) {
+		inter.Implements, err = p.implementsInterfaces()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if p.lookAhead(1).Kind == AT {
 		inter.Directs, err = p.directives()
 		if err != nil {
@@ -1011,6 +1225,11 @@ func (p *parser) interfaceDefinition() (*InterfaceDefinition, error) {
 		}
 	}
 
+	if p.extension && (inter.Implements != nil || inter.Directs != nil) && p.lookAhead(1).Kind != LBRACE {
+		inter.Comment = p.lineComment
+		return inter, nil
+	}
+
 	inter.Lbrace = p.input.pos(p.tokenOffset(1))
 	if err = p.match(LBRACE); err != nil {
 		return nil, err
@@ -1032,11 +1251,15 @@ func (p *parser) interfaceDefinition() (*InterfaceDefinition, error) {
 	}
 
 	inter.Rbrace = p.input.pos(p.tokenOffset(1))
-	return inter, p.match(RBRACE)
+	err = p.match(RBRACE)
+	inter.Comment = p.lineComment
+	return inter, err
 }
 
 func (p *parser) fieldDefinition() (*FieldDefinition, error) {
 	fieldDefn := &FieldDefinition{}
+	fieldDefn.Doc = p.doc()
+	fieldDefn.Desc = p.description()
 
 	fieldDefn.Name = p.lookAhead(1)
 	fieldDefn.NamePos = p.input.pos(p.tokenOffset(1))
@@ -1069,6 +1292,7 @@ func (p *parser) fieldDefinition() (*FieldDefinition, error) {
 		}
 	}
 
+	fieldDefn.Comment = p.lineComment
 	return fieldDefn, nil
 }
 
@@ -1102,6 +1326,8 @@ func (p *parser) argumentsDefinition() (*ArgumentsDefinition, error) {
 
 func (p *parser) inputValueDefinition() (*InputValueDefinition, error) {
 	input := &InputValueDefinition{}
+	input.Doc = p.doc()
+	input.Desc = p.description()
 
 	input.Name = p.lookAhead(1)
 	input.NamePos = p.input.pos(p.tokenOffset(1))
@@ -1134,11 +1360,14 @@ func (p *parser) inputValueDefinition() (*InputValueDefinition, error) {
 		}
 	}
 
+	input.Comment = p.lineComment
 	return input, nil
 }
 
 func (p *parser) scalarDefinition() (*ScalarDefinition, error) {
 	scalar := &ScalarDefinition{}
+	scalar.Doc = p.doc()
+	scalar.Desc = p.description()
 
 	scalar.Scalar = p.input.pos(p.tokenOffset(1))
 	err := p.match(SCALAR)
@@ -1157,12 +1386,17 @@ func (p *parser) scalarDefinition() (*ScalarDefinition, error) {
 		if err != nil {
 			return nil, err
 		}
+	} else if p.extension { // extending a scalar adds directives only
+		return nil, p.parseError(Stringify(AT))
 	}
+	scalar.Comment = p.lineComment
 	return scalar, nil
 }
 
 func (p *parser) inputObjectDefinition() (*InputObjectDefinition, error) {
 	input := &InputObjectDefinition{}
+	input.Doc = p.doc()
+	input.Desc = p.description()
 
 	input.Input = p.input.pos(p.tokenOffset(1))
 	err := p.match(INPUT)
@@ -1183,6 +1417,11 @@ func (p *parser) inputObjectDefinition() (*InputObjectDefinition, error) {
 		}
 	}
 
+	if p.extension && input.Directs != nil && p.lookAhead(1).Kind != LBRACE {
+		input.Comment = p.lineComment
+		return input, nil
+	}
+
 	input.Lbrace = p.input.pos(p.tokenOffset(1))
 	if err = p.match(LBRACE); err != nil {
 		return nil, err
@@ -1204,11 +1443,15 @@ func (p *parser) inputObjectDefinition() (*InputObjectDefinition, error) {
 	}
 
 	input.Rbrace = p.input.pos(p.tokenOffset(1))
-	return input, p.match(RBRACE)
+	err = p.match(RBRACE)
+	input.Comment = p.lineComment
+	return input, err
 }
 
 func (p *parser) typeDefinition() (*TypeDefinition, error) {
 	typDefn := &TypeDefinition{}
+	typDefn.Doc = p.doc()
+	typDefn.Desc = p.description()
 
 	typDefn.Typ = p.input.pos(p.tokenOffset(1))
 	err := p.match(TYPE)
@@ -1236,6 +1479,11 @@ func (p *parser) typeDefinition() (*TypeDefinition, error) {
 		}
 	}
 
+	if p.extension && (typDefn.Implements != nil || typDefn.Directs != nil) && p.lookAhead(1).Kind != LBRACE {
+		typDefn.Comment = p.lineComment
+		return typDefn, nil
+	}
+
 	typDefn.Lbrace = p.input.pos(p.tokenOffset(1))
 	if err = p.match(LBRACE); err != nil {
 		return nil, err
@@ -1255,7 +1503,9 @@ func (p *parser) typeDefinition() (*TypeDefinition, error) {
 	}
 
 	typDefn.Rbrace = p.input.pos(p.tokenOffset(1))
-	return typDefn, p.match(RBRACE)
+	err = p.match(RBRACE)
+	typDefn.Comment = p.lineComment
+	return typDefn, err
 }
 
 func (p *parser) implementsInterfaces() (*ImplementsInterfaces, error) {
@@ -1267,6 +1517,13 @@ func (p *parser) implementsInterfaces() (*ImplementsInterfaces, error) {
 		return nil, err
 	}
 
+	// the types are separated by "&", which may also lead, or by whitespace
+	// in the legacy syntax
+	if p.lookAhead(1).Kind == AMP {
+		implement.Amps = append(implement.Amps, p.input.pos(p.tokenOffset(1)))
+		p.consume()
+	}
+
 	var namedTyp *NamedType
 	namedTyp, err = p.namedType()
 	if err != nil {
@@ -1274,7 +1531,11 @@ func (p *parser) implementsInterfaces() (*ImplementsInterfaces, error) {
 	}
 	implement.NamedTyps = append(implement.NamedTyps, namedTyp)
 
-	for p.lookAhead(1).Kind == NAME {
+	for p.lookAhead(1).Kind == AMP || len(implement.Amps) == 0 && p.lookAhead(1).Kind == NAME && !isDefinitionKeyword(p.lookAhead(1)) {
+		if p.lookAhead(1).Kind == AMP {
+			implement.Amps = append(implement.Amps, p.input.pos(p.tokenOffset(1)))
+			p.consume()
+		}
 		namedTyp, err = p.namedType()
 		if err != nil {
 			return nil, err
@@ -1285,25 +1546,75 @@ func (p *parser) implementsInterfaces() (*ImplementsInterfaces, error) {
 	return implement, nil
 }
 
+// isDefinitionKeyword reports if tok is a keyword starting a definition, which
+// ends the interfaces of an extension without body in the legacy syntax.
+func isDefinitionKeyword(tok Token) bool {
+	return tok.Kind == NAME && (isSchemaStart(Token{}, tok, Token{}) || isDocumentStart(Token{}, tok, Token{}))
+}
+
 func (p *parser) extendDefinition() (*ExtendDefinition, error) {
-	e := &ExtendDefinition{}
+	ext, err := p.typeSystemExtensionOf(func() (Node, error) { return p.typeDefinition() })
+	if err != nil {
+		return nil, err
+	}
+	return &ExtendDefinition{Doc: ext.Doc, Extend: ext.Extend, TypDefn: ext.Defn.(*TypeDefinition), Comment: ext.Comment}, nil
+}
+
+// typeSystemExtensionOf parses extend followed by the definition parsed by
+// defn, whose body is optional.
+func (p *parser) typeSystemExtensionOf(defn func() (Node, error)) (*TypeSystemExtension, error) {
+	e := &TypeSystemExtension{}
+	e.Doc = p.doc()
 
 	e.Extend = p.input.pos(p.tokenOffset(1))
-	err := p.match(EXTEND)
+	err := p.extend()
 	if err != nil {
 		return nil, err
 	}
 
-	e.TypDefn, err = p.typeDefinition()
+	p.extension = true
+	e.Defn, err = defn()
+	p.extension = false
 	if err != nil {
 		return nil, err
 	}
 
+	// the comment trailing the extended definition belongs to the extension
+	switch d := e.Defn.(type) {
+	case *SchemaDefinition:
+		e.Comment, d.Comment = d.Comment, nil
+	case *ScalarDefinition:
+		e.Comment, d.Comment = d.Comment, nil
+	case *TypeDefinition:
+		e.Comment, d.Comment = d.Comment, nil
+	case *InterfaceDefinition:
+		e.Comment, d.Comment = d.Comment, nil
+	case *UnionDefinition:
+		e.Comment, d.Comment = d.Comment, nil
+	case *EnumDefinition:
+		e.Comment, d.Comment = d.Comment, nil
+	case *InputObjectDefinition:
+		e.Comment, d.Comment = d.Comment, nil
+	}
 	return e, nil
 }
 
+// extend matches the extend keyword, which is not preceded by a description
+// and cannot be followed by one.
+func (p *parser) extend() error {
+	if err := p.match(EXTEND); err != nil {
+		return err
+	}
+	if p.lookAhead(1).Kind == STRING { // extensions have no description
+		return p.parseError(Stringify(TYPE))
+	}
+	return nil
+}
+
 func (p *parser) directiveDefinition() (*DirectiveDefinition, error) {
 	d := &DirectiveDefinition{}
+	d.Doc = p.doc()
+	d.Desc = p.description()
 
 	d.Direct = p.input.pos(p.tokenOffset(1))
 	err := p.match(DIRECTIVE)
@@ -1329,6 +1640,11 @@ func (p *parser) directiveDefinition() (*DirectiveDefinition, error) {
 		}
 	}
 
+	if p.lookAhead(1).Text == Stringify(REPEATABLE) {
+		d.Repeat = p.input.pos(p.tokenOffset(1))
+		p.consume()
+	}
+
 	d.On = p.input.pos(p.tokenOffset(1))
 	if err = p.match(ON); err != nil {
 		return nil, err
@@ -1339,6 +1655,7 @@ func (p *parser) directiveDefinition() (*DirectiveDefinition, error) {
 		return nil, err
 	}
 
+	d.Comment = p.lineComment
 	return d, nil
 }
 
@@ -1383,6 +1700,8 @@ func (p *parser) directiveLocation() (*DirectiveLocation, error) {
 
 func (p *parser) schemaDefinition() (*SchemaDefinition, error) {
 	schemaDefn := &SchemaDefinition{}
+	schemaDefn.Doc = p.doc()
+	schemaDefn.Desc = p.description()
 
 	schemaDefn.Schema = p.input.pos(p.tokenOffset(1))
 	err := p.match(SCHEMA)
@@ -1397,6 +1716,11 @@ func (p *parser) schemaDefinition() (*SchemaDefinition, error) {
 		}
 	}
 
+	if p.extension && schemaDefn.Directs != nil && p.lookAhead(1).Kind != LBRACE {
+		schemaDefn.Comment = p.lineComment
+		return schemaDefn, nil
+	}
+
 	schemaDefn.Lbrace = p.input.pos(p.tokenOffset(1))
 	if err = p.match(LBRACE); err != nil {
 		return nil, err
@@ -1418,7 +1742,9 @@ func (p *parser) schemaDefinition() (*SchemaDefinition, error) {
 	}
 
 	schemaDefn.Rbrace = p.input.pos(p.tokenOffset(1))
-	return schemaDefn, p.match(RBRACE)
+	err = p.match(RBRACE)
+	schemaDefn.Comment = p.lineComment
+	return schemaDefn, err
 }
 
 func (p *parser) operationTypeDefinition() (*OperationTypeDefinition, error) {
@@ -1453,6 +1779,8 @@ func (p *parser) operationTypeDefinition() (*OperationTypeDefinition, error) {
 
 func (p *parser) enumDefinition() (*EnumDefinition, error) {
 	e := &EnumDefinition{}
+	e.Doc = p.doc()
+	e.Desc = p.description()
 
 	e.Enum = p.input.pos(p.tokenOffset(1))
 	err := p.match(ENUM)
@@ -1473,6 +1801,11 @@ func (p *parser) enumDefinition() (*EnumDefinition, error) {
 		}
 	}
 
+	if p.extension && e.Directs != nil && p.lookAhead(1).Kind != LBRACE {
+		e.Comment = p.lineComment
+		return e, nil
+	}
+
 	e.Lbrace = p.input.pos(p.tokenOffset(1))
 	if err = p.match(LBRACE); err != nil {
 		return nil, err
@@ -1494,11 +1827,15 @@ func (p *parser) enumDefinition() (*EnumDefinition, error) {
 	}
 
 	e.Rbrace = p.input.pos(p.tokenOffset(1))
-	return e, p.match(RBRACE)
+	err = p.match(RBRACE)
+	e.Comment = p.lineComment
+	return e, err
 }
 
 func (p *parser) unionDefinition() (*UnionDefinition, error) {
 	defn := &UnionDefinition{}
+	defn.Doc = p.doc()
+	defn.Desc = p.description()
 
 	defn.Union = p.input.pos(p.tokenOffset(1))
 	err := p.match(UNION)
@@ -1519,6 +1856,11 @@ func (p *parser) unionDefinition() (*UnionDefinition, error) {
 		}
 	}
 
+	if p.extension && defn.Directs != nil && p.lookAhead(1).Kind != EQL {
+		defn.Comment = p.lineComment
+		return defn, nil
+	}
+
 	defn.Eq = p.input.pos(p.tokenOffset(1))
 	if err = p.match(EQL); err != nil {
 		return nil, err
@@ -1528,6 +1870,7 @@ func (p *parser) unionDefinition() (*UnionDefinition, error) {
 	if err != nil {
 		return nil, err
 	}
+	defn.Comment = p.lineComment
 	return defn, nil
 }
 
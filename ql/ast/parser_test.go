@@ -7,19 +7,19 @@ import (
 )
 
 func TestInvalidLexerOrK(t *testing.T) {
-	if newParser(nil, "", token.NewFileSet()) != nil {
+	if newParser(nil, "", token.NewFileSet(), 0) != nil {
 		t.Error("should return nil")
 	}
 
-	if newParser([]byte("foobar"), "", token.NewFileSet()) == nil {
+	if newParser([]byte("foobar"), "", token.NewFileSet(), 0) == nil {
 		t.Error("should not return nil")
 	}
 
-	if _, err := newParser(nil, "", token.NewFileSet()).parseDocument(); err == nil {
+	if _, err := newParser(nil, "", token.NewFileSet(), 0).parseDocument(); err == nil {
 		t.Error("should return error")
 	}
 
-	if _, err := newParser(nil, "", token.NewFileSet()).parseSchema(); err == nil {
+	if _, err := newParser(nil, "", token.NewFileSet(), 0).parseSchema(); err == nil {
 		t.Error("should return error")
 	}
 }
@@ -425,3 +425,354 @@ directive @include2(if: Boolean!) on
 		t.Error("unexpected error", err)
 	}
 }
+
+func TestParseErrorRecovery(t *testing.T) {
+	doc, err := ParseDocument([]byte(`
+query A { a( }
+query B { b }
+fragment C on { c }
+mutation D { d { e: } }
+{ f }
+subscription`), "", token.NewFileSet())
+
+	list, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expecting ErrorList, found %v", err)
+	}
+	expected := []string{
+		"2:14: expecting NAME, found '}'",
+		"4:15: expecting NAME, found '{'",
+		"5:21: expecting NAME, found '}'",
+		"7:12: expecting {, found '<EOF>'",
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("expecting %d errors, found %v", len(expected), list)
+	}
+	for i, e := range list {
+		if e.Error() != expected[i] {
+			t.Errorf("expecting %s, found %s", expected[i], e)
+		}
+	}
+	if list[0].Pos.Line != 2 || list[0].Expected != "NAME" || list[0].Found != "}" {
+		t.Errorf("unexpected error fields %+v", list[0])
+	}
+	if err.Error() != expected[0]+" (and 3 more errors)" {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	// definitions without errors are kept, the rest of C is skipped and parsing
+	// resumes at { f } following the selection set of D
+	if len(doc.Defs) != 2 {
+		t.Fatalf("expecting 2 definitions, found %d", len(doc.Defs))
+	}
+	if name := doc.Defs[0].(*OperationDefinition).Name.Text; name != "B" {
+		t.Errorf("expecting B, found %s", name)
+	}
+	if sels := doc.Defs[1].(*OperationDefinition).SelSet.Sels; sels[0].(*Field).Name.Text != "f" {
+		t.Errorf("expecting { f }, found %v", sels)
+	}
+
+	doc, err = ParseDocument([]byte(`query Q($a: ) { a }`), "", token.NewFileSet())
+	if list, ok := err.(ErrorList); !ok || len(list) != 1 || list[0].Error() != "1:13: expecting [, found ')'" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(doc.Defs) != 0 {
+		t.Errorf("expecting no definitions, found %d", len(doc.Defs))
+	}
+
+	s, err := ParseSchema([]byte(`
+type A { a: }
+interface B { b: Int }
+type F { f: [Int }
+union G = H | I
+scalar`), "", token.NewFileSet())
+
+	list, ok = err.(ErrorList)
+	if !ok {
+		t.Fatalf("expecting ErrorList, found %v", err)
+	}
+	expected = []string{
+		"2:13: expecting [, found '}'",
+		"4:18: expecting ], found '}'",
+		"6:6: expecting NAME, found '<EOF>'",
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("expecting %d errors, found %v", len(expected), list)
+	}
+	for i, e := range list {
+		if e.Error() != expected[i] {
+			t.Errorf("expecting %s, found %s", expected[i], e)
+		}
+	}
+	if len(s.Interfaces) != 1 || len(s.Unions) != 1 || len(s.Types) != 0 {
+		t.Errorf("unexpected definitions %+v", s)
+	}
+
+	// parens and brackets left open are closed along with the enclosing
+	// braces, and keywords and strings inside them do not start a definition
+	doc, err = ParseDocument([]byte(`query A { a(x: {b: 1) }
+query B { c }
+query C { d( }
+query D { e(x: [1, query]) f(y: "query" }
+query E { g }`), "", token.NewFileSet())
+	list, ok = err.(ErrorList)
+	if !ok {
+		t.Fatalf("expecting ErrorList, found %v", err)
+	}
+	expected = []string{
+		"1:21: expecting NAME, found ')'",
+		"3:14: expecting NAME, found '}'",
+		"4:41: expecting NAME, found '}'",
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("expecting %d errors, found %v", len(expected), list)
+	}
+	for i, e := range list {
+		if e.Error() != expected[i] {
+			t.Errorf("expecting %s, found %s", expected[i], e)
+		}
+	}
+	if len(doc.Defs) != 2 {
+		t.Errorf("expecting 2 definitions, found %d", len(doc.Defs))
+	}
+
+	s, err = ParseSchema([]byte(`type A { a(arg: type = "type" b: Int }
+"B" type B { b: Int }
+type C { c(x: [Int = [type]) d: }
+"D"
+type D { d: Int }`), "", token.NewFileSet())
+	list, ok = err.(ErrorList)
+	if !ok || len(list) != 2 || list[0].Error() != "1:38: expecting NAME, found '}'" || list[1].Error() != "3:20: expecting ], found '='" {
+		t.Errorf("unexpected errors %v", err)
+	}
+	if len(s.Types) != 2 || s.Types[0].Name.Text != "B" || s.Types[1].Name.Text != "D" {
+		t.Errorf("unexpected definitions %+v", s.Types)
+	}
+}
+
+func TestErrorListSort(t *testing.T) {
+	var list ErrorList
+	list.Add(token.Position{Line: 2, Column: 1}, "b", "x")
+	list.Add(token.Position{Line: 1, Column: 5}, "a", "y")
+	list.Add(token.Position{Line: 1, Column: 3}, "c", "z")
+	list.Sort()
+	for i, line := range []int{1, 1, 2} {
+		if list[i].Pos.Line != line {
+			t.Errorf("expecting line %d, found %v", line, list[i].Pos)
+		}
+	}
+	if list[0].Found != "z" {
+		t.Errorf("expecting z first, found %s", list[0].Found)
+	}
+
+	list.Reset()
+	if list.Err() != nil {
+		t.Errorf("expecting nil, found %v", list.Err())
+	}
+}
+
+func TestParseDescriptions(t *testing.T) {
+	fset := token.NewFileSet()
+	s, err := ParseSchema([]byte(`
+"""
+The root query.
+"""
+type Query {
+  "A friend of the viewer."
+  friend(
+    "The id of the friend."
+    id: ID!
+  ): String
+}
+
+"Episodes of the saga."
+enum Episode {
+  "Released in 1977."
+  NEWHOPE
+}
+
+"A scalar." scalar Time
+`), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typ := s.Types[0]
+	assertEqual(t, "The root query.", typ.Desc.Val.Text)
+	assertEqual(t, "2:1", fset.Position(typ.Pos()).String())
+	assertEqual(t, "4:4", fset.Position(typ.Desc.End()).String())
+
+	field := typ.FieldDefns[0]
+	assertEqual(t, "A friend of the viewer.", field.Desc.Val.Text)
+	assertEqual(t, "6:3", fset.Position(field.Pos()).String())
+	assertEqual(t, "The id of the friend.", field.ArgDefns.InputValDefns[0].Desc.Val.Text)
+
+	enum := s.Enums[0]
+	assertEqual(t, "Episodes of the saga.", enum.Desc.Val.Text)
+	assertEqual(t, "Released in 1977.", enum.EnumVals[0].Desc.Val.Text)
+	assertEqual(t, "A scalar.", s.Scalars[0].Desc.Val.Text)
+
+	if _, err := ParseSchema([]byte(`extend "not allowed" type Query { a: Int }`), "", token.NewFileSet()); err == nil {
+		t.Error("expecting error for description of extension")
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	const source = `# the viewer
+# and friends
+query Viewer {
+  # the name
+  name # trailing
+  ...Friends
+
+  # unattached
+
+  id
+} # end
+
+# about friends
+fragment Friends on User { friends { id } }
+`
+
+	fset := token.NewFileSet()
+	doc, err := ParseDocument([]byte(source), "", fset, ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oper := doc.Defs[0].(*OperationDefinition)
+	assertEqual(t, "the viewer\nand friends\n", oper.Doc.Text())
+	assertEqual(t, "1:1", fset.Position(oper.Doc.Pos()).String())
+	assertEqual(t, "end\n", oper.Comment.Text())
+
+	name := oper.SelSet.Sels[0].(*Field)
+	assertEqual(t, "the name\n", name.Doc.Text())
+	assertEqual(t, "trailing\n", name.Comment.Text())
+	assertEqual(t, "5:18", fset.Position(name.Comment.End()).String())
+
+	spread := oper.SelSet.Sels[1].(*FragmentSpread)
+	if spread.Doc != nil || spread.Comment != nil {
+		t.Errorf("expecting no comments of spread, found %v %v", spread.Doc, spread.Comment)
+	}
+	if id := oper.SelSet.Sels[2].(*Field); id.Doc != nil {
+		t.Errorf("expecting no lead comment separated by a blank line, found %q", id.Doc.Text())
+	}
+
+	frag := doc.Defs[1].(*FragmentDefinition)
+	assertEqual(t, "about friends\n", frag.Doc.Text())
+
+	var texts []string
+	for _, g := range doc.Comments {
+		texts = append(texts, g.Text())
+	}
+	assertEqual(t, []string{"the viewer\nand friends\n", "the name\n", "trailing\n", "unattached\n", "end\n", "about friends\n"}, texts)
+
+	doc, err = ParseDocument([]byte(source), "", token.NewFileSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Comments != nil || doc.Defs[0].(*OperationDefinition).Doc != nil {
+		t.Error("expecting no comments without ParseComments")
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	fset := token.NewFileSet()
+	s, err := ParseSchema([]byte(`
+extend schema @dir { subscription: Subscription }
+extend scalar Time @dir
+extend type User implements Node @dir
+extend type User { name: String }
+extend interface Node @dir
+extend interface Node { id: ID }
+extend union Result @dir
+extend union Result = Photo | Video
+extend enum Kind @dir
+extend enum Kind { C }
+extend input Filter @dir
+extend input Filter { text: String }
+`), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exts := s.TypeSystemExtends
+	assertEqual(t, 10, len(exts))
+	assertEqual(t, "subscription", exts[0].Defn.(*SchemaDefinition).OperDefns[0].OperType.Text)
+	assertEqual(t, "Time", exts[1].Defn.(*ScalarDefinition).Name.Text)
+	assertEqual(t, 2, len(s.Extends))
+	assertEqual(t, "Node", s.Extends[0].TypDefn.Implements.NamedTyps[0].Name.Text)
+	assertEqual(t, "5:1", fset.Position(s.Extends[1].Pos()).String())
+	assertEqual(t, "5:34", fset.Position(s.Extends[1].End()).String())
+	assertEqual(t, "Node", exts[3].Defn.(*InterfaceDefinition).Name.Text)
+	assertEqual(t, "9:36", fset.Position(exts[5].End()).String())
+	assertEqual(t, "C", exts[7].Defn.(*EnumDefinition).EnumVals[0].Name.Text)
+	assertEqual(t, "12:1", fset.Position(exts[8].Pos()).String())
+	assertEqual(t, "12:25", fset.Position(exts[8].End()).String())
+
+	invalids := []string{
+		`extend scalar Time`,
+		`extend type User`,
+		`extend interface Node`,
+		`extend union Result`,
+		`extend enum Kind`,
+		`extend input Filter`,
+		`extend schema`,
+		`extend "description" enum Kind { C }`,
+	}
+	for _, invalid := range invalids {
+		if _, err := ParseSchema([]byte(invalid), "", token.NewFileSet()); err == nil {
+			t.Errorf("expecting error for %s", invalid)
+		}
+	}
+}
+
+func TestParseInterfaceImplements(t *testing.T) {
+	fset := token.NewFileSet()
+	s, err := ParseSchema([]byte(`interface Resource implements Node & Entity { id: ID }
+type Image implements & Resource & Node & Entity { id: ID }
+type Legacy implements Node Entity { id: ID }
+extend interface Node implements Entity
+`), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource := s.Interfaces[0]
+	assertEqual(t, 2, len(resource.Implements.NamedTyps))
+	assertEqual(t, "Entity", resource.Implements.NamedTyps[1].Name.Text)
+	assertEqual(t, "1:36", fset.Position(resource.Implements.Amps[0]).String())
+	assertEqual(t, 3, len(s.Types[0].Implements.NamedTyps))
+	assertEqual(t, 3, len(s.Types[0].Implements.Amps))
+	assertEqual(t, 2, len(s.Types[1].Implements.NamedTyps))
+	assertEqual(t, 0, len(s.Types[1].Implements.Amps))
+	assertEqual(t, "Entity", s.TypeSystemExtends[0].Defn.(*InterfaceDefinition).Implements.NamedTyps[0].Name.Text)
+
+	if _, err := ParseSchema([]byte(`type Image implements Node & { id: ID }`), "", token.NewFileSet()); err == nil {
+		t.Error("expecting error for trailing &")
+	}
+
+	// the interfaces of an extension without body end at the next definition
+	s, err = ParseSchema([]byte(`extend type A implements B
+type C { c: Int }
+extend interface X implements Y Z
+"description"
+interface W { w: Int }
+extend type D implements E & F
+extend type D implements G H
+scalar S`), "", token.NewFileSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, named := range s.Extends[0].TypDefn.Implements.NamedTyps {
+		names = append(names, named.Name.Text)
+	}
+	assertEqual(t, []string{"B"}, names)
+	assertEqual(t, "C", s.Types[0].Name.Text)
+	assertEqual(t, 2, len(s.TypeSystemExtends[0].Defn.(*InterfaceDefinition).Implements.NamedTyps))
+	assertEqual(t, "W", s.Interfaces[0].Name.Text)
+	assertEqual(t, 2, len(s.Extends[1].TypDefn.Implements.NamedTyps))
+	assertEqual(t, 2, len(s.Extends[2].TypDefn.Implements.NamedTyps))
+	assertEqual(t, "S", s.Scalars[0].Name.Text)
+}
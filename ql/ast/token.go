@@ -37,6 +37,7 @@ const (
 	LBRACE // {
 	PIPE   // |
 	RBRACE // }
+	AMP    // &
 	punctEnd
 
 	NAME // query
@@ -65,6 +66,7 @@ const (
 	INPUT
 	ENUM
 	DIRECTIVE
+	REPEATABLE
 	reservedEnd
 )
 
@@ -84,6 +86,7 @@ var tokens = map[Kind]string{
 	LBRACE:       "{",
 	PIPE:         "|",
 	RBRACE:       "}",
+	AMP:          "&",
 	NAME:         "NAME",
 	INT:          "INT",
 	FLOAT:        "FLOAT",
@@ -103,6 +106,7 @@ var tokens = map[Kind]string{
 	INPUT:        "input",
 	ENUM:         "enum",
 	DIRECTIVE:    "directive",
+	REPEATABLE:   "repeatable",
 }
 
 var puncts = map[rune]Kind{
@@ -118,6 +122,7 @@ var puncts = map[rune]Kind{
 	'{': LBRACE,
 	'|': PIPE,
 	'}': RBRACE,
+	'&': AMP,
 }
 
 // String returns the string corresponding to the token tok.
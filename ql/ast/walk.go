@@ -41,6 +41,9 @@ func Walk(v Visitor, node Node) {
 			Walk(v, def)
 		}
 	case *OperationDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
 		if n.VarDefns != nil {
 			Walk(v, n.VarDefns)
 		}
@@ -48,11 +51,17 @@ func Walk(v Visitor, node Node) {
 			Walk(v, n.Directs)
 		}
 		Walk(v, n.SelSet)
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *SelectionSet:
 		for _, s := range n.Sels {
 			Walk(v, s)
 		}
 	case *Field:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
 		if n.Als != nil {
 			Walk(v, n.Als)
 		}
@@ -65,6 +74,9 @@ func Walk(v Visitor, node Node) {
 		if n.SelSet != nil {
 			Walk(v, n.SelSet)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *Alias:
 		// do nothing
 	case *Arguments:
@@ -74,10 +86,19 @@ func Walk(v Visitor, node Node) {
 	case *Argument:
 		Walk(v, n.Val)
 	case *FragmentSpread:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *InlineFragment:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
 		if n.TypeCond != nil {
 			Walk(v, n.TypeCond)
 		}
@@ -85,12 +106,21 @@ func Walk(v Visitor, node Node) {
 			Walk(v, n.Directs)
 		}
 		Walk(v, n.SelSet)
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *FragmentDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
 		Walk(v, n.TypeCond)
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
 		Walk(v, n.SelSet)
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *TypeCondition:
 		Walk(v, n.NamedTyp)
 	case *Variable, *LiteralValue, *NameValue:
@@ -115,6 +145,9 @@ func Walk(v Visitor, node Node) {
 		if n.DeflVal != nil {
 			Walk(v, n.DeflVal)
 		}
+		if n.Directs != nil {
+			Walk(v, n.Directs)
+		}
 	case *DefaultValue:
 		Walk(v, n.Val)
 	case *NamedType:
@@ -157,14 +190,35 @@ func Walk(v Visitor, node Node) {
 		for _, union := range n.Unions {
 			Walk(v, union)
 		}
+		for _, extend := range n.TypeSystemExtends {
+			Walk(v, extend)
+		}
 	case *InterfaceDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
+		if n.Implements != nil {
+			Walk(v, n.Implements)
+		}
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
 		for _, fd := range n.FieldDefns {
 			Walk(v, fd)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *FieldDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.ArgDefns != nil {
 			Walk(v, n.ArgDefns)
 		}
@@ -172,11 +226,20 @@ func Walk(v Visitor, node Node) {
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *ArgumentsDefinition:
 		for _, input := range n.InputValDefns {
 			Walk(v, input)
 		}
 	case *InputValueDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		Walk(v, n.Typ)
 		if n.DeflVal != nil {
 			Walk(v, n.DeflVal)
@@ -184,18 +247,45 @@ func Walk(v Visitor, node Node) {
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *ScalarDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *InputObjectDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
 		for _, input := range n.InputValDefns {
 			Walk(v, input)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *TypeDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.Implements != nil {
 			Walk(v, n.Implements)
 		}
@@ -205,56 +295,124 @@ func Walk(v Visitor, node Node) {
 		for _, fd := range n.FieldDefns {
 			Walk(v, fd)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *ImplementsInterfaces:
 		for _, namdTyp := range n.NamedTyps {
 			Walk(v, namdTyp)
 		}
 	case *ExtendDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
 		if n.TypDefn != nil {
 			Walk(v, n.TypDefn)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
+	case *TypeSystemExtension:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		Walk(v, n.Defn)
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *DirectiveDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.Args != nil {
 			Walk(v, n.Args)
 		}
 		if n.Locs != nil {
 			Walk(v, n.Locs)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *DirectiveLocations:
 		for _, l := range n.Locs {
 			Walk(v, l)
 		}
 	case *DirectiveLocation:
 		// do nothing
+	case *CommentGroup:
+		for _, c := range n.List {
+			Walk(v, c)
+		}
+	case *Comment, *Description:
+		// do nothing
 	case *SchemaDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
 		for _, o := range n.OperDefns {
 			Walk(v, o)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *OperationTypeDefinition:
 		if n.NamedTyp != nil {
 			Walk(v, n.NamedTyp)
 		}
 	case *EnumDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
 		for _, e := range n.EnumVals {
 			Walk(v, e)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *EnumValue:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *UnionDefinition:
+		if n.Doc != nil {
+			Walk(v, n.Doc)
+		}
+		if n.Desc != nil {
+			Walk(v, n.Desc)
+		}
 		if n.Directs != nil {
 			Walk(v, n.Directs)
 		}
 		if n.Members != nil {
 			Walk(v, n.Members)
 		}
+		if n.Comment != nil {
+			Walk(v, n.Comment)
+		}
 	case *UnionMembers:
 		if n.NamedTyp != nil {
 			Walk(v, n.NamedTyp)
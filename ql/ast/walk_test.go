@@ -50,7 +50,7 @@ directive @include2(if: Boolean!) on
 	| INLINE_FRAGMENT`
 
 	fset := token.NewFileSet()
-	p := newParser([]byte(schemas), "", fset)
+	p := newParser([]byte(schemas), "", fset, 0)
 	def, _ := p.parseSchema()
 
 	Inspect(def, func(n Node) bool {
@@ -148,7 +148,7 @@ fragment frag on Friend {
 }`
 
 	fset := token.NewFileSet()
-	p := newParser([]byte(document), "", fset)
+	p := newParser([]byte(document), "", fset, 0)
 	doc, _ := p.parseDocument()
 
 	Inspect(doc, func(n Node) bool {
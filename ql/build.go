@@ -0,0 +1,819 @@
+package ql
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"sort"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// BuildSchema returns a Schema built from the type definitions in schema. Named
+// types can be referenced before they are defined, and types can reference
+// themselves. The root operation types are taken from the schema definition if
+// there is one, otherwise the types named Query, Mutation and Subscription are
+// used. Extensions are applied to the definitions they extend in the order of
+// appearance, wherever they are defined. Directives used in schema are checked
+// against their definitions and carried by the types they are applied to.
+func BuildSchema(fset *token.FileSet, schema *ast.Schema) (*Schema, error) {
+	if fset == nil {
+		return nil, errors.New("no token.FileSet provided (fset == nil)")
+	}
+	if schema == nil {
+		return nil, errors.New("no ast.Schema provided (schema == nil)")
+	}
+
+	b := &builder{
+		fset:       fset,
+		types:      map[string]Type{},
+		directives: map[string]*Directive{},
+	}
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		b.types[scalar.Name] = scalar
+	}
+	for _, direct := range builtinDirectives {
+		b.directives[direct.Name] = direct
+	}
+
+	schema, err := b.merge(schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.declare(schema); err != nil {
+		return nil, err
+	}
+	if err := b.define(schema); err != nil {
+		return nil, err
+	}
+	if err := b.coerceDefaults(); err != nil {
+		return nil, err
+	}
+	s, err := b.schema(schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.applyDirectives(); err != nil {
+		return nil, err
+	}
+	s.Directives = b.defined
+	return s, nil
+}
+
+// builder builds a Schema in two passes: all named types are declared first so
+// that definitions can refer to each other, and then they are defined.
+type builder struct {
+	fset       *token.FileSet
+	types      map[string]Type
+	typs       []Type
+	defaults   []defaultValue
+	directives map[string]*Directive
+	defined    []*Directive // directives defined in the schema
+	usages     []directiveUsage
+}
+
+func (b *builder) errorf(pos token.Pos, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", b.fset.Position(pos), fmt.Sprintf(format, args...))
+}
+
+// defaultValue of an argument or input field, it is coerced into defl after all
+// types are defined.
+type defaultValue struct {
+	typ  Type
+	val  ast.Value
+	defl *interface{}
+}
+
+// directiveUsage is where directives are used in the schema, they are applied
+// to the type system after all types and directives are defined.
+type directiveUsage struct {
+	directs *ast.Directives
+	loc     string
+	applied *[]*AppliedDirective
+}
+
+// use records the directives used at loc, which are applied to applied.
+func (b *builder) use(directs *ast.Directives, loc string, applied *[]*AppliedDirective) {
+	if directs != nil {
+		b.usages = append(b.usages, directiveUsage{directs, loc, applied})
+	}
+}
+
+// declaration of a named type.
+type declaration struct {
+	name ast.Token
+	pos  token.Pos
+	typ  Type
+}
+
+func (b *builder) declare(schema *ast.Schema) error {
+	var decls []declaration
+	for _, defn := range schema.Scalars {
+		url, _, err := b.stringArg(defn.Directs, "specifiedBy", "url")
+		if err != nil {
+			return err
+		}
+		scalar := &Scalar{Name: defn.Name.Text, Desc: description(defn.Desc), SpecifiedBy: url}
+		b.use(defn.Directs, "SCALAR", &scalar.Directs)
+		decls = append(decls, declaration{defn.Name, defn.NamePos, scalar})
+	}
+	for _, defn := range schema.Types {
+		obj := &Object{Name: defn.Name.Text, Desc: description(defn.Desc)}
+		b.use(defn.Directs, "OBJECT", &obj.Directs)
+		decls = append(decls, declaration{defn.Name, defn.NamePos, obj})
+	}
+	for _, defn := range schema.Interfaces {
+		iface := &Interface{Name: defn.Name.Text, Desc: description(defn.Desc)}
+		b.use(defn.Directs, "INTERFACE", &iface.Directs)
+		decls = append(decls, declaration{defn.Name, defn.NamePos, iface})
+	}
+	for _, defn := range schema.Unions {
+		union := &Union{Name: defn.Name.Text, Desc: description(defn.Desc)}
+		b.use(defn.Directs, "UNION", &union.Directs)
+		decls = append(decls, declaration{defn.Name, defn.NamePos, union})
+	}
+	for _, defn := range schema.Enums {
+		enum := &Enum{Name: defn.Name.Text, Desc: description(defn.Desc)}
+		b.use(defn.Directs, "ENUM", &enum.Directs)
+		for _, ev := range defn.EnumVals {
+			deprecated, err := b.deprecated(ev.Directs)
+			if err != nil {
+				return err
+			}
+			value := &EnumValue{Name: ev.Name.Text, Desc: description(ev.Desc), Deprecated: deprecated}
+			b.use(ev.Directs, "ENUM_VALUE", &value.Directs)
+			enum.Values = append(enum.Values, value)
+		}
+		decls = append(decls, declaration{defn.Name, defn.NamePos, enum})
+	}
+	for _, defn := range schema.InputObjects {
+		iobj := &InputObject{Name: defn.Name.Text, Desc: description(defn.Desc)}
+		b.use(defn.Directs, "INPUT_OBJECT", &iobj.Directs)
+		decls = append(decls, declaration{defn.Name, defn.NamePos, iobj})
+	}
+
+	// declare in the order of appearance
+	sort.Slice(decls, func(i, j int) bool {
+		return decls[i].pos < decls[j].pos
+	})
+
+	for _, decl := range decls {
+		if _, ok := b.types[decl.name.Text]; ok {
+			return b.errorf(decl.pos, "type %s defined more than once", decl.name.Text)
+		}
+		b.types[decl.name.Text] = decl.typ
+		b.typs = append(b.typs, decl.typ)
+	}
+	return nil
+}
+
+func (b *builder) define(schema *ast.Schema) error {
+	var err error
+	for _, defn := range schema.Directives {
+		if err = b.directive(defn); err != nil {
+			return err
+		}
+	}
+
+	for _, defn := range schema.Types {
+		obj := b.types[defn.Name.Text].(*Object)
+		if defn.Implements != nil {
+			for _, named := range defn.Implements.NamedTyps {
+				var iface *Interface
+				if iface, err = b.iface(named); err != nil {
+					return err
+				}
+				obj.Ifaces = append(obj.Ifaces, iface)
+			}
+		}
+		if obj.Fields, err = b.fields(defn.FieldDefns); err != nil {
+			return err
+		}
+	}
+
+	for _, defn := range schema.Interfaces {
+		iface := b.types[defn.Name.Text].(*Interface)
+		if defn.Implements != nil {
+			for _, named := range defn.Implements.NamedTyps {
+				var super *Interface
+				if super, err = b.iface(named); err != nil {
+					return err
+				}
+				iface.Ifaces = append(iface.Ifaces, super)
+			}
+		}
+		if iface.Fields, err = b.fields(defn.FieldDefns); err != nil {
+			return err
+		}
+	}
+
+	for _, defn := range schema.Unions {
+		union := b.types[defn.Name.Text].(*Union)
+		named := []*ast.NamedType{defn.Members.NamedTyp}
+		for _, m := range defn.Members.Members {
+			named = append(named, m.NamedTyp)
+		}
+		for _, n := range named {
+			var typ Type
+			if typ, err = b.namedType(n); err != nil {
+				return err
+			}
+			union.Typs = append(union.Typs, typ)
+		}
+	}
+
+	for _, defn := range schema.InputObjects {
+		iobj := b.types[defn.Name.Text].(*InputObject)
+		for _, input := range defn.InputValDefns {
+			var typ Type
+			if typ, err = b.resolveType(input.Typ); err != nil {
+				return err
+			}
+			field := &Field{Name: input.Name.Text, Desc: description(input.Desc), Typ: typ}
+			if field.Deprecated, err = b.deprecated(input.Directs); err != nil {
+				return err
+			}
+			b.use(input.Directs, "INPUT_FIELD_DEFINITION", &field.Directs)
+			if input.DeflVal != nil {
+				b.defaults = append(b.defaults, defaultValue{typ, input.DeflVal.Val, &field.Defl})
+			}
+			iobj.Fields = append(iobj.Fields, field)
+		}
+	}
+	return nil
+}
+
+func (b *builder) coerceDefaults() error {
+	for _, d := range b.defaults {
+		defl, err := coerceLiteralValue(d.typ, d.val, nil)
+		if err != nil {
+			return b.errorf(d.val.Pos(), "%s", err)
+		}
+		*d.defl = defl
+	}
+	return nil
+}
+
+// directive defines the directive defined by defn.
+func (b *builder) directive(defn *ast.DirectiveDefinition) error {
+	name := defn.Name.Text
+	if _, ok := b.directives[name]; ok {
+		return b.errorf(defn.NamePos, "directive @%s defined more than once", name)
+	}
+	direct := &Directive{Name: name, Desc: description(defn.Desc), Repeatable: defn.Repeat.IsValid()}
+
+	first := &ast.DirectiveLocation{Name: defn.Locs.Name, NamePos: defn.Locs.NamePos}
+	for _, loc := range append([]*ast.DirectiveLocation{first}, defn.Locs.Locs...) {
+		if indexOf(len(directiveLocations), func(i int) bool { return directiveLocations[i] == loc.Name.Text }) < 0 {
+			return b.errorf(loc.NamePos, "unknown directive location %s", loc.Name.Text)
+		}
+		direct.Locs = append(direct.Locs, loc.Name.Text)
+	}
+
+	if defn.Args != nil {
+		for _, input := range defn.Args.InputValDefns {
+			argDef, err := b.argDef(input)
+			if err != nil {
+				return err
+			}
+			direct.Defs = append(direct.Defs, argDef)
+		}
+	}
+	b.directives[name] = direct
+	b.defined = append(b.defined, direct)
+	return nil
+}
+
+// applyDirectives applies the directives used in the schema, each one must be
+// defined, allowed at the location it is used and given valid arguments. Only
+// repeatable directives can be used more than once at a location.
+func (b *builder) applyDirectives() error {
+	for _, usage := range b.usages {
+		used := map[string]bool{}
+		for _, direct := range usage.directs.Directs {
+			name := direct.Name.Text
+			def, ok := b.directives[name]
+			if !ok {
+				return b.errorf(direct.NamePos, "unknown directive @%s", name)
+			}
+			if indexOf(len(def.Locs), func(i int) bool { return def.Locs[i] == usage.loc }) < 0 {
+				return b.errorf(direct.NamePos, "directive @%s may not be used on %s", name, usage.loc)
+			}
+			if used[name] && !def.Repeatable {
+				return b.errorf(direct.NamePos, "directive @%s can only be used once at this location", name)
+			}
+			used[name] = true
+
+			args, err := b.directiveArgs(direct, def)
+			if err != nil {
+				return err
+			}
+			*usage.applied = append(*usage.applied, &AppliedDirective{Name: name, Args: args})
+		}
+	}
+	return nil
+}
+
+// directiveArgs returns the coerced arguments of direct, the ones not given
+// take the default values of def.
+func (b *builder) directiveArgs(direct *ast.Directive, def *Directive) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if direct.Args != nil {
+		for _, arg := range direct.Args.Args {
+			argDef := findArgDef(def.Defs, arg.Name.Text)
+			if argDef == nil {
+				return nil, b.errorf(arg.NamePos, "unknown argument %s of directive @%s", arg.Name.Text, def.Name)
+			}
+			if _, ok := args[arg.Name.Text]; ok {
+				return nil, b.errorf(arg.NamePos, "argument %s of directive @%s given more than once", arg.Name.Text, def.Name)
+			}
+			val, err := coerceLiteralValue(argDef.Typ, arg.Val, nil)
+			if err != nil {
+				return nil, b.errorf(arg.Val.Pos(), "%s", err)
+			}
+			args[arg.Name.Text] = val
+		}
+	}
+
+	for _, argDef := range def.Defs {
+		if _, ok := args[argDef.Name]; ok {
+			continue
+		}
+		if argDef.Defl != nil {
+			args[argDef.Name] = argDef.Defl
+		} else if isNonNull(argDef.Typ) {
+			return nil, b.errorf(direct.NamePos, "argument %s of directive @%s is required", argDef.Name, def.Name)
+		}
+	}
+	return args, nil
+}
+
+func (b *builder) schema(schema *ast.Schema) (*Schema, error) {
+	s := &Schema{Typs: b.typs}
+
+	if len(schema.Schemas) > 1 {
+		return nil, b.errorf(schema.Schemas[1].Pos(), "schema defined more than once")
+	}
+
+	if len(schema.Schemas) == 0 {
+		s.Qry, _ = b.types["Query"].(*Object)
+		s.Mut, _ = b.types["Mutation"].(*Object)
+		s.Sub, _ = b.types["Subscription"].(*Object)
+		return s, nil
+	}
+
+	b.use(schema.Schemas[0].Directs, "SCHEMA", &s.Directs)
+	for _, oper := range schema.Schemas[0].OperDefns {
+		typ, err := b.namedType(oper.NamedTyp)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := typ.(*Object)
+		if !ok {
+			return nil, b.errorf(oper.NamedTyp.NamePos, "%s root type %s must be an object type", oper.OperType.Text, oper.NamedTyp.Name.Text)
+		}
+
+		switch oper.OperType.Text {
+		case ast.Stringify(ast.QUERY):
+			s.Qry = obj
+		case ast.Stringify(ast.MUTATION):
+			s.Mut = obj
+		case ast.Stringify(ast.SUBSCRIPTION):
+			s.Sub = obj
+		}
+	}
+	return s, nil
+}
+
+// merge returns a copy of schema in which the extended definitions are replaced
+// by the ones with their extensions applied, schema is left unchanged.
+// Extensions cannot add a field, value, member or interface twice.
+func (b *builder) merge(schema *ast.Schema) (*ast.Schema, error) {
+	merged := *schema
+	merged.Extends = nil
+	merged.TypeSystemExtends = nil
+	merged.Scalars = append([]*ast.ScalarDefinition(nil), schema.Scalars...)
+	merged.Types = append([]*ast.TypeDefinition(nil), schema.Types...)
+	merged.Interfaces = append([]*ast.InterfaceDefinition(nil), schema.Interfaces...)
+	merged.Unions = append([]*ast.UnionDefinition(nil), schema.Unions...)
+	merged.Enums = append([]*ast.EnumDefinition(nil), schema.Enums...)
+	merged.InputObjects = append([]*ast.InputObjectDefinition(nil), schema.InputObjects...)
+
+	var schemaExts []*ast.SchemaDefinition
+	extensions := make([]ast.Node, 0, len(schema.Extends)+len(schema.TypeSystemExtends))
+	for _, ext := range schema.Extends {
+		extensions = append(extensions, ext.TypDefn)
+	}
+	for _, ext := range schema.TypeSystemExtends {
+		extensions = append(extensions, ext.Defn)
+	}
+	for _, ext := range extensions {
+		var err error
+		switch ext := ext.(type) {
+		case *ast.SchemaDefinition:
+			schemaExts = append(schemaExts, ext)
+		case *ast.ScalarDefinition:
+			err = b.extendScalar(&merged, ext)
+		case *ast.TypeDefinition:
+			err = b.extendType(&merged, ext)
+		case *ast.InterfaceDefinition:
+			err = b.extendInterface(&merged, ext)
+		case *ast.UnionDefinition:
+			err = b.extendUnion(&merged, ext)
+		case *ast.EnumDefinition:
+			err = b.extendEnum(&merged, ext)
+		case *ast.InputObjectDefinition:
+			err = b.extendInputObject(&merged, ext)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(schemaExts) > 0 {
+		var defn ast.SchemaDefinition
+		if len(schema.Schemas) > 0 {
+			defn = *schema.Schemas[0]
+		}
+		defn.OperDefns = append([]*ast.OperationTypeDefinition(nil), defn.OperDefns...)
+		for _, ext := range schemaExts {
+			for _, oper := range ext.OperDefns {
+				if hasOperation(defn.OperDefns, oper.OperType.Text) {
+					return nil, b.errorf(oper.OperPos, "%s root type defined more than once", oper.OperType.Text)
+				}
+				defn.OperDefns = append(defn.OperDefns, oper)
+			}
+			defn.Directs = mergeDirectives(defn.Directs, ext.Directs)
+		}
+		if len(schema.Schemas) == 0 {
+			// extending the implicit schema, the default root types remain
+			// unless they are given by the extensions
+			for _, oper := range defaultOperations(schema) {
+				if !hasOperation(defn.OperDefns, oper.OperType.Text) {
+					defn.OperDefns = append(defn.OperDefns, oper)
+				}
+			}
+		}
+		merged.Schemas = []*ast.SchemaDefinition{&defn}
+		if len(schema.Schemas) > 1 { // reported as defined more than once
+			merged.Schemas = append(merged.Schemas, schema.Schemas[1:]...)
+		}
+	}
+	return &merged, nil
+}
+
+// extended returns the index of the definition named by the extension among n
+// definitions of kind, whose names are given by nameAt.
+func (b *builder) extended(kind string, name ast.Token, namePos token.Pos, n int, nameAt func(int) string) (int, error) {
+	i := indexOf(n, func(i int) bool { return nameAt(i) == name.Text })
+	if i < 0 {
+		return -1, b.errorf(namePos, "cannot extend undefined %s %s", kind, name.Text)
+	}
+	return i, nil
+}
+
+func (b *builder) extendScalar(merged *ast.Schema, ext *ast.ScalarDefinition) error {
+	// built-in scalars are shared, directives are only applied to the scalars
+	// defined in schema
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		if scalar.Name == ext.Name.Text {
+			return nil
+		}
+	}
+	i, err := b.extended("scalar", ext.Name, ext.NamePos, len(merged.Scalars), func(i int) string { return merged.Scalars[i].Name.Text })
+	if err != nil {
+		return err
+	}
+	defn := *merged.Scalars[i]
+	defn.Directs = mergeDirectives(defn.Directs, ext.Directs)
+	merged.Scalars[i] = &defn
+	return nil
+}
+
+func (b *builder) extendType(merged *ast.Schema, ext *ast.TypeDefinition) error {
+	i, err := b.extended("type", ext.Name, ext.NamePos, len(merged.Types), func(i int) string { return merged.Types[i].Name.Text })
+	if err != nil {
+		return err
+	}
+	defn := *merged.Types[i]
+	if defn.Implements, err = b.mergeImplements("type "+defn.Name.Text, defn.Implements, ext.Implements); err != nil {
+		return err
+	}
+	defn.Directs = mergeDirectives(defn.Directs, ext.Directs)
+	if defn.FieldDefns, err = b.mergeFields(defn.Name.Text, defn.FieldDefns, ext.FieldDefns); err != nil {
+		return err
+	}
+	merged.Types[i] = &defn
+	return nil
+}
+
+func (b *builder) extendInterface(merged *ast.Schema, ext *ast.InterfaceDefinition) error {
+	i, err := b.extended("interface", ext.Name, ext.NamePos, len(merged.Interfaces), func(i int) string { return merged.Interfaces[i].Name.Text })
+	if err != nil {
+		return err
+	}
+	defn := *merged.Interfaces[i]
+	if defn.Implements, err = b.mergeImplements("interface "+defn.Name.Text, defn.Implements, ext.Implements); err != nil {
+		return err
+	}
+	defn.Directs = mergeDirectives(defn.Directs, ext.Directs)
+	if defn.FieldDefns, err = b.mergeFields(defn.Name.Text, defn.FieldDefns, ext.FieldDefns); err != nil {
+		return err
+	}
+	merged.Interfaces[i] = &defn
+	return nil
+}
+
+func (b *builder) extendUnion(merged *ast.Schema, ext *ast.UnionDefinition) error {
+	i, err := b.extended("union", ext.Name, ext.NamePos, len(merged.Unions), func(i int) string { return merged.Unions[i].Name.Text })
+	if err != nil {
+		return err
+	}
+	defn := *merged.Unions[i]
+	defn.Directs = mergeDirectives(defn.Directs, ext.Directs)
+	if ext.Members != nil {
+		members := *defn.Members
+		members.Members = append([]*ast.UnionMember(nil), members.Members...)
+		named := []*ast.NamedType{ext.Members.NamedTyp}
+		for _, m := range ext.Members.Members {
+			named = append(named, m.NamedTyp)
+		}
+		for _, n := range named {
+			if n.Name.Text == members.NamedTyp.Name.Text || indexOf(len(members.Members), func(i int) bool { return members.Members[i].NamedTyp.Name.Text == n.Name.Text }) >= 0 {
+				return b.errorf(n.NamePos, "member %s of union %s defined more than once", n.Name.Text, defn.Name.Text)
+			}
+			members.Members = append(members.Members, &ast.UnionMember{NamedTyp: n})
+		}
+		defn.Members = &members
+	}
+	merged.Unions[i] = &defn
+	return nil
+}
+
+func (b *builder) extendEnum(merged *ast.Schema, ext *ast.EnumDefinition) error {
+	i, err := b.extended("enum", ext.Name, ext.NamePos, len(merged.Enums), func(i int) string { return merged.Enums[i].Name.Text })
+	if err != nil {
+		return err
+	}
+	defn := *merged.Enums[i]
+	defn.Directs = mergeDirectives(defn.Directs, ext.Directs)
+	defn.EnumVals = append([]*ast.EnumValue(nil), defn.EnumVals...)
+	for _, ev := range ext.EnumVals {
+		if indexOf(len(defn.EnumVals), func(i int) bool { return defn.EnumVals[i].Name.Text == ev.Name.Text }) >= 0 {
+			return b.errorf(ev.Pos(), "value %s of enum %s defined more than once", ev.Name.Text, defn.Name.Text)
+		}
+		defn.EnumVals = append(defn.EnumVals, ev)
+	}
+	merged.Enums[i] = &defn
+	return nil
+}
+
+func (b *builder) extendInputObject(merged *ast.Schema, ext *ast.InputObjectDefinition) error {
+	i, err := b.extended("input", ext.Name, ext.NamePos, len(merged.InputObjects), func(i int) string { return merged.InputObjects[i].Name.Text })
+	if err != nil {
+		return err
+	}
+	defn := *merged.InputObjects[i]
+	defn.Directs = mergeDirectives(defn.Directs, ext.Directs)
+	defn.InputValDefns = append([]*ast.InputValueDefinition(nil), defn.InputValDefns...)
+	for _, input := range ext.InputValDefns {
+		if indexOf(len(defn.InputValDefns), func(i int) bool { return defn.InputValDefns[i].Name.Text == input.Name.Text }) >= 0 {
+			return b.errorf(input.NamePos, "field %s of %s defined more than once", input.Name.Text, defn.Name.Text)
+		}
+		defn.InputValDefns = append(defn.InputValDefns, input)
+	}
+	merged.InputObjects[i] = &defn
+	return nil
+}
+
+// mergeFields returns fields followed by the ones in ext, which are not in
+// fields.
+func (b *builder) mergeFields(owner string, fields, ext []*ast.FieldDefinition) ([]*ast.FieldDefinition, error) {
+	merged := append([]*ast.FieldDefinition(nil), fields...)
+	for _, field := range ext {
+		if indexOf(len(merged), func(i int) bool { return merged[i].Name.Text == field.Name.Text }) >= 0 {
+			return nil, b.errorf(field.NamePos, "field %s of %s defined more than once", field.Name.Text, owner)
+		}
+		merged = append(merged, field)
+	}
+	return merged, nil
+}
+
+// mergeImplements returns the interfaces implemented by owner followed by the
+// ones added by the extension ext, each interface can only be implemented once.
+func (b *builder) mergeImplements(owner string, implements, ext *ast.ImplementsInterfaces) (*ast.ImplementsInterfaces, error) {
+	if ext == nil {
+		return implements, nil
+	}
+	merged := ast.ImplementsInterfaces{Implements: ext.Implements}
+	if implements != nil {
+		merged = *implements
+	}
+	merged.NamedTyps = append([]*ast.NamedType(nil), merged.NamedTyps...)
+	for _, named := range ext.NamedTyps {
+		if indexOf(len(merged.NamedTyps), func(i int) bool { return merged.NamedTyps[i].Name.Text == named.Name.Text }) >= 0 {
+			return nil, b.errorf(named.NamePos, "%s already implements %s", owner, named.Name.Text)
+		}
+		merged.NamedTyps = append(merged.NamedTyps, named)
+	}
+	return &merged, nil
+}
+
+// mergeDirectives returns the directives in directs followed by the ones in ext.
+func mergeDirectives(directs, ext *ast.Directives) *ast.Directives {
+	if ext == nil {
+		return directs
+	}
+	if directs == nil {
+		return ext
+	}
+	return &ast.Directives{Directs: append(append([]*ast.Directive(nil), directs.Directs...), ext.Directs...)}
+}
+
+// defaultOperations returns the root operation types of schema without a schema
+// definition, the object types named Query, Mutation and Subscription.
+func defaultOperations(schema *ast.Schema) []*ast.OperationTypeDefinition {
+	var opers []*ast.OperationTypeDefinition
+	for _, oper := range []struct {
+		kind ast.Kind
+		name string
+	}{
+		{ast.QUERY, "Query"},
+		{ast.MUTATION, "Mutation"},
+		{ast.SUBSCRIPTION, "Subscription"},
+	} {
+		for _, defn := range schema.Types {
+			if defn.Name.Text == oper.name {
+				opers = append(opers, &ast.OperationTypeDefinition{
+					OperType: ast.Token{Kind: ast.NAME, Text: ast.Stringify(oper.kind)},
+					NamedTyp: &ast.NamedType{Name: defn.Name, NamePos: defn.NamePos},
+				})
+				break
+			}
+		}
+	}
+	return opers
+}
+
+func hasOperation(opers []*ast.OperationTypeDefinition, operType string) bool {
+	for _, oper := range opers {
+		if oper.OperType.Text == operType {
+			return true
+		}
+	}
+	return false
+}
+
+// indexOf returns the smallest index i in [0, n) at which f(i) is true, or -1
+// if there is none.
+func indexOf(n int, f func(int) bool) int {
+	for i := 0; i < n; i++ {
+		if f(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *builder) fields(fieldDefns []*ast.FieldDefinition) ([]*Field, error) {
+	var fields []*Field
+	for _, defn := range fieldDefns {
+		typ, err := b.resolveType(defn.Typ)
+		if err != nil {
+			return nil, err
+		}
+		field := &Field{Name: defn.Name.Text, Desc: description(defn.Desc), Typ: typ}
+		if field.Deprecated, err = b.deprecated(defn.Directs); err != nil {
+			return nil, err
+		}
+		b.use(defn.Directs, "FIELD_DEFINITION", &field.Directs)
+
+		if defn.ArgDefns != nil {
+			for _, input := range defn.ArgDefns.InputValDefns {
+				var argDef *ArgDef
+				if argDef, err = b.argDef(input); err != nil {
+					return nil, err
+				}
+				field.Defs = append(field.Defs, argDef)
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func (b *builder) argDef(input *ast.InputValueDefinition) (*ArgDef, error) {
+	typ, err := b.resolveType(input.Typ)
+	if err != nil {
+		return nil, err
+	}
+	argDef := &ArgDef{Name: input.Name.Text, Desc: description(input.Desc), Typ: typ}
+	if argDef.Deprecated, err = b.deprecated(input.Directs); err != nil {
+		return nil, err
+	}
+	b.use(input.Directs, "ARGUMENT_DEFINITION", &argDef.Directs)
+	if input.DeflVal != nil {
+		b.defaults = append(b.defaults, defaultValue{typ, input.DeflVal.Val, &argDef.Defl})
+	}
+	return argDef, nil
+}
+
+// description returns the text of desc, an empty string if there is none.
+func description(desc *ast.Description) string {
+	if desc == nil {
+		return ""
+	}
+	return desc.Val.Text
+}
+
+// deprecated returns the reason of @deprecated in directs, or an empty string
+// if not deprecated.
+func (b *builder) deprecated(directs *ast.Directives) (string, error) {
+	reason, used, err := b.stringArg(directs, "deprecated", "reason")
+	if err != nil || !used {
+		return "", err
+	}
+	if reason == "" {
+		return DefaultDeprecationReason, nil
+	}
+	return reason, nil
+}
+
+// stringArg returns the string value of argument arg of the directive name in
+// directs, used reports if the directive is in directs. The value is an empty
+// string if the argument is not given.
+func (b *builder) stringArg(directs *ast.Directives, name, arg string) (s string, used bool, err error) {
+	if directs == nil {
+		return "", false, nil
+	}
+	for _, direct := range directs.Directs {
+		if direct.Name.Text != name {
+			continue
+		}
+		if direct.Args == nil {
+			return "", true, nil
+		}
+		for _, a := range direct.Args.Args {
+			if a.Name.Text != arg {
+				continue
+			}
+			value, err := literalValue(a.Val, nil)
+			if err != nil {
+				return "", true, b.errorf(a.Val.Pos(), "%s", err)
+			}
+			if s, ok := value.(string); ok {
+				return s, true, nil
+			}
+			return "", true, b.errorf(a.Val.Pos(), "%s of @%s must be a string", arg, name)
+		}
+		return "", true, nil
+	}
+	return "", false, nil
+}
+
+func (b *builder) iface(named *ast.NamedType) (*Interface, error) {
+	typ, err := b.namedType(named)
+	if err != nil {
+		return nil, err
+	}
+	iface, ok := typ.(*Interface)
+	if !ok {
+		return nil, b.errorf(named.NamePos, "type %s is not an interface", named.Name.Text)
+	}
+	return iface, nil
+}
+
+func (b *builder) namedType(named *ast.NamedType) (Type, error) {
+	typ, ok := b.types[named.Name.Text]
+	if !ok {
+		return nil, b.errorf(named.NamePos, "unknown type %s", named.Name.Text)
+	}
+	return typ, nil
+}
+
+func (b *builder) resolveType(astTyp ast.Type) (Type, error) {
+	var typ Type
+	var err error
+	var nonNull bool
+	switch astTyp := astTyp.(type) {
+	case *ast.NamedType:
+		nonNull = astTyp.NonNull
+		typ, err = b.namedType(astTyp)
+	case *ast.ListType:
+		nonNull = astTyp.NonNull
+		var ofType Type
+		if ofType, err = b.resolveType(astTyp.Typ); err == nil {
+			typ = &List{OfType: ofType}
+		}
+	default:
+		panic(fmt.Errorf("unexpected AST type %T", astTyp))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if nonNull {
+		typ = &NonNull{OfType: typ}
+	}
+	return typ, nil
+}
@@ -0,0 +1,477 @@
+package ql
+
+import (
+	"context"
+	"go/token"
+	"testing"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+func buildSchema(t *testing.T, sdl string) *Schema {
+	fset := token.NewFileSet()
+	s, err := ast.ParseSchema([]byte(sdl), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := BuildSchema(fset, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestBuildSchema(t *testing.T) {
+	schema := buildSchema(t, `
+schema {
+	query: Root
+	mutation: Mutation
+}
+
+type Root {
+	node(id: ID!): Node
+	search(text: String = "foo", limit: Int = 10, kinds: [Kind] = [A, B]): [Result!]!
+}
+
+type Mutation {
+	create(input: CreateInput!): Human
+}
+
+type Human implements Node {
+	id: ID!
+	friends: [Human]
+	kind: Kind
+}
+
+interface Node {
+	id: ID!
+}
+
+union Result = Human | Droid
+
+type Droid implements Node {
+	id: ID!
+	date: Date
+}
+
+scalar Date
+
+enum Kind {
+	A
+	B
+}
+
+input CreateInput {
+	name: String!
+}`)
+
+	assertEqual(t, "Root", schema.Qry.Name)
+	assertEqual(t, "Mutation", schema.Mut.Name)
+
+	node := findField(schema.Qry, "node")
+	nodeIface := node.Typ.(*Interface)
+	assertEqual(t, "Node", nodeIface.Name)
+	assertEqual(t, "ID!", typeName(node.Defs[0].Typ))
+
+	search := findField(schema.Qry, "search")
+	assertEqual(t, "[Result!]!", typeName(search.Typ))
+	assertEqual(t, "foo", search.Defs[0].Defl)
+	assertEqual(t, 10, search.Defs[1].Defl)
+	assertEqual(t, []interface{}{"A", "B"}, search.Defs[2].Defl)
+
+	result := search.Typ.(*NonNull).OfType.(*List).OfType.(*NonNull).OfType.(*Union)
+	human := result.Typs[0].(*Object)
+	droid := result.Typs[1].(*Object)
+	assertEqual(t, "Human", human.Name)
+	assertEqual(t, "Droid", droid.Name)
+	if human.Ifaces[0] != nodeIface || droid.Ifaces[0] != nodeIface {
+		t.Error("objects should implement the same Node interface")
+	}
+	if findField(human, "friends").Typ.(*List).OfType != human {
+		t.Error("Human should reference itself")
+	}
+	if findField(droid, "date").Typ.(*Scalar).Name != "Date" {
+		t.Error("expecting custom scalar Date")
+	}
+	assertEqual(t, []*EnumValue{{Name: "A"}, {Name: "B"}}, findField(human, "kind").Typ.(*Enum).Values)
+
+	create := findField(schema.Mut, "create")
+	input := create.Defs[0].Typ.(*NonNull).OfType.(*InputObject)
+	assertEqual(t, "CreateInput", input.Name)
+	assertEqual(t, String, input.Fields[0].Typ.(*NonNull).OfType)
+
+	runtime := newRuntime(schema)
+	for _, name := range []string{"Root", "Mutation", "Human", "Droid"} {
+		if runtime.Objects[name] == nil {
+			t.Errorf("object %s not found in runtime", name)
+		}
+	}
+	if runtime.Unions["Result"] == nil || runtime.InputObjs["CreateInput"] == nil {
+		t.Error("union and input object should be in runtime")
+	}
+}
+
+func TestBuildSchemaDefaultRoots(t *testing.T) {
+	schema := buildSchema(t, `type Query { hello: String }`)
+	assertEqual(t, "Query", schema.Qry.Name)
+	if schema.Mut != nil {
+		t.Error("expecting no mutation type")
+	}
+	if schema.Sub != nil {
+		t.Error("expecting no subscription type")
+	}
+
+	schema = buildSchema(t, `type Query { hello: String } type Subscription { ticks: Int }`)
+	assertEqual(t, "Subscription", schema.Sub.Name)
+}
+
+func TestBuildSchemaDescriptions(t *testing.T) {
+	sdl := `"""
+The root query.
+"""
+type Query {
+  "Search by text."
+  search(
+    "The text to search."
+    text: String
+  ): Result
+  kind: Kind
+  time(input: TimeInput): Time
+}
+
+"A result."
+interface Result {
+  "The id."
+  id: ID
+}
+
+"A kind."
+enum Kind {
+  "Kind A."
+  A
+}
+
+"A time."
+scalar Time
+
+"A time input."
+input TimeInput {
+  "The zone."
+  zone: String
+}
+`
+	schema := buildSchema(t, sdl)
+
+	assertEqual(t, "The root query.", schema.Qry.Desc)
+	search := findField(schema.Qry, "search")
+	assertEqual(t, "Search by text.", search.Desc)
+	assertEqual(t, "The text to search.", search.Defs[0].Desc)
+
+	result := search.Typ.(*Interface)
+	assertEqual(t, "A result.", result.Desc)
+	assertEqual(t, "The id.", result.Fields[0].Desc)
+
+	kind := findField(schema.Qry, "kind").Typ.(*Enum)
+	assertEqual(t, "A kind.", kind.Desc)
+	assertEqual(t, "Kind A.", kind.Values[0].Desc)
+
+	time := findField(schema.Qry, "time")
+	assertEqual(t, "A time.", time.Typ.(*Scalar).Desc)
+	input := time.Defs[0].Typ.(*InputObject)
+	assertEqual(t, "A time input.", input.Desc)
+	assertEqual(t, "The zone.", input.Fields[0].Desc)
+}
+
+func TestBuildSchemaExtensions(t *testing.T) {
+	schema := buildSchema(t, `
+type Query { user: User }
+type User { id: ID }
+interface Node { id: ID }
+union Result = User
+enum Kind { A }
+input Filter { text: String }
+scalar Time
+
+extend type User implements Node { name: String }
+extend type User @dir
+extend interface Node { name: String }
+extend union Result = Photo
+extend enum Kind { B }
+extend input Filter { kind: Kind }
+extend scalar Time @dir
+extend schema { subscription: Subscription }
+
+type Photo { url: String }
+type Subscription { photos: Photo }
+directive @dir on OBJECT | SCALAR
+`)
+
+	user := findField(schema.Qry, "user").Typ.(*Object)
+	assertEqual(t, "Node", user.Ifaces[0].Name)
+	assertEqual(t, "String", typeName(findField(user, "name").Typ))
+	node := user.Ifaces[0]
+	assertEqual(t, 2, len(node.Fields))
+
+	var result *Union
+	var kind *Enum
+	var filter *InputObject
+	for _, typ := range schema.Typs {
+		switch typ := typ.(type) {
+		case *Union:
+			result = typ
+		case *Enum:
+			kind = typ
+		case *InputObject:
+			filter = typ
+		}
+	}
+	assertEqual(t, 2, len(result.Typs))
+	assertEqual(t, "Photo", result.Typs[1].(*Object).Name)
+	assertEqual(t, []*EnumValue{{Name: "A"}, {Name: "B"}}, kind.Values)
+	assertEqual(t, "kind", filter.Fields[1].Name)
+	assertEqual(t, []*AppliedDirective{{Name: "dir", Args: map[string]interface{}{}}}, user.Directs)
+
+	assertEqual(t, "Query", schema.Qry.Name)
+	assertEqual(t, "Subscription", schema.Sub.Name)
+}
+
+func TestBuildSchemaInterfaceExtensions(t *testing.T) {
+	schema := buildSchema(t, `
+type Query { named: Named }
+interface Node { id: ID! }
+interface Named { id: ID! name: String }
+type User implements Named & Node { id: ID! name: String }
+extend interface Named implements Node
+`)
+	runtime, err := NewRuntime(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, []*Interface{runtime.Ifaces["Node"]}, runtime.Ifaces["Named"].Ifaces)
+
+	// the interfaces implemented through extensions are checked as well
+	tests := []struct {
+		sdl      string
+		expected []string
+	}{
+		{
+			`
+type Query { named: Named }
+interface Node { id: ID! }
+interface Named { id: ID! name: String }
+type User implements Named { id: ID! name: String }
+extend interface Named implements Node`,
+			[]string{"object User must implement interface Node, which is implemented by Named"},
+		},
+		{
+			`
+type Query { named: Named }
+interface Node implements Named { id: ID! name: String }
+interface Named { id: ID! name: String }
+extend interface Named implements Node`,
+			[]string{
+				"interface Named cannot implement itself through Node",
+				"interface Node cannot implement itself through Named",
+			},
+		},
+	}
+	for _, test := range tests {
+		_, err := NewRuntime(buildSchema(t, test.sdl))
+		errs, ok := err.(ErrBadSchema)
+		if !ok {
+			t.Fatalf("expecting ErrBadSchema, found %v", err)
+		}
+		var found []string
+		for _, err := range errs {
+			found = append(found, err.Error())
+		}
+		assertEqual(t, test.expected, found)
+	}
+}
+
+func TestBuildSchemaDefaultLiterals(t *testing.T) {
+	schema := buildSchema(t, `
+scalar JSON
+type Query { echo(value: JSON = {a: 1, b: [2.5, "c", null]}): JSON }
+`)
+	findField(schema.Qry, "echo").Resolve = func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		return args["value"], nil
+	}
+
+	// a default is converted just like the same literal in a query
+	rsp := execute(t, newRuntime(schema), `{ defl: echo literal: echo(value: {a: 1, b: [2.5, "c", null]}) }`, "", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+	assertEqual(t, rsp.Data.Get("literal"), rsp.Data.Get("defl"))
+}
+
+func TestBuildSchemaDirectives(t *testing.T) {
+	schema := buildSchema(t, `
+schema @meta(version: 2) { query: Query }
+
+"An entity key."
+directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+directive @auth(role: Role = USER, scopes: [String]) on FIELD_DEFINITION | ARGUMENT_DEFINITION
+directive @meta(version: Int) on SCHEMA | ENUM_VALUE | INPUT_FIELD_DEFINITION
+directive @trace on QUERY | FIELD
+
+type Query @key(fields: "id") @key(fields: "name") {
+  id: ID
+  name: String @auth(role: ADMIN, scopes: "read")
+  old: Int @deprecated
+  find(by: Filter @auth): Role
+}
+
+enum Role { USER ADMIN @meta(version: 1) }
+input Filter { text: String @meta }
+`)
+
+	key := schema.Directives[0]
+	assertEqual(t, &Directive{
+		Name:       "key",
+		Desc:       "An entity key.",
+		Defs:       []*ArgDef{{Name: "fields", Typ: &NonNull{OfType: String}}},
+		Locs:       []string{"OBJECT", "INTERFACE"},
+		Repeatable: true,
+	}, key)
+	assertEqual(t, 4, len(schema.Directives))
+
+	assertEqual(t, []*AppliedDirective{
+		{Name: "key", Args: map[string]interface{}{"fields": "id"}},
+		{Name: "key", Args: map[string]interface{}{"fields": "name"}},
+	}, schema.Qry.Directs)
+	assertEqual(t, []*AppliedDirective{{Name: "meta", Args: map[string]interface{}{"version": 2}}}, schema.Directs)
+	assertEqual(t, []*AppliedDirective{
+		{Name: "auth", Args: map[string]interface{}{"role": "ADMIN", "scopes": []interface{}{"read"}}},
+	}, findField(schema.Qry, "name").Directs)
+	assertEqual(t, []*AppliedDirective{
+		{Name: "deprecated", Args: map[string]interface{}{"reason": DefaultDeprecationReason}},
+	}, findField(schema.Qry, "old").Directs)
+
+	find := findField(schema.Qry, "find")
+	assertEqual(t, []*AppliedDirective{{Name: "auth", Args: map[string]interface{}{"role": "USER"}}}, find.Defs[0].Directs)
+	filter := namedType(find.Defs[0].Typ).(*InputObject)
+	assertEqual(t, []*AppliedDirective{{Name: "meta", Args: map[string]interface{}{}}}, filter.Fields[0].Directs)
+	role := find.Typ.(*Enum)
+	assertEqual(t, []*AppliedDirective(nil), role.Values[0].Directs)
+	assertEqual(t, []*AppliedDirective{{Name: "meta", Args: map[string]interface{}{"version": 1}}}, role.Values[1].Directs)
+}
+
+func TestBuildSchemaErrors(t *testing.T) {
+	tests := []struct {
+		sdl      string
+		expected string
+	}{
+		{
+			"type Query {\n\tfoo: Bar\n}",
+			"2:7: unknown type Bar",
+		},
+		{
+			"type Query { foo: [Bar!] }",
+			"1:20: unknown type Bar",
+		},
+		{
+			"type Query implements Query { foo: Int }",
+			"1:23: type Query is not an interface",
+		},
+		{
+			"type Query { foo: Int }\nscalar Query",
+			"2:8: type Query defined more than once",
+		},
+		{
+			"schema { query: Q }\ntype Query { foo: Int }",
+			"1:17: unknown type Q",
+		},
+		{
+			"schema { query: Int }",
+			"1:17: query root type Int must be an object type",
+		},
+		{
+			"type Query { foo(bar: Int = 1.5): Int }",
+			"1:29: invalid value 1.5; Int cannot represent non-integer value 1.5",
+		},
+		{
+			"type Query { foo: Int }\nextend type Mutation { bar: Int }",
+			"2:13: cannot extend undefined type Mutation",
+		},
+		{
+			"type Query { foo: Int }\nextend type Query { foo: String }",
+			"2:21: field foo of Query defined more than once",
+		},
+		{
+			"type Query { foo: Int }\ninterface Node { id: ID }\ninterface Named implements Node { id: ID }\nextend interface Named implements Node",
+			"4:35: interface Named already implements Node",
+		},
+		{
+			"type Query implements Node { foo: Int }\ninterface Node { id: ID }\nextend type Query implements Node",
+			"3:30: type Query already implements Node",
+		},
+		{
+			"type Query { foo: Kind }\nenum Kind { A }\nextend enum Kind { A }",
+			"3:20: value A of enum Kind defined more than once",
+		},
+		{
+			"type Query { foo: Int }\nextend scalar Time @dir",
+			"2:15: cannot extend undefined scalar Time",
+		},
+		{
+			"schema { query: Query }\ntype Query { foo: Int }\nextend schema { query: Query }",
+			"3:17: query root type defined more than once",
+		},
+		{
+			"type Query { foo(bar: In = {a: 1}): Int }\ninput In { a: Int, b: String! }",
+			"1:28: invalid value {a: 1}; field b of required type String! was not provided",
+		},
+		{
+			"type Query { foo: Int @dir }",
+			"1:24: unknown directive @dir",
+		},
+		{
+			"directive @dir on OBJECT\ndirective @dir on FIELD",
+			"2:12: directive @dir defined more than once",
+		},
+		{
+			"directive @skip on OBJECT",
+			"1:12: directive @skip defined more than once",
+		},
+		{
+			"directive @dir on OBJECT | FIELDS",
+			"1:28: unknown directive location FIELDS",
+		},
+		{
+			"directive @dir on OBJECT\ntype Query { foo: Int @dir }",
+			"2:24: directive @dir may not be used on FIELD_DEFINITION",
+		},
+		{
+			"directive @dir on OBJECT\ntype Query @dir @dir { foo: Int }",
+			"2:18: directive @dir can only be used once at this location",
+		},
+		{
+			"directive @dir(a: Int!) on OBJECT\ntype Query @dir { foo: Int }",
+			"2:13: argument a of directive @dir is required",
+		},
+		{
+			"directive @dir(a: Int) on OBJECT\ntype Query @dir(b: 1) { foo: Int }",
+			"2:17: unknown argument b of directive @dir",
+		},
+		{
+			"directive @dir(a: Int) on OBJECT\ntype Query @dir(a: \"1\") { foo: Int }",
+			"2:20: invalid value \"1\"; Int cannot represent non-integer value \"1\"",
+		},
+	}
+
+	for _, test := range tests {
+		fset := token.NewFileSet()
+		s, err := ast.ParseSchema([]byte(test.sdl), "", fset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = BuildSchema(fset, s)
+		if err == nil {
+			t.Errorf("expecting error %s, found nil", test.expected)
+			continue
+		}
+		assertEqual(t, test.expected, err.Error())
+	}
+}
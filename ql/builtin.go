@@ -1,10 +1,259 @@
 package ql
 
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
 // built-in scalar types.
 var (
-	Int     = &Scalar{Name: "Int"}
-	Float   = &Scalar{Name: "Float"}
-	String  = &Scalar{Name: "String"}
-	Boolean = &Scalar{Name: "Boolean"}
-	ID      = &Scalar{Name: "ID"}
+	Int     = &Scalar{Name: "Int", Serialize: serializeInt, ParseValue: parseInt, ParseLiteral: parseIntLiteral}
+	Float   = &Scalar{Name: "Float", Serialize: serializeFloat, ParseValue: parseFloat, ParseLiteral: parseFloatLiteral}
+	String  = &Scalar{Name: "String", Serialize: serializeString, ParseValue: parseString, ParseLiteral: parseStringLiteral}
+	Boolean = &Scalar{Name: "Boolean", Serialize: serializeBoolean, ParseValue: parseBoolean, ParseLiteral: parseBooleanLiteral}
+	ID      = &Scalar{Name: "ID", Serialize: serializeID, ParseValue: parseID, ParseLiteral: parseIDLiteral}
 )
+
+func serializeInt(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < math.MinInt32 || v.Int() > math.MaxInt32 {
+			return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value %d", v.Int())
+		}
+		return int(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt32 {
+			return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value %d", v.Uint())
+		}
+		return int(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, fmt.Errorf("Int cannot represent non-integer value %v", f)
+		}
+		return int(f), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.String:
+		i, err := strconv.ParseInt(v.String(), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Int cannot represent value %q", v.String())
+		}
+		return int(i), nil
+	default:
+		return nil, fmt.Errorf("Int cannot represent value %v", value)
+	}
+}
+
+func serializeFloat(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0) {
+			return nil, fmt.Errorf("Float cannot represent non numeric value %v", v.Float())
+		}
+		return v.Float(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case reflect.String:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Float cannot represent value %q", v.String())
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("Float cannot represent value %v", value)
+	}
+}
+
+func serializeString(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case fmt.Stringer:
+		return value.String(), nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		return fmt.Sprint(value), nil
+	default:
+		return nil, fmt.Errorf("String cannot represent value %v", value)
+	}
+}
+
+func serializeBoolean(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() != 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() != 0, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float() != 0, nil
+	default:
+		return nil, fmt.Errorf("Boolean cannot represent value %v", value)
+	}
+}
+
+func serializeID(value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		if s, ok := value.(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+		return nil, fmt.Errorf("ID cannot represent value %v", value)
+	}
+}
+
+// numberValue returns value as float64 if it is a number, json.Number included.
+func numberValue(value interface{}) (float64, bool) {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func parseInt(value interface{}) (interface{}, error) {
+	f, ok := numberValue(value)
+	if !ok {
+		return nil, fmt.Errorf("Int cannot represent non-integer value %v", value)
+	}
+	if f != math.Trunc(f) {
+		return nil, fmt.Errorf("Int cannot represent non-integer value %v", value)
+	}
+	if f < math.MinInt32 || f > math.MaxInt32 {
+		return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value %v", value)
+	}
+	return int(f), nil
+}
+
+func parseFloat(value interface{}) (interface{}, error) {
+	f, ok := numberValue(value)
+	if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("Float cannot represent non numeric value %v", value)
+	}
+	return f, nil
+}
+
+func parseString(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("String cannot represent a non string value %v", value)
+}
+
+func parseBoolean(value interface{}) (interface{}, error) {
+	if b, ok := value.(bool); ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("Boolean cannot represent a non boolean value %v", value)
+}
+
+func parseID(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	if f, ok := numberValue(value); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return strconv.FormatInt(int64(f), 10), nil
+	}
+	return nil, fmt.Errorf("ID cannot represent value %v", value)
+}
+
+// literalToken returns the token of a literal or name value.
+func literalToken(value ast.Value) ast.Token {
+	switch value := value.(type) {
+	case *ast.LiteralValue:
+		return value.Val
+	case *ast.NameValue:
+		return value.Val
+	default:
+		return ast.Token{Kind: ast.ILLEGAL}
+	}
+}
+
+func parseIntLiteral(value ast.Value) (interface{}, error) {
+	tok := literalToken(value)
+	if tok.Kind != ast.INT {
+		return nil, fmt.Errorf("Int cannot represent non-integer value %s", formatASTValue(value))
+	}
+	i, err := strconv.ParseInt(tok.Text, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value %s", tok.Text)
+	}
+	return int(i), nil
+}
+
+func parseFloatLiteral(value ast.Value) (interface{}, error) {
+	tok := literalToken(value)
+	if tok.Kind != ast.INT && tok.Kind != ast.FLOAT {
+		return nil, fmt.Errorf("Float cannot represent non numeric value %s", formatASTValue(value))
+	}
+	return strconv.ParseFloat(tok.Text, 64)
+}
+
+func parseStringLiteral(value ast.Value) (interface{}, error) {
+	tok := literalToken(value)
+	if tok.Kind != ast.STRING {
+		return nil, fmt.Errorf("String cannot represent a non string value %s", formatASTValue(value))
+	}
+	return tok.Text, nil
+}
+
+func parseBooleanLiteral(value ast.Value) (interface{}, error) {
+	tok := literalToken(value)
+	if tok.Kind == ast.NAME && (tok.Text == "true" || tok.Text == "false") {
+		return tok.Text == "true", nil
+	}
+	return nil, fmt.Errorf("Boolean cannot represent a non boolean value %s", formatASTValue(value))
+}
+
+func parseIDLiteral(value ast.Value) (interface{}, error) {
+	tok := literalToken(value)
+	if tok.Kind != ast.STRING && tok.Kind != ast.INT {
+		return nil, fmt.Errorf("ID cannot represent value %s", formatASTValue(value))
+	}
+	return tok.Text, nil
+}
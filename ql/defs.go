@@ -1,16 +1,127 @@
 package ql
 
-// Field represents fields in Object, Interface and InputObject.
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// Field represents fields in Object, Interface and InputObject. A non-empty
+// Deprecated is the reason why the field is deprecated. Defl is the default
+// value of a field of InputObject. Subscribe creates the source event stream
+// of a field of the subscription root type. Timeout is the deadline of the
+// context passed to Resolve, there is no deadline if it is 0.
 type Field struct {
-	Name string
-	Typ  Type
-	Defs []*ArgDef
+	Name       string
+	Desc       string
+	Typ        Type
+	Defs       []*ArgDef
+	Defl       interface{}
+	Deprecated string
+	Directs    []*AppliedDirective
+	Resolve    ResolveFunc
+	Subscribe  SubscribeFunc
+	Timeout    time.Duration
 }
 
-// ArgDef represents argument definitions in Object and Interface.
+// ArgDef represents argument definitions in Object and Interface. Defl is the
+// coerced value used when the argument is not provided. A non-empty Deprecated
+// is the reason why the argument is deprecated.
 type ArgDef struct {
-	Name string
-	Typ  Type
-	defl interface{}
-	// directs []*Directive
+	Name       string
+	Desc       string
+	Typ        Type
+	Defl       interface{}
+	Deprecated string
+	Directs    []*AppliedDirective
+}
+
+// DefaultDeprecationReason is the reason used when a field, argument or enum
+// value is deprecated without giving one.
+const DefaultDeprecationReason = "No longer supported"
+
+// ResolveFunc resolves the value of a field. source is the value of the parent
+// object, args are the coerced argument values of the field. DefaultResolve is
+// used if the Resolve of a Field is nil.
+type ResolveFunc func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error)
+
+// SubscribeFunc returns the source event stream of a subscription field. The
+// stream should be closed when ctx is done or there are no more events. For
+// each event, the selection set of the subscription is executed with the
+// event as the source of the field, which is also its value if Resolve of the
+// field is nil.
+type SubscribeFunc func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (<-chan interface{}, error)
+
+// ResolveTypeFunc determines the concrete Object type of value resolved for an
+// Interface or Union.
+type ResolveTypeFunc func(ctx context.Context, value interface{}, info *ResolveInfo) *Object
+
+// ResolveInfo carries information about the field being resolved.
+type ResolveInfo struct {
+	FieldName string
+	// FieldNodes are all the field nodes in document sharing the same response
+	// key, merged together in execution.
+	FieldNodes []*ast.Field
+	ReturnType Type
+	ParentType *Object
+	// Path from the root of response to this field, made of response keys and
+	// list indices.
+	Path      []interface{}
+	Operation *ast.OperationDefinition
+	// Fragments are the fragment definitions of the document by name.
+	Fragments      map[string]*ast.FragmentDefinition
+	VariableValues map[string]interface{}
+	Runtime        *Runtime
+}
+
+// DefaultResolve resolves a field by looking up source. It returns the value
+// keyed by the field name if source is a map, or the value of the struct field
+// whose json tag or name matches the field name case-insensitively.
+func DefaultResolve(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+	if source == nil {
+		return nil, nil
+	}
+
+	if m, ok := source.(map[string]interface{}); ok {
+		return m[info.FieldName], nil
+	}
+
+	v := reflect.ValueOf(source)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, nil
+		}
+		val := v.MapIndex(reflect.ValueOf(info.FieldName).Convert(v.Type().Key()))
+		if !val.IsValid() {
+			return nil, nil
+		}
+		return val.Interface(), nil
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			sf := typ.Field(i)
+			if sf.PkgPath != "" { // unexported
+				continue
+			}
+			tag := strings.Split(sf.Tag.Get("json"), ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if tag == info.FieldName || (tag == "" && strings.EqualFold(sf.Name, info.FieldName)) {
+				return v.Field(i).Interface(), nil
+			}
+		}
+	}
+	return nil, nil
 }
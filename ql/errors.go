@@ -0,0 +1,152 @@
+package ql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// Error is an error in a Response. Locations are the positions in the document
+// of the nodes the error is related to, and Path is the path of the field in
+// the response it occurred at, made of field names and list indices. Extensions
+// holds additional information, errors of validating documents have the code
+// GRAPHQL_VALIDATION_FAILED, and errors of coercing variable values have the
+// code BAD_USER_INPUT. A resolver can return an Error to provide its own
+// extensions, the locations and path are filled in by the executor.
+type Error struct {
+	Message    string
+	Locations  []Location
+	Path       []interface{}
+	Extensions map[string]interface{}
+	Err        error // underlying error, such as the one returned by a resolver
+}
+
+// Location is a position in a document, both line and column start at 1.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error returns the message prefixed by the first location if there is one.
+func (e *Error) Error() string {
+	if len(e.Locations) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Locations[0].Line, e.Locations[0].Column, e.Message)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the error in the JSON shape of GraphQL errors, fields
+// without value are omitted except message.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Message    string                 `json:"message"`
+		Locations  []Location             `json:"locations,omitempty"`
+		Path       []interface{}          `json:"path,omitempty"`
+		Extensions map[string]interface{} `json:"extensions,omitempty"`
+	}{e.Message, e.Locations, e.Path, e.Extensions})
+}
+
+// newError returns err as an Error located at nodes, an Error in the chain of
+// err keeps its message, along with the errors wrapping it, and provides the
+// extensions, and the locations and path if it has them.
+func newError(err error, fset *token.FileSet, path []interface{}, nodes ...ast.Node) *Error {
+	e := &Error{Message: err.Error(), Err: err}
+	var qlErr *Error
+	if errors.As(err, &qlErr) {
+		// the locations of qlErr are reported by e instead of its message
+		e.Message = strings.Replace(err.Error(), qlErr.Error(), qlErr.Message, 1)
+		e.Locations = append([]Location(nil), qlErr.Locations...)
+		e.Path = append([]interface{}(nil), qlErr.Path...)
+		if qlErr.Extensions != nil {
+			e.Extensions = make(map[string]interface{}, len(qlErr.Extensions))
+			for k, v := range qlErr.Extensions {
+				e.Extensions[k] = v
+			}
+		}
+	}
+	if len(e.Locations) == 0 {
+		e.Locations = locations(fset, nodes...)
+	}
+	if e.Path == nil {
+		e.Path = path
+	}
+	return e
+}
+
+// locations returns the locations of nodes, nil if there is no fset.
+func locations(fset *token.FileSet, nodes ...ast.Node) []Location {
+	if fset == nil {
+		return nil
+	}
+	var locs []Location
+	for _, node := range nodes {
+		if node == nil || !node.Pos().IsValid() {
+			continue
+		}
+		pos := fset.Position(node.Pos())
+		locs = append(locs, Location{Line: pos.Line, Column: pos.Column})
+	}
+	return locs
+}
+
+// sortErrors sorts errs of executing fields, which may be added in any order
+// as fields are executed concurrently, by their locations and then their paths.
+// Errors without locations, such as the ones which are not an Error, follow the
+// ones with locations.
+func sortErrors(errs []error) []error {
+	sort.SliceStable(errs, func(i, j int) bool {
+		e, f := &Error{}, &Error{}
+		errors.As(errs[i], &e)
+		errors.As(errs[j], &f)
+		if located := len(e.Locations) > 0; located != (len(f.Locations) > 0) {
+			return located
+		}
+		if len(e.Locations) > 0 {
+			l, m := e.Locations[0], f.Locations[0]
+			if l.Line != m.Line {
+				return l.Line < m.Line
+			}
+			if l.Column != m.Column {
+				return l.Column < m.Column
+			}
+		}
+		return pathLess(e.Path, f.Path)
+	})
+	return errs
+}
+
+// pathLess reports if path p is ordered before q, list indices are ordered
+// before field names.
+func pathLess(p, q []interface{}) bool {
+	for i := 0; i < len(p) && i < len(q); i++ {
+		switch a := p[i].(type) {
+		case int:
+			b, ok := q[i].(int)
+			if !ok {
+				return true
+			}
+			if a != b {
+				return a < b
+			}
+		case string:
+			b, ok := q[i].(string)
+			if !ok {
+				return false
+			}
+			if a != b {
+				return a < b
+			}
+		}
+	}
+	return len(p) < len(q)
+}
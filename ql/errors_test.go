@@ -0,0 +1,119 @@
+package ql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorLocationsAndPath(t *testing.T) {
+	itemType := &Object{Name: "Item"}
+	itemType.Fields = []*Field{
+		{
+			Name: "price",
+			Typ:  Int,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+				if source.(int) == 1 {
+					return nil, fmt.Errorf("pricing failed: %w", &Error{
+						Message:    "price unavailable",
+						Extensions: map[string]interface{}{"code": "UNAVAILABLE"},
+					})
+				}
+				return source, nil
+			},
+		},
+	}
+	queryType := &Object{
+		Name: "Query",
+		Fields: []*Field{
+			{
+				Name: "items",
+				Typ:  &List{OfType: itemType},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+					return []int{0, 1}, nil
+				},
+			},
+		},
+	}
+
+	runtime := newRuntime(&Schema{Qry: queryType})
+	rsp := execute(t, runtime, `{
+  items {
+    cost: price
+    cost: price
+  }
+}`, "", nil)
+	if len(rsp.Errors) != 1 {
+		t.Fatalf("expecting 1 error, found %v", rsp.Errors)
+	}
+	err := rsp.Errors[0].(*Error)
+	assertEqual(t, "pricing failed: price unavailable", err.Message)
+	var qlErr *Error
+	if !errors.As(err.Err, &qlErr) || qlErr.Message != "price unavailable" {
+		t.Errorf("expecting the error of the resolver to be wrapped, found %v", err.Err)
+	}
+	assertEqual(t, []Location{{Line: 3, Column: 5}, {Line: 4, Column: 5}}, err.Locations)
+	assertEqual(t, []interface{}{"items", 1, "cost"}, err.Path)
+	assertEqual(t, map[string]interface{}{"code": "UNAVAILABLE"}, err.Extensions)
+	err.Extensions["code"] = "CHANGED"
+	if qlErr.Extensions["code"] != "UNAVAILABLE" {
+		t.Errorf("expecting the extensions of the resolver error to be kept, found %v", qlErr.Extensions)
+	}
+
+	rsp = execute(t, runtime, `{ items { cost } }`, "", nil)
+	err = rsp.Errors[0].(*Error)
+	assertEqual(t, "1:11: cannot query field \"cost\" on type \"Item\"", err.Error())
+	assertEqual(t, map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"}, err.Extensions)
+
+	rsp = execute(t, runtime, `query Q($b: Boolean!) { items @skip(if: $b) { price } }`, "", nil)
+	err = rsp.Errors[0].(*Error)
+	assertEqual(t, []Location{{Line: 1, Column: 8}}, err.Locations)
+	assertEqual(t, map[string]interface{}{"code": "BAD_USER_INPUT"}, err.Extensions)
+}
+
+func TestErrorJSON(t *testing.T) {
+	tests := []struct {
+		err      *Error
+		expected string
+	}{
+		{
+			&Error{Message: "failed"},
+			`{"message":"failed"}`,
+		},
+		{
+			&Error{
+				Message:    "failed",
+				Locations:  []Location{{Line: 2, Column: 3}},
+				Path:       []interface{}{"items", 0, "price"},
+				Extensions: map[string]interface{}{"code": "UNAVAILABLE"},
+			},
+			`{"message":"failed","locations":[{"line":2,"column":3}],"path":["items",0,"price"],"extensions":{"code":"UNAVAILABLE"}}`,
+		},
+	}
+	for _, test := range tests {
+		b, err := json.Marshal(test.err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertEqual(t, test.expected, string(b))
+	}
+}
+
+func TestSortErrors(t *testing.T) {
+	plain := errors.New("plain")
+	errs := sortErrors([]error{
+		&Error{Message: "b", Locations: []Location{{Line: 2, Column: 1}}, Path: []interface{}{"b"}},
+		plain,
+		fmt.Errorf("wrapped: %w", &Error{Message: "a", Locations: []Location{{Line: 1, Column: 1}}, Path: []interface{}{"a"}}),
+		&Error{Message: "c", Path: []interface{}{"c", 0}},
+		&Error{Message: "d", Path: []interface{}{"a"}},
+		&Error{Message: "e", Locations: []Location{{Line: 2, Column: 1}}, Path: []interface{}{"0"}},
+	})
+	var found []string
+	for _, err := range errs {
+		found = append(found, err.Error())
+	}
+	assertEqual(t, []string{"wrapped: 1:1: a", "2:1: e", "2:1: b", "plain", "d", "c"}, found)
+}
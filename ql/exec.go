@@ -1,21 +1,42 @@
 package ql
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/leesper/pureql/ql/ast"
 )
 
-// Runtime represents runtime type info extract from schema.
+// Runtime represents runtime type info extract from schema. Directives holds
+// the built-in directives and the ones defined by schema.
+//
+// Sibling fields of queries and subscription events are executed concurrently,
+// while the root fields of mutations are executed one after another in the
+// order of the document. Concurrency limits the number of resolvers called at
+// the same time, there is no limit if it is 0, and fields are executed one
+// after another if it is 1. Timeout is the deadline of executing an operation,
+// or an event of a subscription, there is no deadline if it is 0.
 type Runtime struct {
-	Scalars   map[string]*Scalar
-	Objects   map[string]*Object
-	Ifaces    map[string]*Interface
-	Unions    map[string]*Union
-	Enums     map[string]*Enum
-	InputObjs map[string]*InputObject
-	Lists     map[string]*List
-	NonNulls  map[string]*NonNull
+	Schema     *Schema
+	Scalars    map[string]*Scalar
+	Objects    map[string]*Object
+	Ifaces     map[string]*Interface
+	Unions     map[string]*Union
+	Enums      map[string]*Enum
+	InputObjs  map[string]*InputObject
+	Lists      map[string]*List
+	NonNulls   map[string]*NonNull
+	Directives map[string]*Directive
+
+	Concurrency int
+	Timeout     time.Duration
 }
 
 // NewRuntime returns a new Runtime shipped with type infos from schema. It returns
@@ -24,23 +45,44 @@ func NewRuntime(schema *Schema) (*Runtime, error) {
 	if err := validateSchema(schema); err != nil {
 		return nil, err
 	}
+	return newRuntime(schema), nil
+}
 
+func newRuntime(schema *Schema) *Runtime {
 	runtime := &Runtime{
-		Scalars:   make(map[string]*Scalar),
-		Objects:   make(map[string]*Object),
-		Ifaces:    make(map[string]*Interface),
-		Unions:    make(map[string]*Union),
-		Enums:     make(map[string]*Enum),
-		InputObjs: make(map[string]*InputObject),
-		Lists:     make(map[string]*List),
-		NonNulls:  make(map[string]*NonNull),
+		Schema:     schema,
+		Scalars:    make(map[string]*Scalar),
+		Objects:    make(map[string]*Object),
+		Ifaces:     make(map[string]*Interface),
+		Unions:     make(map[string]*Union),
+		Enums:      make(map[string]*Enum),
+		InputObjs:  make(map[string]*InputObject),
+		Lists:      make(map[string]*List),
+		NonNulls:   make(map[string]*NonNull),
+		Directives: make(map[string]*Directive),
+	}
+	for _, direct := range builtinDirectives {
+		runtime.Directives[direct.Name] = direct
 	}
 	if schema == nil {
-		return runtime, nil
+		return runtime
+	}
+	for _, direct := range schema.Directives {
+		runtime.Directives[direct.Name] = direct
+		for _, def := range direct.Defs {
+			extractTypes(runtime, def.Typ)
+		}
 	}
 	extractObjectTypes(runtime, schema.Qry)
 	extractObjectTypes(runtime, schema.Mut)
-	return runtime, nil
+	extractObjectTypes(runtime, schema.Sub)
+	for _, typ := range schema.Typs {
+		extractTypes(runtime, typ)
+	}
+	if schema.Qry != nil {
+		extractObjectTypes(runtime, schemaType)
+	}
+	return runtime
 }
 
 func extractObjectTypes(runtime *Runtime, obj *Object) {
@@ -69,6 +111,9 @@ func extractIfaceTypes(runtime *Runtime, iface *Interface) {
 	}
 
 	runtime.Ifaces[iface.Name] = iface
+	for _, super := range iface.Ifaces {
+		extractIfaceTypes(runtime, super)
+	}
 	for _, field := range iface.Fields {
 		extractFieldTypes(runtime, field)
 	}
@@ -93,17 +138,20 @@ func extractFieldTypes(runtime *Runtime, field *Field) {
 		return
 	}
 	extractTypes(runtime, field.Typ)
+	for _, def := range field.Defs {
+		extractTypes(runtime, def.Typ)
+	}
 }
 
 func extractListTypes(runtime *Runtime, list *List) {
 	if list == nil {
 		return
 	}
-	if _, ok := runtime.Lists[list.Type()]; ok {
+	if _, ok := runtime.Lists[typeName(list)]; ok {
 		return
 	}
 
-	runtime.Lists[list.Type()] = list
+	runtime.Lists[typeName(list)] = list
 	extractTypes(runtime, list.OfType)
 }
 
@@ -111,11 +159,11 @@ func extractNonNullTypes(runtime *Runtime, nn *NonNull) {
 	if nn == nil {
 		return
 	}
-	if _, ok := runtime.NonNulls[nn.Type()]; ok {
+	if _, ok := runtime.NonNulls[typeName(nn)]; ok {
 		return
 	}
 
-	runtime.NonNulls[nn.Type()] = nn
+	runtime.NonNulls[typeName(nn)] = nn
 	extractTypes(runtime, nn.OfType)
 }
 
@@ -126,6 +174,8 @@ func extractUnionTypes(runtime *Runtime, union *Union) {
 	if _, ok := runtime.Unions[union.Name]; ok {
 		return
 	}
+
+	runtime.Unions[union.Name] = union
 	for _, typ := range union.Typs {
 		extractTypes(runtime, typ)
 	}
@@ -184,49 +234,125 @@ func (runtime *Runtime) findType(name string) Type {
 	return nil
 }
 
-// Response of executing request.
+// Response of executing request. Data is nil if a non-null root field is null.
+// Executed reports if the operation was executed, it is false if the request
+// failed before, such as failing to validate or to coerce variable values, in
+// which case the response has no data at all.
 type Response struct {
-	Errors []error
+	Data     *Result
+	Errors   []error
+	Executed bool
+}
+
+// Result is the result map of a selection set, which is also the value of
+// object fields. Keys are the response keys in the order of fields and Values
+// holds the value of each key. Result is marshalled to a JSON object with its
+// keys in order.
+type Result struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+// Get returns the value of key.
+func (r *Result) Get(key string) interface{} {
+	return r.Values[key]
+}
+
+// MarshalJSON marshals r to a JSON object with its keys in order.
+func (r *Result) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, key := range r.Keys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(r.Values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(append(buf, k...), ':'), v...)
+	}
+	return append(buf, '}'), nil
 }
 
 // Execute executes the request defined by document with optional variable values.
-func (runtime *Runtime) Execute(document *ast.Document, operationName string, variableValues map[string]interface{}) *Response {
+// The document is validated first, fset is used to report the locations of
+// errors, which are all *Error.
+func (runtime *Runtime) Execute(fset *token.FileSet, document *ast.Document, operationName string, variableValues map[string]interface{}) *Response {
+	return runtime.ExecuteContext(context.Background(), fset, document, operationName, variableValues)
+}
+
+// ExecuteContext is like Execute but ctx is passed to every resolver. Once ctx
+// is done, no more fields are executed, each of them is null with an error
+// wrapping the error of ctx.
+func (runtime *Runtime) ExecuteContext(ctx context.Context, fset *token.FileSet, document *ast.Document, operationName string, variableValues map[string]interface{}) *Response {
 	rsp := &Response{}
 
-	// TODO
-	// err = validateDocument(document)
-	// if err != nil {
-	// 	rsp.Errors = append(rsp.Errors, err)
-	// 	return rsp
-	// }
+	if errs := validateDocument(runtime, fset, document); len(errs) > 0 {
+		rsp.Errors = errs
+		return rsp
+	}
 
 	operation, err := runtime.getOperation(document, operationName)
 	if err != nil {
-		rsp.Errors = append(rsp.Errors, err)
+		rsp.Errors = append(rsp.Errors, newError(err, fset, nil))
 		return rsp
 	}
 
 	coercedVarVals, err := runtime.coerceVariableValues(operation, variableValues)
 	if err != nil {
-		rsp.Errors = append(rsp.Errors, err)
+		rsp.Errors = append(rsp.Errors, inputError(err, fset, operation))
 		return rsp
 	}
-	return runtime.executeRequest(operation, coercedVarVals)
+	if runtime.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runtime.Timeout)
+		defer cancel()
+	}
+	return runtime.executeRequest(ctx, fset, operation, fragmentsOf(document), coercedVarVals)
+}
+
+// fragmentsOf returns the fragment definitions of document by name.
+func fragmentsOf(document *ast.Document) map[string]*ast.FragmentDefinition {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range document.Defs {
+		if frag, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[frag.Name.Text] = frag
+		}
+	}
+	return fragments
+}
+
+// inputError returns err of coercing variable values as an *Error located at
+// the variable definitions of operation.
+func inputError(err error, fset *token.FileSet, operation *ast.OperationDefinition) *Error {
+	var node ast.Node = operation
+	if operation.VarDefns != nil {
+		node = operation.VarDefns
+	}
+	e := newError(err, fset, nil, node)
+	e.Extensions = map[string]interface{}{"code": "BAD_USER_INPUT"}
+	return e
 }
 
 func (runtime *Runtime) getOperation(document *ast.Document, operationName string) (*ast.OperationDefinition, error) {
 	var oper *ast.OperationDefinition
 	var ok bool
 	if operationName == "" {
+		var found *ast.OperationDefinition
 		count := 0
 		for _, def := range document.Defs {
 			oper, ok = def.(*ast.OperationDefinition)
 			if ok {
+				found = oper
 				count++
 			}
 		}
 		if count == 1 {
-			return oper, nil
+			return found, nil
 		}
 		return nil, fmt.Errorf("query error: requiring operation name")
 
@@ -244,79 +370,889 @@ func (runtime *Runtime) getOperation(document *ast.Document, operationName strin
 
 func (runtime *Runtime) coerceVariableValues(operation *ast.OperationDefinition, variableValues map[string]interface{}) (map[string]interface{}, error) {
 	coercedValues := map[string]interface{}{}
+	if operation.VarDefns == nil {
+		return coercedValues, nil
+	}
 	for _, varDefn := range operation.VarDefns.VarDefns {
 		varName := varDefn.Var.Name.Text
 		varType := runtime.resolveASTType(varDefn.Typ)
-		deflVal := varDefn.DeflVal.Val
 		value, ok := variableValues[varName]
 		if !ok {
-			if deflVal != nil {
-				coercedValues[varName] = deflVal
+			if varDefn.DeflVal != nil {
+				coercedVal, err := coerceLiteralValue(varType, varDefn.DeflVal.Val, nil)
+				if err != nil {
+					return nil, fmt.Errorf("query error: variable \"$%s\" got %s", varName, err)
+				}
+				coercedValues[varName] = coercedVal
 			} else if isNonNull(varType) {
-				return nil, fmt.Errorf("query error: type %T is non-null", varType)
+				return nil, fmt.Errorf("query error: variable \"$%s\" of required type %s was not provided", varName, typeName(varType))
 			}
-		} else {
-			// coerce value
-			coercedVal, err := coerceInputValue(varType, value)
+			continue
+		}
+
+		coercedVal, err := coerceInputValue(varType, value, varName)
+		if err != nil {
+			return nil, fmt.Errorf("query error: variable \"$%s\" got %s", varName, err)
+		}
+		coercedValues[varName] = coercedVal
+	}
+	return coercedValues, nil
+}
+
+// coerceInputValue coerces value of an input type such as a variable value
+// decoded from JSON, path locates value inside the variable for errors.
+func coerceInputValue(typ Type, value interface{}, path string) (interface{}, error) {
+	if nt, ok := typ.(*NonNull); ok {
+		if isNil(value) {
+			return nil, inputErrorf(value, path, "expected non-nullable type %s not to be null", typeName(typ))
+		}
+		return coerceInputValue(nt.OfType, value, path)
+	}
+
+	if isNil(value) {
+		return nil, nil
+	}
+
+	switch typ := typ.(type) {
+	case *Scalar:
+		if typ.ParseValue == nil {
+			return value, nil
+		}
+		coerced, err := typ.ParseValue(value)
+		if err != nil {
+			return nil, inputErrorf(value, path, "%s", err)
+		}
+		return coerced, nil
+	case *Enum:
+		if name, ok := value.(string); ok {
+			for _, ev := range typ.Values {
+				if ev.Name == name {
+					return ev.value(), nil
+				}
+			}
+		}
+		return nil, inputErrorf(value, path, "value does not exist in %s enum", typ.Name)
+	case *InputObject:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, inputErrorf(value, path, "expected type %s to be an object", typ.Name)
+		}
+
+		defined := map[string]bool{}
+		for _, f := range typ.Fields {
+			defined[f.Name] = true
+		}
+		var names []string
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !defined[name] {
+				return nil, inputErrorf(value, path, "field %s is not defined by type %s", name, typ.Name)
+			}
+		}
+
+		coercedFields := map[string]interface{}{}
+		for _, f := range typ.Fields {
+			fieldValue, ok := fields[f.Name]
+			if !ok {
+				if f.Defl != nil {
+					coercedFields[f.Name] = f.Defl
+				} else if isNonNull(f.Typ) {
+					return nil, inputErrorf(value, path, "field %s of required type %s was not provided", f.Name, typeName(f.Typ))
+				}
+				continue
+			}
+			coerced, err := coerceInputValue(f.Typ, fieldValue, path+"."+f.Name)
+			if err != nil {
+				return nil, err
+			}
+			coercedFields[f.Name] = coerced
+		}
+		return coercedFields, nil
+	case *List:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			coerced, err := coerceInputValue(typ.OfType, value, path)
 			if err != nil {
 				return nil, err
 			}
-			coercedValues[varName] = coercedVal
+			return []interface{}{coerced}, nil
+		}
+
+		coercedItems := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			coerced, err := coerceInputValue(typ.OfType, v.Index(i).Interface(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			coercedItems[i] = coerced
+		}
+		return coercedItems, nil
+	default:
+		return nil, inputErrorf(value, path, "type %s is not an input type", typeName(typ))
+	}
+}
+
+// coerceArgumentValues coerces the arguments of a field or directive defined
+// by defs, variables in arguments are substituted by variableValues.
+func coerceArgumentValues(defs []*ArgDef, args *ast.Arguments, variableValues map[string]interface{}) (map[string]interface{}, error) {
+	argNodes := map[string]*ast.Argument{}
+	if args != nil {
+		for _, arg := range args.Args {
+			argNodes[arg.Name.Text] = arg
 		}
 	}
+
+	coercedValues := map[string]interface{}{}
+	for _, def := range defs {
+		arg, hasValue := argNodes[def.Name]
+		var value interface{}
+		if hasValue {
+			if variable, ok := arg.Val.(*ast.Variable); ok {
+				value, hasValue = variableValues[variable.Name.Text]
+			} else if isNullValue(arg.Val) {
+				value = nil
+			} else {
+				value = arg.Val
+			}
+		}
+
+		if !hasValue && def.Defl != nil {
+			coercedValues[def.Name] = def.Defl
+			continue
+		}
+		if isNonNull(def.Typ) && (!hasValue || isNil(value)) {
+			return nil, fmt.Errorf("query error: argument %s of non-null type %s must not be null", def.Name, typeName(def.Typ))
+		}
+		if !hasValue {
+			continue
+		}
+
+		if _, ok := arg.Val.(*ast.Variable); ok || isNil(value) {
+			coercedValues[def.Name] = value
+			continue
+		}
+		coercedVal, err := coerceLiteralValue(def.Typ, arg.Val, variableValues)
+		if err != nil {
+			return nil, fmt.Errorf("query error: argument %s got %s", def.Name, err)
+		}
+		coercedValues[def.Name] = coercedVal
+	}
 	return coercedValues, nil
 }
 
-func coerceInputValue(typ Type, value interface{}) (interface{}, error) {
+// coerceLiteralValue coerces val of type typ, variables in val are substituted
+// by variableValues.
+func coerceLiteralValue(typ Type, val ast.Value, variableValues map[string]interface{}) (interface{}, error) {
+	if variable, ok := val.(*ast.Variable); ok {
+		value := variableValues[variable.Name.Text]
+		if isNil(value) && isNonNull(typ) {
+			return nil, literalErrorf(val, "expected non-nullable type %s not to be null", typeName(typ))
+		}
+		return value, nil
+	}
+
+	if nt, ok := typ.(*NonNull); ok {
+		if isNullValue(val) {
+			return nil, literalErrorf(val, "expected non-nullable type %s not to be null", typeName(typ))
+		}
+		return coerceLiteralValue(nt.OfType, val, variableValues)
+	}
+
+	if isNullValue(val) {
+		return nil, nil
+	}
+
 	switch typ := typ.(type) {
+	case *Scalar:
+		if typ.ParseLiteral != nil {
+			coerced, err := typ.ParseLiteral(val)
+			if err != nil {
+				return nil, literalErrorf(val, "%s", err)
+			}
+			return coerced, nil
+		}
+		value, err := literalValue(val, variableValues)
+		if err != nil {
+			return nil, err
+		}
+		return coerceInputValue(typ, value, "")
+	case *Enum:
+		if name, ok := val.(*ast.NameValue); ok {
+			for _, ev := range typ.Values {
+				if ev.Name == name.Val.Text {
+					return ev.value(), nil
+				}
+			}
+		}
+		return nil, literalErrorf(val, "value does not exist in %s enum", typ.Name)
+	case *InputObject:
+		obj, ok := val.(*ast.ObjectValue)
+		if !ok {
+			return nil, literalErrorf(val, "expected type %s to be an object", typ.Name)
+		}
+
+		fieldNodes := map[string]*ast.ObjectField{}
+		for _, f := range obj.ObjFields {
+			if findInputField(typ, f.Name.Text) == nil {
+				return nil, literalErrorf(val, "field %s is not defined by type %s", f.Name.Text, typ.Name)
+			}
+			fieldNodes[f.Name.Text] = f
+		}
+
+		coercedFields := map[string]interface{}{}
+		for _, f := range typ.Fields {
+			node, hasValue := fieldNodes[f.Name]
+			if hasValue {
+				if variable, ok := node.Val.(*ast.Variable); ok {
+					_, hasValue = variableValues[variable.Name.Text]
+				}
+			}
+			if !hasValue {
+				if f.Defl != nil {
+					coercedFields[f.Name] = f.Defl
+				} else if isNonNull(f.Typ) {
+					return nil, literalErrorf(val, "field %s of required type %s was not provided", f.Name, typeName(f.Typ))
+				}
+				continue
+			}
+			coerced, err := coerceLiteralValue(f.Typ, node.Val, variableValues)
+			if err != nil {
+				return nil, err
+			}
+			coercedFields[f.Name] = coerced
+		}
+		return coercedFields, nil
+	case *List:
+		list, ok := val.(*ast.ListValue)
+		if !ok {
+			coerced, err := coerceLiteralValue(typ.OfType, val, variableValues)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{coerced}, nil
+		}
+
+		coercedItems := make([]interface{}, len(list.Vals))
+		for i, item := range list.Vals {
+			coerced, err := coerceLiteralValue(typ.OfType, item, variableValues)
+			if err != nil {
+				return nil, err
+			}
+			coercedItems[i] = coerced
+		}
+		return coercedItems, nil
+	default:
+		return nil, literalErrorf(val, "type %s is not an input type", typeName(typ))
+	}
+}
+
+// literalValue converts val into its Go representation without knowing its
+// type, as a JSON decoded value would be.
+func literalValue(val ast.Value, variableValues map[string]interface{}) (interface{}, error) {
+	switch val := val.(type) {
+	case *ast.Variable:
+		return variableValues[val.Name.Text], nil
+	case *ast.LiteralValue:
+		switch val.Val.Kind {
+		case ast.INT, ast.FLOAT:
+			f, err := strconv.ParseFloat(val.Val.Text, 64)
+			if err != nil {
+				return nil, literalErrorf(val, "%s", err)
+			}
+			return f, nil
+		default:
+			return val.Val.Text, nil
+		}
+	case *ast.NameValue:
+		switch val.Val.Text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			return val.Val.Text, nil
+		}
+	case *ast.ListValue:
+		list := []interface{}{}
+		for _, v := range val.Vals {
+			item, err := literalValue(v, variableValues)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case *ast.ObjectValue:
+		obj := map[string]interface{}{}
+		for _, f := range val.ObjFields {
+			v, err := literalValue(f.Val, variableValues)
+			if err != nil {
+				return nil, err
+			}
+			obj[f.Name.Text] = v
+		}
+		return obj, nil
+	default:
+		return nil, fmt.Errorf("unexpected value %T", val)
+	}
+}
+
+func findInputField(iobj *InputObject, name string) *Field {
+	for _, f := range iobj.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func literalErrorf(val ast.Value, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid value %s; %s", formatASTValue(val), fmt.Sprintf(format, args...))
+}
+
+func inputErrorf(value interface{}, path string, format string, args ...interface{}) error {
+	repr := fmt.Sprint(value)
+	if b, err := json.Marshal(value); err == nil {
+		repr = string(b)
+	}
+	return fmt.Errorf("invalid value %s at %q; %s", repr, path, fmt.Sprintf(format, args...))
+}
+
+func (runtime *Runtime) executeRequest(ctx context.Context, fset *token.FileSet, operation *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition, coercedVariableValues map[string]interface{}) *Response {
+	switch operation.OperType.Text {
+	case "", ast.Stringify(ast.QUERY):
+		return runtime.executeQuery(ctx, fset, operation, fragments, coercedVariableValues, nil)
+	case ast.Stringify(ast.MUTATION):
+		return runtime.executeMutation(ctx, fset, operation, fragments, coercedVariableValues, nil)
+	case ast.Stringify(ast.SUBSCRIPTION):
+		return &Response{
+			Errors: []error{newError(fmt.Errorf("query error: subscription operations must be executed with Subscribe"), fset, nil, operation)},
+		}
+	default:
+		return &Response{
+			Errors: []error{newError(fmt.Errorf("query error: operation type %s not supported", operation.OperType.Text), fset, nil, operation)},
+		}
+	}
+}
+
+func (runtime *Runtime) executeQuery(ctx context.Context, fset *token.FileSet, query *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition, variableValues map[string]interface{}, initialValue interface{}) *Response {
+	if runtime.Schema == nil || runtime.Schema.Qry == nil {
+		return &Response{Errors: []error{newError(fmt.Errorf("query error: schema has no query type"), fset, nil, query)}}
+	}
+	ec := newExecutionContext(ctx, fset, runtime, query, fragments, variableValues)
+	data, _ := ec.executeSelectionSet(query.SelSet, runtime.Schema.Qry, initialValue, nil)
+	return &Response{Data: data, Errors: sortErrors(ec.errs), Executed: true}
+}
+
+func (runtime *Runtime) executeMutation(ctx context.Context, fset *token.FileSet, mutation *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition, variableValues map[string]interface{}, initialValue interface{}) *Response {
+	if runtime.Schema == nil || runtime.Schema.Mut == nil {
+		return &Response{Errors: []error{newError(fmt.Errorf("query error: schema has no mutation type"), fset, nil, mutation)}}
+	}
+	ec := newExecutionContext(ctx, fset, runtime, mutation, fragments, variableValues)
+	data, _ := ec.executeFields(runtime.Schema.Mut, initialValue, ec.collectFields(runtime.Schema.Mut, mutation.SelSet), nil, false)
+	return &Response{Data: data, Errors: sortErrors(ec.errs), Executed: true}
+}
+
+// executionContext holds the state of executing one operation, fset is used
+// to locate errors.
+type executionContext struct {
+	ctx            context.Context
+	fset           *token.FileSet
+	runtime        *Runtime
+	operation      *ast.OperationDefinition
+	fragments      map[string]*ast.FragmentDefinition
+	variableValues map[string]interface{}
+	slots          chan struct{} // one for each resolver being called, nil if unlimited
+
+	mu   sync.Mutex // guards errs
+	errs []error
+}
+
+func newExecutionContext(ctx context.Context, fset *token.FileSet, runtime *Runtime, operation *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition, variableValues map[string]interface{}) *executionContext {
+	ec := &executionContext{
+		ctx:            ctx,
+		fset:           fset,
+		runtime:        runtime,
+		operation:      operation,
+		fragments:      fragments,
+		variableValues: variableValues,
+	}
+	if runtime.Concurrency > 1 {
+		ec.slots = make(chan struct{}, runtime.Concurrency)
+	}
+	return ec
+}
+
+// addError adds err of the field at path, which is located at fields.
+func (ec *executionContext) addError(err error, fields []*ast.Field, path []interface{}) {
+	nodes := make([]ast.Node, len(fields))
+	for i, field := range fields {
+		nodes[i] = field
+	}
+	e := newError(err, ec.fset, path, nodes...)
+	ec.mu.Lock()
+	ec.errs = append(ec.errs, e)
+	ec.mu.Unlock()
+}
+
+// executeSelectionSet returns the result map of selSet on objValue, the fields
+// are executed concurrently. If a non-null field is null, the result map is
+// null and ok is false, the null propagates to the nearest nullable parent.
+func (ec *executionContext) executeSelectionSet(selSet *ast.SelectionSet, objType *Object, objValue interface{}, path []interface{}) (*Result, bool) {
+	return ec.executeFields(objType, objValue, ec.collectFields(objType, selSet), path, ec.runtime.Concurrency != 1)
+}
+
+// executeFields executes groupedFieldSet on objValue, concurrently if parallel
+// or one after another otherwise. Results are kept in the order of
+// groupedFieldSet whichever field completes first.
+func (ec *executionContext) executeFields(objType *Object, objValue interface{}, groupedFieldSet []*FieldGroup, path []interface{}, parallel bool) (*Result, bool) {
+	fieldDefs := make([]*Field, len(groupedFieldSet))
+	for i, group := range groupedFieldSet {
+		fieldDefs[i] = findField(objType, group.Fields[0].Name.Text)
+		if fieldDefs[i] == nil {
+			fieldDefs[i] = ec.runtime.metaField(objType, group.Fields[0].Name.Text)
+		}
+	}
+
+	values := make([]interface{}, len(groupedFieldSet))
+	valid := make([]bool, len(groupedFieldSet))
+	execute := func(i int) {
+		group := groupedFieldSet[i]
+		values[i], valid[i] = ec.executeField(objType, objValue, fieldDefs[i], group.Fields, appendPath(path, group.Key))
+	}
+
+	var wg sync.WaitGroup
+	for i := range groupedFieldSet {
+		switch {
+		case fieldDefs[i] == nil:
+			valid[i] = true
+		case parallel && len(groupedFieldSet) > 1:
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				execute(i)
+			}(i)
+		default:
+			execute(i)
+		}
+	}
+	wg.Wait()
+
+	// a null non-null field nulls the result map after all fields are
+	// executed, so that the errors of the other fields are reported
+	resultMap := &Result{Values: map[string]interface{}{}}
+	for i, group := range groupedFieldSet {
+		if !valid[i] {
+			return nil, false
+		}
+		if fieldDefs[i] != nil {
+			resultMap.Keys = append(resultMap.Keys, group.Key)
+			resultMap.Values[group.Key] = values[i]
+		}
+	}
+	return resultMap, true
+}
+
+// FieldGroup is a group of field nodes sharing the same response key, which
+// is the alias of the fields or their name.
+type FieldGroup struct {
+	Key    string
+	Fields []*ast.Field
+}
+
+// CollectFields returns the fields of selSet executed on objects of objType,
+// grouped by their response keys in the order they first appear. Fields of
+// fragment spreads and inline fragments are collected if their type conditions
+// apply to objType, and fields skipped by @skip or @include are left out.
+// fragments are the fragment definitions of the document by name, such as the
+// ones in ResolveInfo, and variableValues are the coerced variable values.
+func (runtime *Runtime) CollectFields(objType *Object, selSet *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, variableValues map[string]interface{}) []*FieldGroup {
+	var groupedFields []*FieldGroup
+	groups := map[string]*FieldGroup{}
+	visited := map[string]bool{}
+
+	var collect func(selSet *ast.SelectionSet)
+	collect = func(selSet *ast.SelectionSet) {
+		if selSet == nil {
+			return
+		}
+		for _, sel := range selSet.Sels {
+			switch sel := sel.(type) {
+			case *ast.Field:
+				if !runtime.included(sel.Directs, variableValues) {
+					continue
+				}
+				key := responseKey(sel)
+				group, ok := groups[key]
+				if !ok {
+					group = &FieldGroup{Key: key}
+					groups[key] = group
+					groupedFields = append(groupedFields, group)
+				}
+				group.Fields = append(group.Fields, sel)
+			case *ast.FragmentSpread:
+				name := sel.Name.Text
+				if visited[name] || !runtime.included(sel.Directs, variableValues) {
+					continue
+				}
+				visited[name] = true
+				frag, ok := fragments[name]
+				if !ok || !runtime.doesFragmentTypeApply(objType, frag.TypeCond) {
+					continue
+				}
+				collect(frag.SelSet)
+			case *ast.InlineFragment:
+				if !runtime.included(sel.Directs, variableValues) || !runtime.doesFragmentTypeApply(objType, sel.TypeCond) {
+					continue
+				}
+				collect(sel.SelSet)
+			}
+		}
+	}
+	collect(selSet)
+	return groupedFields
+}
+
+// included reports if a selection with directs is included, it is not if
+// @skip(if: true) or @include(if: false) is present.
+func (runtime *Runtime) included(directs *ast.Directives, variableValues map[string]interface{}) bool {
+	if directs == nil {
+		return true
+	}
+	for _, direct := range directs.Directs {
+		name := direct.Name.Text
+		if name != "skip" && name != "include" {
+			continue
+		}
+		args, err := coerceArgumentValues(runtime.Directives[name].Defs, direct.Args, variableValues)
+		if err != nil {
+			continue
+		}
+		if cond, _ := args["if"].(bool); cond == (name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// doesFragmentTypeApply reports if a fragment with typeCond applies to objects
+// of objType, which is the case if typeCond is objType, an interface objType
+// implements or a union including objType. A fragment without type condition
+// always applies.
+func (runtime *Runtime) doesFragmentTypeApply(objType *Object, typeCond *ast.TypeCondition) bool {
+	if typeCond == nil {
+		return true
+	}
+	switch typ := runtime.findType(typeCond.NamedTyp.Name.Text).(type) {
 	case *Object:
-		return nil, fmt.Errorf("invalid input object %s", typ.Name)
+		return typ == objType
 	case *Interface:
-		return nil, fmt.Errorf("invalid input interface %s", typ.Name)
+		for _, iface := range objType.Ifaces {
+			if iface == typ {
+				return true
+			}
+		}
 	case *Union:
-		return nil, fmt.Errorf("invalid input union %s", typ.Name)
+		for _, member := range typ.Typs {
+			if member == objType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// collectFields collects the fields of selSet executed on objType.
+func (ec *executionContext) collectFields(objType *Object, selSet *ast.SelectionSet) []*FieldGroup {
+	return ec.runtime.CollectFields(objType, selSet, ec.fragments, ec.variableValues)
+}
+
+// executeField returns the completed value of a field, which is null if an
+// error occurred. The error is recorded once, and ok is false if the field is
+// non-null so that the null propagates.
+func (ec *executionContext) executeField(objType *Object, objValue interface{}, fieldDef *Field, fields []*ast.Field, path []interface{}) (interface{}, bool) {
+	info := &ResolveInfo{
+		FieldName:      fieldDef.Name,
+		FieldNodes:     fields,
+		ReturnType:     fieldDef.Typ,
+		ParentType:     objType,
+		Path:           path,
+		Operation:      ec.operation,
+		Fragments:      ec.fragments,
+		VariableValues: ec.variableValues,
+		Runtime:        ec.runtime,
+	}
+	if err := ec.ctx.Err(); err != nil {
+		ec.addError(notExecuted(fields, err), fields, path)
+		return nil, !isNonNull(fieldDef.Typ)
+	}
+	argumentValues, err := coerceArgumentValues(fieldDef.Defs, fields[0].Args, ec.variableValues)
+	if err != nil {
+		ec.addError(err, fields, path)
+		return nil, !isNonNull(fieldDef.Typ)
+	}
+	resolvedValue, err := ec.resolveFieldValue(fieldDef, objValue, argumentValues, info)
+	if err != nil {
+		ec.addError(err, fields, path)
+		return nil, !isNonNull(fieldDef.Typ)
+	}
+	completedValue, ok := ec.completeValue(fieldDef.Typ, fields, resolvedValue, info, path)
+	if !ok {
+		return nil, !isNonNull(fieldDef.Typ)
+	}
+	return completedValue, true
+}
+
+func (ec *executionContext) resolveFieldValue(fieldDef *Field, objValue interface{}, argumentValues map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+	resolve := fieldDef.Resolve
+	if resolve == nil {
+		if fieldDef.Subscribe != nil {
+			// the source is the event of subscription
+			return objValue, nil
+		}
+		resolve = DefaultResolve
+	}
+	if ec.slots != nil {
+		select {
+		case ec.slots <- struct{}{}:
+			defer func() { <-ec.slots }()
+		case <-ec.ctx.Done():
+			return nil, notExecuted(info.FieldNodes, ec.ctx.Err())
+		}
+	}
+
+	ctx := ec.ctx
+	if fieldDef.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fieldDef.Timeout)
+		defer cancel()
+	}
+	return resolve(ctx, objValue, argumentValues, info)
+}
+
+// notExecuted returns the error of fields not executed as the context of
+// execution is done with err.
+func notExecuted(fields []*ast.Field, err error) error {
+	return fmt.Errorf("query error: field %s was not executed: %w", fields[0].Name.Text, err)
+}
+
+// completeValue completes result of fieldType at path. If an error occurred,
+// it is recorded and ok is false, the caller decides whether the null
+// propagates: nullable fields and list items become null, while non-null
+// ones pass it on to their parents.
+func (ec *executionContext) completeValue(fieldType Type, fields []*ast.Field, result interface{}, info *ResolveInfo, path []interface{}) (completedResult interface{}, ok bool) {
+	if nn, ok := fieldType.(*NonNull); ok {
+		value, valid := ec.completeValue(nn.OfType, fields, result, info, path)
+		if !valid {
+			return nil, false
+		}
+		if value == nil {
+			ec.addError(fmt.Errorf("query error: non-null field %s returned null", fields[0].Name.Text), fields, path)
+			return nil, false
+		}
+		return value, true
+	}
+
+	if isNil(result) {
+		return nil, true
+	}
+
+	var err error
+	switch typ := fieldType.(type) {
+	case *List:
+		v := reflect.ValueOf(result)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			err = fmt.Errorf("query error: expecting list for field %s, found %T", fields[0].Name.Text, result)
+			break
+		}
+		completedList := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, valid := ec.completeValue(typ.OfType, fields, v.Index(i).Interface(), info, appendPath(path, i))
+			if !valid && isNonNull(typ.OfType) {
+				return nil, false
+			}
+			completedList[i] = item
+		}
+		return completedList, true
 	case *Scalar:
-		return nil, fmt.Errorf("invalid input type %T", typ)
+		completedResult, err = serializeScalar(typ, result)
 	case *Enum:
-		return nil, fmt.Errorf("invalid input type %T", typ)
-	case *InputObject:
-		return nil, fmt.Errorf("invalid input type %T", typ)
-	case *List:
-		return nil, fmt.Errorf("invalid input type %T", typ)
-	case *NonNull:
-		return nil, fmt.Errorf("invalid input type %T", typ)
+		completedResult, err = serializeEnum(typ, result)
+	case *Object:
+		return ec.completeObject(typ, fields, result, path)
+	case *Interface, *Union:
+		var objType *Object
+		if objType, err = ec.resolveAbstractType(typ, result, info); err == nil {
+			return ec.completeObject(objType, fields, result, path)
+		}
 	default:
-		return nil, fmt.Errorf("invalid input type %T", typ)
+		err = fmt.Errorf("query error: unexpected output type %T", typ)
+	}
+
+	if err != nil {
+		ec.addError(err, fields, path)
+		return nil, false
+	}
+	return completedResult, true
+}
+
+// completeObject executes the merged selection sets of fields on result, the
+// result map is returned as an untyped nil if it is null.
+func (ec *executionContext) completeObject(objType *Object, fields []*ast.Field, result interface{}, path []interface{}) (interface{}, bool) {
+	resultMap, ok := ec.executeSelectionSet(mergeSelectionSets(fields), objType, result, path)
+	if !ok {
+		return nil, false
+	}
+	return resultMap, true
+}
+
+func serializeScalar(scalar *Scalar, result interface{}) (interface{}, error) {
+	if scalar.Serialize == nil {
+		return result, nil
+	}
+	return scalar.Serialize(result)
+}
+
+func serializeEnum(enum *Enum, result interface{}) (interface{}, error) {
+	for _, ev := range enum.Values {
+		if reflect.DeepEqual(ev.value(), result) {
+			return ev.Name, nil
+		}
+	}
+	return nil, fmt.Errorf("enum %s cannot represent value %v", enum.Name, result)
+}
+
+func (ec *executionContext) resolveAbstractType(typ Type, result interface{}, info *ResolveInfo) (*Object, error) {
+	var name string
+	var resolveType ResolveTypeFunc
+	switch typ := typ.(type) {
+	case *Interface:
+		name, resolveType = typ.Name, typ.ResolveType
+	case *Union:
+		name, resolveType = typ.Name, typ.ResolveType
+	}
+
+	if resolveType == nil {
+		return nil, fmt.Errorf("query error: no ResolveType provided for abstract type %s", name)
+	}
+	objType := resolveType(ec.ctx, result, info)
+	if objType == nil {
+		return nil, fmt.Errorf("query error: abstract type %s must resolve to an object type", name)
+	}
+	return objType, nil
+}
+
+func mergeSelectionSets(fields []*ast.Field) *ast.SelectionSet {
+	selSet := &ast.SelectionSet{}
+	for _, field := range fields {
+		if field.SelSet == nil {
+			continue
+		}
+		selSet.Sels = append(selSet.Sels, field.SelSet.Sels...)
 	}
+	return selSet
 }
 
-func (runtime *Runtime) executeRequest(operation *ast.OperationDefinition, coercedVariableValues map[string]interface{}) *Response {
+func findField(objType *Object, name string) *Field {
+	for _, f := range objType.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
 	return nil
 }
 
+func responseKey(field *ast.Field) string {
+	if field.Als != nil {
+		return field.Als.Name.Text
+	}
+	return field.Name.Text
+}
+
+// appendPath returns a copy of path with key appended, so that paths of sibling
+// fields never share the underlying array.
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	newPath := make([]interface{}, len(path), len(path)+1)
+	copy(newPath, path)
+	return append(newPath, key)
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
 func isNonNull(typ Type) bool {
 	_, ok := typ.(*NonNull)
 	return ok
 }
 
+// resolveASTType returns the type astTyp refers to, or nil if the named type is
+// not found.
 func (runtime *Runtime) resolveASTType(astTyp ast.Type) Type {
-	return runtime.findType(formatName(astTyp))
+	var typ Type
+	var nonNull bool
+	switch astTyp := astTyp.(type) {
+	case *ast.NamedType:
+		nonNull = astTyp.NonNull
+		typ = runtime.findType(astTyp.Name.Text)
+	case *ast.ListType:
+		nonNull = astTyp.NonNull
+		if ofType := runtime.resolveASTType(astTyp.Typ); ofType != nil {
+			typ = &List{OfType: ofType}
+		}
+	default:
+		panic(fmt.Errorf("unexpected AST type %T", astTyp))
+	}
+
+	if typ == nil {
+		return nil
+	}
+	if nonNull {
+		return &NonNull{OfType: typ}
+	}
+	return typ
 }
 
-func formatName(typ ast.Type) string {
-	var bang string
+// possibleTypes returns the object types typ may be resolved to at runtime.
+func (runtime *Runtime) possibleTypes(typ Type) []*Object {
 	switch typ := typ.(type) {
-	case *ast.NamedType:
-		if typ.NonNull {
-			bang = "!"
+	case *Object:
+		return []*Object{typ}
+	case *Union:
+		var objs []*Object
+		for _, t := range typ.Typs {
+			if obj, ok := t.(*Object); ok {
+				objs = append(objs, obj)
+			}
 		}
-		return typ.Name.Text + bang
-	case *ast.ListType:
-		if typ.NonNull {
-			bang = "!"
+		return objs
+	case *Interface:
+		var objs []*Object
+		for _, obj := range runtime.Objects {
+			for _, iface := range obj.Ifaces {
+				if iface == typ {
+					objs = append(objs, obj)
+					break
+				}
+			}
 		}
-		return fmt.Sprintf("[ %s ]", formatName(typ.Typ)) + bang
-	default:
-		panic(fmt.Errorf("unexpected AST type %T", typ))
+		sort.Slice(objs, func(i, j int) bool {
+			return objs[i].Name < objs[j].Name
+		})
+		return objs
 	}
+	return nil
 }
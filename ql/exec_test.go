@@ -0,0 +1,752 @@
+package ql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// assertEqual compares expected with found, in which results are turned into
+// plain maps by plainResult.
+func assertEqual(t *testing.T, expected, found interface{}) {
+	if found = plainResult(found); !reflect.DeepEqual(expected, found) {
+		t.Errorf("expected %#v, found %#v", expected, found)
+	}
+}
+
+// plainResult returns v with every *Result in it turned into a map.
+func plainResult(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *Result:
+		if v == nil {
+			return map[string]interface{}(nil)
+		}
+		m := map[string]interface{}{}
+		for _, key := range v.Keys {
+			m[key] = plainResult(v.Values[key])
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i := range v {
+			list[i] = plainResult(v[i])
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+func parseDocument(t *testing.T, fset *token.FileSet, document string) *ast.Document {
+	doc, err := ast.ParseDocument([]byte(document), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func execute(t *testing.T, runtime *Runtime, document, operationName string, variableValues map[string]interface{}) *Response {
+	fset := token.NewFileSet()
+	return runtime.Execute(fset, parseDocument(t, fset, document), operationName, variableValues)
+}
+
+type human struct {
+	Name    string
+	Height  float64 `json:"height"`
+	Friends []*human
+}
+
+func humanSchema() *Schema {
+	episode := &Enum{
+		Name: "Episode",
+		Values: []*EnumValue{
+			{Name: "NEWHOPE", Value: 4},
+			{Name: "EMPIRE", Value: 5},
+			{Name: "JEDI", Value: 6},
+		},
+	}
+
+	humanType := &Object{Name: "Human"}
+	humanType.Fields = []*Field{
+		{Name: "name", Typ: &NonNull{OfType: String}},
+		{Name: "height", Typ: Float},
+		{Name: "friends", Typ: &List{OfType: humanType}},
+		{
+			Name: "appearsIn",
+			Typ:  &List{OfType: episode},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+				return []int{4, 5, 6}, nil
+			},
+		},
+		{
+			Name: "secret",
+			Typ:  String,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+				return nil, errors.New("secret is secret")
+			},
+		},
+	}
+
+	luke := &human{Name: "Luke", Height: 1.72}
+	han := &human{Name: "Han", Height: 1.8}
+	luke.Friends = []*human{han}
+	han.Friends = []*human{luke}
+
+	queryType := &Object{
+		Name: "Query",
+		Fields: []*Field{
+			{
+				Name: "hero",
+				Typ:  humanType,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+					return luke, nil
+				},
+			},
+		},
+	}
+
+	return &Schema{Qry: queryType}
+}
+
+func TestExecuteQuery(t *testing.T) {
+	runtime := newRuntime(humanSchema())
+	rsp := execute(t, runtime, `
+{
+	hero {
+		name
+		tall: height
+		friends {
+			name
+			friends { name }
+		}
+		appearsIn
+	}
+}`, "", nil)
+
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+
+	expected := map[string]interface{}{
+		"hero": map[string]interface{}{
+			"name": "Luke",
+			"tall": 1.72,
+			"friends": []interface{}{
+				map[string]interface{}{
+					"name": "Han",
+					"friends": []interface{}{
+						map[string]interface{}{"name": "Luke"},
+					},
+				},
+			},
+			"appearsIn": []interface{}{"NEWHOPE", "EMPIRE", "JEDI"},
+		},
+	}
+	assertEqual(t, expected, rsp.Data)
+}
+
+func TestExecuteResultOrder(t *testing.T) {
+	runtime := newRuntime(humanSchema())
+	rsp := execute(t, runtime, `{ hero { z: name friends { name z: height } a: height } }`, "", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+
+	b, err := json.Marshal(rsp.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, `{"hero":{"z":"Luke","friends":[{"name":"Han","z":1.8}],"a":1.72}}`, string(b))
+}
+
+func TestExecuteResolverError(t *testing.T) {
+	runtime := newRuntime(humanSchema())
+	rsp := execute(t, runtime, `query Q { hero { name secret } }`, "Q", nil)
+
+	if len(rsp.Errors) != 1 {
+		t.Fatalf("expecting 1 error, found %v", rsp.Errors)
+	}
+	assertEqual(t, &Error{
+		Message:   "secret is secret",
+		Locations: []Location{{Line: 1, Column: 23}},
+		Path:      []interface{}{"hero", "secret"},
+		Err:       rsp.Errors[0].(*Error).Err,
+	}, rsp.Errors[0])
+	assertEqual(t, "1:23: secret is secret", rsp.Errors[0].Error())
+
+	expected := map[string]interface{}{
+		"hero": map[string]interface{}{
+			"name":   "Luke",
+			"secret": nil,
+		},
+	}
+	assertEqual(t, expected, rsp.Data)
+}
+
+func TestExecuteNullPropagation(t *testing.T) {
+	schema := buildSchema(t, `
+type Item {
+  id: Int!
+  name: String!
+  tags: [String!]
+  notes: [String]!
+  fail: String!
+}
+
+type Query {
+  item: Item
+  items: [Item!]
+  required: Item!
+  nullable: String
+}
+`)
+	root := map[string]interface{}{
+		"item":     map[string]interface{}{"id": 1, "tags": []interface{}{"a", nil}, "notes": []interface{}{"b", nil}},
+		"items":    []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{}},
+		"required": map[string]interface{}{},
+		"nullable": "x",
+	}
+	for _, f := range schema.Qry.Fields {
+		f.Resolve = func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+			return root[info.FieldName], nil
+		}
+	}
+	item := namedType(findField(schema.Qry, "item").Typ).(*Object)
+	findField(item, "fail").Resolve = func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		return nil, errors.New("failed")
+	}
+	runtime := newRuntime(schema)
+
+	tests := []struct {
+		doc      string
+		data     map[string]interface{}
+		messages []string
+		paths    [][]interface{}
+	}{
+		{
+			`{ item { id name } nullable }`,
+			map[string]interface{}{"item": nil, "nullable": "x"},
+			[]string{"query error: non-null field name returned null"},
+			[][]interface{}{{"item", "name"}},
+		},
+		{
+			`{ item { id fail } }`,
+			map[string]interface{}{"item": nil},
+			[]string{"failed"},
+			[][]interface{}{{"item", "fail"}},
+		},
+		{
+			`{ items { id } }`,
+			map[string]interface{}{"items": nil},
+			[]string{"query error: non-null field id returned null"},
+			[][]interface{}{{"items", 1, "id"}},
+		},
+		{
+			`{ item { id tags notes } }`,
+			map[string]interface{}{"item": map[string]interface{}{"id": 1, "tags": nil, "notes": []interface{}{"b", nil}}},
+			[]string{"query error: non-null field tags returned null"},
+			[][]interface{}{{"item", "tags", 1}},
+		},
+		{
+			`{ nullable required { id name } }`,
+			nil,
+			[]string{"query error: non-null field id returned null", "query error: non-null field name returned null"},
+			[][]interface{}{{"required", "id"}, {"required", "name"}},
+		},
+	}
+	for _, test := range tests {
+		rsp := execute(t, runtime, test.doc, "", nil)
+		assertEqual(t, test.data, rsp.Data)
+		var messages []string
+		var paths [][]interface{}
+		for _, err := range rsp.Errors {
+			messages = append(messages, err.(*Error).Message)
+			paths = append(paths, err.(*Error).Path)
+		}
+		assertEqual(t, test.messages, messages)
+		assertEqual(t, test.paths, paths)
+	}
+}
+
+func TestExecuteConcurrently(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	var started chan string
+	var release map[string]chan struct{}
+	resolve := func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		key := info.Path[len(info.Path)-1].(string)
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		started <- key
+		<-release[key]
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return key, nil
+	}
+	schema := &Schema{
+		Qry: &Object{Name: "Query", Fields: []*Field{{Name: "wait", Typ: String, Resolve: resolve}}},
+		Mut: &Object{Name: "Mutation", Fields: []*Field{{Name: "wait", Typ: String, Resolve: resolve}}},
+	}
+
+	// maxRunning resolvers must be running before any of them is released,
+	// then they are released in the order of release, or the order they
+	// started if it is nil.
+	tests := []struct {
+		concurrency int
+		doc         string
+		maxRunning  int
+		release     []string
+		starts      []string
+	}{
+		{0, `{ a: wait b: wait c: wait }`, 3, []string{"c", "b", "a"}, nil},
+		{2, `{ a: wait b: wait c: wait }`, 2, nil, nil},
+		{1, `{ a: wait b: wait c: wait }`, 1, nil, []string{"a", "b", "c"}},
+		{0, `mutation { a: wait b: wait c: wait }`, 1, nil, []string{"a", "b", "c"}},
+	}
+	for _, test := range tests {
+		runtime := newRuntime(schema)
+		runtime.Concurrency = test.concurrency
+		running, maxRunning = 0, 0
+		started = make(chan string, 3)
+		release = map[string]chan struct{}{"a": make(chan struct{}, 1), "b": make(chan struct{}, 1), "c": make(chan struct{}, 1)}
+
+		done := make(chan *Response, 1)
+		go func(doc string) {
+			done <- execute(t, runtime, doc, "", nil)
+		}(test.doc)
+
+		var starts []string
+		receive := func() {
+			select {
+			case key := <-started:
+				starts = append(starts, key)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: expecting %d resolvers running, found %v", test.doc, test.maxRunning, starts)
+			}
+		}
+		for len(starts) < test.maxRunning {
+			receive()
+		}
+		for i := 0; i < 3; i++ {
+			for len(starts) <= i || (test.release != nil && !contains(starts, test.release[i])) {
+				receive()
+			}
+			key := starts[i]
+			if test.release != nil {
+				key = test.release[i]
+			}
+			release[key] <- struct{}{}
+		}
+
+		rsp := <-done
+		if len(rsp.Errors) > 0 {
+			t.Fatal(rsp.Errors)
+		}
+		assertEqual(t, test.maxRunning, maxRunning)
+		if test.starts != nil {
+			assertEqual(t, test.starts, starts)
+		}
+		assertEqual(t, []string{"a", "b", "c"}, rsp.Data.Keys)
+		assertEqual(t, map[string]interface{}{"a": "a", "b": "b", "c": "c"}, rsp.Data)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestExecuteConcurrentErrors(t *testing.T) {
+	// fields fail in the order of c, a and b
+	done := map[string]chan struct{}{"a": make(chan struct{}), "b": make(chan struct{}), "c": make(chan struct{})}
+	after := map[string]string{"a": "c", "b": "a"}
+	fail := func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		key := info.Path[len(info.Path)-1].(string)
+		defer close(done[key])
+		if prev, ok := after[key]; ok {
+			select {
+			case <-done[prev]:
+			case <-time.After(5 * time.Second):
+				return nil, fmt.Errorf("%s not executed concurrently with %s", key, prev)
+			}
+		}
+		return nil, fmt.Errorf("%s failed", key)
+	}
+	runtime := newRuntime(&Schema{Qry: &Object{Name: "Query", Fields: []*Field{
+		{Name: "fail", Typ: Int, Resolve: fail},
+	}}})
+
+	rsp := execute(t, runtime, `{ a: fail b: fail c: fail }`, "", nil)
+	var messages []string
+	for _, err := range rsp.Errors {
+		messages = append(messages, err.Error())
+	}
+	assertEqual(t, []string{"1:3: a failed", "1:11: b failed", "1:19: c failed"}, messages)
+	assertEqual(t, map[string]interface{}{"a": nil, "b": nil, "c": nil}, rsp.Data)
+}
+
+func TestExecuteContext(t *testing.T) {
+	type key struct{}
+	value := func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		return ctx.Value(key{}), nil
+	}
+	// block returns once the operation is done
+	block := func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		<-ctx.Done()
+		return ctx.Value(key{}), nil
+	}
+	wait := func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	runtime := newRuntime(&Schema{Qry: &Object{Name: "Query", Fields: []*Field{
+		{Name: "value", Typ: String, Resolve: value},
+		{Name: "block", Typ: String, Resolve: block},
+		{Name: "wait", Typ: String, Resolve: wait, Timeout: 10 * time.Millisecond},
+	}}})
+	runtime.Concurrency = 1
+
+	fset := token.NewFileSet()
+	doc := parseDocument(t, fset, `{ a: value b: value wait }`)
+	ctx := context.WithValue(context.Background(), key{}, "value")
+
+	// the field timeout
+	rsp := runtime.ExecuteContext(ctx, fset, doc, "", nil)
+	assertEqual(t, map[string]interface{}{"a": "value", "b": "value", "wait": nil}, rsp.Data)
+	assertEqual(t, 1, len(rsp.Errors))
+	assertEqual(t, []interface{}{"wait"}, rsp.Errors[0].(*Error).Path)
+	assertEqual(t, true, errors.Is(rsp.Errors[0], context.DeadlineExceeded))
+
+	// the operation timeout, fields after a are not executed
+	runtime.Timeout = 10 * time.Millisecond
+	rsp = runtime.ExecuteContext(ctx, fset, parseDocument(t, fset, `{ a: block b: value wait }`), "", nil)
+	assertEqual(t, map[string]interface{}{"a": "value", "b": nil, "wait": nil}, rsp.Data)
+	var messages []string
+	for _, err := range rsp.Errors {
+		messages = append(messages, err.Error())
+		assertEqual(t, true, errors.Is(err, context.DeadlineExceeded))
+	}
+	assertEqual(t, []string{
+		"1:12: query error: field value was not executed: context deadline exceeded",
+		"1:21: query error: field wait was not executed: context deadline exceeded",
+	}, messages)
+
+	// cancelled before execution
+	runtime.Timeout = 0
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	rsp = runtime.ExecuteContext(cancelled, fset, doc, "", nil)
+	assertEqual(t, map[string]interface{}{"a": nil, "b": nil, "wait": nil}, rsp.Data)
+	assertEqual(t, 3, len(rsp.Errors))
+	assertEqual(t, true, errors.Is(rsp.Errors[0], context.Canceled))
+}
+
+func TestExecuteFragments(t *testing.T) {
+	runtime := newRuntime(humanSchema())
+	rsp := execute(t, runtime, `query Q($hide: Boolean = true) {
+	hero {
+		...fields
+		... on Human { tall: height friends { height } }
+		... @skip(if: $hide) { secret }
+		secret @include(if: false)
+	}
+}
+
+fragment fields on Human { name friends { name } }`, "Q", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+	expected := map[string]interface{}{
+		"hero": map[string]interface{}{
+			"name":    "Luke",
+			"tall":    1.72,
+			"friends": []interface{}{map[string]interface{}{"name": "Han", "height": 1.8}},
+		},
+	}
+	assertEqual(t, expected, rsp.Data)
+}
+
+func TestCollectFields(t *testing.T) {
+	runtime := newRuntime(buildSchema(t, validateSDL))
+	fset := token.NewFileSet()
+	doc := parseDocument(t, fset, `query Q($skip: Boolean!) {
+  pet {
+    name
+    ... on Dog { name barks }
+    ... on Cat { meows }
+    ... on CatOrDog { __typename }
+    ...dogFields @skip(if: $skip)
+    alias: name @include(if: false)
+    ... { name }
+  }
+}
+
+fragment dogFields on Dog { owner { name } }`)
+	selSet := doc.Defs[0].(*ast.OperationDefinition).SelSet.Sels[0].(*ast.Field).SelSet
+
+	tests := []struct {
+		typ    *Object
+		skip   bool
+		keys   []string
+		counts []int
+	}{
+		{runtime.Objects["Dog"], false, []string{"name", "barks", "__typename", "owner"}, []int{3, 1, 1, 1}},
+		{runtime.Objects["Cat"], true, []string{"name", "meows", "__typename"}, []int{2, 1, 1}},
+		{runtime.Objects["Human"], false, []string{"name"}, []int{2}},
+	}
+	for _, test := range tests {
+		var keys []string
+		var counts []int
+		for _, group := range runtime.CollectFields(test.typ, selSet, fragmentsOf(doc), map[string]interface{}{"skip": test.skip}) {
+			keys = append(keys, group.Key)
+			counts = append(counts, len(group.Fields))
+		}
+		assertEqual(t, test.keys, keys)
+		assertEqual(t, test.counts, counts)
+	}
+}
+
+func TestExecuteMissingMutation(t *testing.T) {
+	runtime := newRuntime(humanSchema())
+	rsp := execute(t, runtime, `mutation { hero { name } }`, "", nil)
+
+	if len(rsp.Errors) != 1 {
+		t.Fatalf("expecting 1 error, found %v", rsp.Errors)
+	}
+	if rsp.Data != nil {
+		t.Errorf("expecting no data, found %v", rsp.Data)
+	}
+}
+
+func TestResolveInfo(t *testing.T) {
+	var paths [][]interface{}
+	var parents []string
+	schema := humanSchema()
+	friends := findField(schema.Qry.Fields[0].Typ.(*Object), "friends")
+	friends.Resolve = func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		paths = append(paths, info.Path)
+		parents = append(parents, info.ParentType.Name)
+		assertEqual(t, "buddies", info.FieldNodes[0].Als.Name.Text)
+		return source.(*human).Friends, nil
+	}
+
+	runtime := newRuntime(schema)
+	rsp := execute(t, runtime, `{ hero { buddies: friends { buddies: friends { name } } } }`, "", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+
+	expected := [][]interface{}{
+		{"hero", "buddies"},
+		{"hero", "buddies", 0, "buddies"},
+	}
+	assertEqual(t, expected, paths)
+	assertEqual(t, []string{"Human", "Human"}, parents)
+}
+
+func TestDefaultResolve(t *testing.T) {
+	type droid struct {
+		Name     string
+		Function string `json:"primaryFunction"`
+		Secret   string `json:"-"`
+	}
+
+	tests := []struct {
+		source   interface{}
+		field    string
+		expected interface{}
+	}{
+		{map[string]interface{}{"name": "R2-D2"}, "name", "R2-D2"},
+		{map[string]string{"name": "R2-D2"}, "name", "R2-D2"},
+		{droid{Name: "R2-D2"}, "name", "R2-D2"},
+		{&droid{Function: "Astromech"}, "primaryFunction", "Astromech"},
+		{&droid{Function: "Astromech"}, "function", nil},
+		{&droid{Secret: "plans"}, "secret", nil},
+		{(*droid)(nil), "name", nil},
+		{nil, "name", nil},
+	}
+
+	for _, test := range tests {
+		found, err := DefaultResolve(context.Background(), test.source, nil, &ResolveInfo{FieldName: test.field})
+		if err != nil {
+			t.Error(err)
+		}
+		assertEqual(t, test.expected, found)
+	}
+}
+
+func TestCoerceVariableValues(t *testing.T) {
+	color := &Enum{Name: "Color", Values: []*EnumValue{{Name: "RED", Value: 0}, {Name: "BLUE", Value: 2}}}
+	filter := &InputObject{
+		Name: "Filter",
+		Fields: []*Field{
+			{Name: "color", Typ: &NonNull{OfType: color}},
+			{Name: "ids", Typ: &List{OfType: &NonNull{OfType: ID}}},
+			{Name: "limit", Typ: Int, Defl: 10},
+		},
+	}
+	query := &Object{
+		Name: "Query",
+		Fields: []*Field{
+			{Name: "search", Typ: String, Defs: []*ArgDef{
+				{Name: "filter", Typ: filter},
+				{Name: "ratio", Typ: Float},
+				{Name: "tags", Typ: &List{OfType: String}},
+				{Name: "first", Typ: &NonNull{OfType: Int}},
+			}},
+		},
+	}
+	runtime := newRuntime(&Schema{Qry: query})
+	fset := token.NewFileSet()
+	doc := parseDocument(t, fset, `query Q($filter: Filter, $ratio: Float, $tags: [String], $first: Int!) {
+	search(filter: $filter, ratio: $ratio, tags: $tags, first: $first)
+}`)
+	oper := doc.Defs[0].(*ast.OperationDefinition)
+
+	values, err := runtime.coerceVariableValues(oper, map[string]interface{}{
+		"filter": map[string]interface{}{"color": "BLUE", "ids": []interface{}{1.0, "b"}},
+		"ratio":  1.0,
+		"tags":   "a",
+		"first":  json.Number("3"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"filter": map[string]interface{}{"color": 2, "ids": []interface{}{"1", "b"}, "limit": 10},
+		"ratio":  1.0,
+		"tags":   []interface{}{"a"},
+		"first":  3,
+	}
+	assertEqual(t, expected, values)
+
+	tests := []struct {
+		values   map[string]interface{}
+		expected string
+	}{
+		{
+			map[string]interface{}{},
+			`query error: variable "$first" of required type Int! was not provided`,
+		},
+		{
+			map[string]interface{}{"first": nil},
+			`query error: variable "$first" got invalid value null at "first"; expected non-nullable type Int! not to be null`,
+		},
+		{
+			map[string]interface{}{"first": 1.5},
+			`query error: variable "$first" got invalid value 1.5 at "first"; Int cannot represent non-integer value 1.5`,
+		},
+		{
+			map[string]interface{}{"first": 1, "ratio": "1"},
+			`query error: variable "$ratio" got invalid value "1" at "ratio"; Float cannot represent non numeric value 1`,
+		},
+		{
+			map[string]interface{}{"first": 1, "filter": map[string]interface{}{"color": "GREEN"}},
+			`query error: variable "$filter" got invalid value "GREEN" at "filter.color"; value does not exist in Color enum`,
+		},
+		{
+			map[string]interface{}{"first": 1, "filter": map[string]interface{}{"color": "RED", "ids": []interface{}{"a", nil}}},
+			`query error: variable "$filter" got invalid value null at "filter.ids[1]"; expected non-nullable type ID! not to be null`,
+		},
+		{
+			map[string]interface{}{"first": 1, "filter": map[string]interface{}{"color": "RED", "size": 1}},
+			`query error: variable "$filter" got invalid value {"color":"RED","size":1} at "filter"; field size is not defined by type Filter`,
+		},
+		{
+			map[string]interface{}{"first": 1, "filter": map[string]interface{}{}},
+			`query error: variable "$filter" got invalid value {} at "filter"; field color of required type Color! was not provided`,
+		},
+		{
+			map[string]interface{}{"first": 1, "filter": "RED"},
+			`query error: variable "$filter" got invalid value "RED" at "filter"; expected type Filter to be an object`,
+		},
+	}
+	for _, test := range tests {
+		_, err := runtime.coerceVariableValues(oper, test.values)
+		if err == nil {
+			t.Errorf("expecting error %s", test.expected)
+			continue
+		}
+		assertEqual(t, test.expected, err.Error())
+	}
+}
+
+func TestCoerceArgumentValues(t *testing.T) {
+	var args map[string]interface{}
+	filter := &InputObject{
+		Name: "Filter",
+		Fields: []*Field{
+			{Name: "name", Typ: &NonNull{OfType: String}},
+			{Name: "limit", Typ: Int, Defl: 10},
+			{Name: "ratio", Typ: Float},
+		},
+	}
+	query := &Object{
+		Name: "Query",
+		Fields: []*Field{
+			{
+				Name: "search",
+				Typ:  String,
+				Defs: []*ArgDef{
+					{Name: "filter", Typ: filter},
+					{Name: "ids", Typ: &List{OfType: ID}},
+					{Name: "first", Typ: &NonNull{OfType: Int}, Defl: 5},
+					{Name: "after", Typ: String},
+					{Name: "flag", Typ: Boolean},
+				},
+				Resolve: func(ctx context.Context, source interface{}, arguments map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+					args = arguments
+					return "ok", nil
+				},
+			},
+		},
+	}
+	runtime := newRuntime(&Schema{Qry: query})
+
+	rsp := execute(t, runtime, `query Q($name: String!, $ratio: Float, $after: String) {
+	search(filter: {name: $name, ratio: $ratio, limit: 3}, ids: 1, after: $after, flag: null)
+}`, "Q", map[string]interface{}{"name": "foo"})
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+	expected := map[string]interface{}{
+		"filter": map[string]interface{}{"name": "foo", "limit": 3},
+		"ids":    []interface{}{"1"},
+		"first":  5,
+		"flag":   nil,
+	}
+	assertEqual(t, expected, args)
+
+	rsp = execute(t, runtime, `query Q($first: Int) { search(first: $first, filter: {name: "a", ratio: 1}) }`, "Q", map[string]interface{}{"first": nil})
+	if len(rsp.Errors) != 1 {
+		t.Fatalf("expecting 1 error, found %v", rsp.Errors)
+	}
+	assertEqual(t, "1:24: query error: argument first of non-null type Int! must not be null", rsp.Errors[0].Error())
+	assertEqual(t, map[string]interface{}{"search": nil}, rsp.Data)
+
+	rsp = execute(t, runtime, `query Q($first: Int = 2) { search(first: $first, filter: {name: "a", ratio: 1}) }`, "Q", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+	assertEqual(t, 2, args["first"])
+	assertEqual(t, map[string]interface{}{"name": "a", "limit": 10, "ratio": 1.0}, args["filter"])
+}
@@ -0,0 +1,333 @@
+// Package handler serves GraphQL over HTTP for a ql.Runtime.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/leesper/pureql/ql"
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// media types of GraphQL over HTTP.
+const (
+	mediaTypeJSON            = "application/json"
+	mediaTypeGraphQL         = "application/graphql"
+	mediaTypeGraphQLResponse = "application/graphql-response+json"
+)
+
+// Handler is an http.Handler executing GraphQL requests against Runtime. It
+// accepts GET requests with the request in URL query parameters, and POST
+// requests with a body of application/json, or application/graphql in which
+// case the body is the query and the other parameters are taken from the URL.
+//
+// The response is application/graphql-response+json if the client accepts it,
+// in which case requests failing to parse or validate get status 400. With
+// application/json, every well-formed request gets status 200.
+//
+// MaxBodySize limits the size of request bodies, requests with a larger body
+// get status 413. DefaultMaxBodySize is used if it is 0.
+type Handler struct {
+	Runtime     *ql.Runtime
+	MaxBodySize int64
+}
+
+// DefaultMaxBodySize is the limit of request bodies used by handlers without
+// one.
+const DefaultMaxBodySize = 1 << 20
+
+// New returns a Handler serving runtime.
+func New(runtime *ql.Runtime) *Handler {
+	return &Handler{Runtime: runtime}
+}
+
+// Request is the parameters of a GraphQL request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+// errBadRequest for requests which are not well-formed.
+type errBadRequest struct {
+	status int
+	msg    string
+}
+
+func (e errBadRequest) Error() string {
+	return e.msg
+}
+
+func badRequest(status int, format string, args ...interface{}) error {
+	return errBadRequest{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mediaType, ok := negotiate(r.Header.Get("Accept"))
+	if !ok {
+		http.Error(w, "accepting neither "+mediaTypeGraphQLResponse+" nor "+mediaTypeJSON, http.StatusNotAcceptable)
+		return
+	}
+
+	req, err := readRequest(w, r, h.MaxBodySize)
+	if err != nil {
+		var bad errBadRequest
+		if errors.As(err, &bad) && bad.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", "GET, POST")
+		}
+		writeRequestError(w, mediaType, err)
+		return
+	}
+
+	fset := token.NewFileSet()
+	doc, err := ast.ParseDocument([]byte(req.Query), "", fset)
+	if err != nil {
+		writeResponse(w, mediaType, requestErrorStatus(mediaType), &ql.Response{Errors: syntaxErrors(err)}, false)
+		return
+	}
+
+	if r.Method == http.MethodGet && isMutation(doc, req.OperationName) {
+		w.Header().Set("Allow", "POST")
+		writeRequestError(w, mediaType, badRequest(http.StatusMethodNotAllowed, "mutations can only be executed with POST"))
+		return
+	}
+
+	rsp := h.Runtime.ExecuteContext(r.Context(), fset, doc, req.OperationName, req.Variables)
+	if !rsp.Executed {
+		writeResponse(w, mediaType, requestErrorStatus(mediaType), rsp, false)
+		return
+	}
+	writeResponse(w, mediaType, http.StatusOK, rsp, true)
+}
+
+// negotiate returns the media type of response for the Accept header accept.
+// application/json is used if there is no Accept header, for legacy clients.
+func negotiate(accept string) (string, bool) {
+	if accept == "" {
+		return mediaTypeJSON, true
+	}
+
+	acceptJSON := false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mediaType {
+		case mediaTypeGraphQLResponse:
+			return mediaTypeGraphQLResponse, true
+		case mediaTypeJSON, "application/*", "*/*":
+			acceptJSON = true
+		}
+	}
+	return mediaTypeJSON, acceptJSON
+}
+
+// readRequest reads the request of r, the body of which is limited to maxBody
+// bytes, or DefaultMaxBodySize if maxBody is 0.
+func readRequest(w http.ResponseWriter, r *http.Request, maxBody int64) (*Request, error) {
+	var req *Request
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		req, err = readParams(r)
+	case http.MethodPost:
+		req, err = readBody(w, r, maxBody)
+	default:
+		return nil, badRequest(http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if req.Query == "" {
+		return nil, badRequest(http.StatusBadRequest, "missing query")
+	}
+	return req, nil
+}
+
+func readBody(w http.ResponseWriter, r *http.Request, maxBody int64) (*Request, error) {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, badRequest(http.StatusUnsupportedMediaType, "invalid content type %q", contentType)
+	}
+
+	if maxBody == 0 {
+		maxBody = DefaultMaxBodySize
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, badRequest(http.StatusRequestEntityTooLarge, "request body larger than %d bytes", maxBody)
+		}
+		return nil, badRequest(http.StatusBadRequest, "reading body: %v", err)
+	}
+
+	switch mediaType {
+	case mediaTypeJSON:
+		req := &Request{}
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, badRequest(http.StatusBadRequest, "invalid JSON body: %v", err)
+		}
+		return req, nil
+	case mediaTypeGraphQL:
+		req, err := readParams(r)
+		if err != nil {
+			return nil, err
+		}
+		req.Query = string(body)
+		return req, nil
+	default:
+		return nil, badRequest(http.StatusUnsupportedMediaType, "unsupported content type %q", mediaType)
+	}
+}
+
+// readParams reads request from URL query parameters.
+func readParams(r *http.Request) (*Request, error) {
+	params := r.URL.Query()
+	req := &Request{
+		Query:         params.Get("query"),
+		OperationName: params.Get("operationName"),
+	}
+	if v := params.Get("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+			return nil, badRequest(http.StatusBadRequest, "invalid variables: %v", err)
+		}
+	}
+	if v := params.Get("extensions"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Extensions); err != nil {
+			return nil, badRequest(http.StatusBadRequest, "invalid extensions: %v", err)
+		}
+	}
+	return req, nil
+}
+
+// syntaxErrors returns the errors of parsing a query, one *ql.Error with the
+// code GRAPHQL_PARSE_FAILED for each syntax error.
+func syntaxErrors(err error) []error {
+	list, ok := err.(ast.ErrorList)
+	if !ok {
+		return []error{err}
+	}
+	errs := make([]error, len(list))
+	for i, e := range list {
+		errs[i] = &ql.Error{
+			Message:    fmt.Sprintf("expecting %s, found '%s'", e.Expected, e.Found),
+			Locations:  []ql.Location{{Line: e.Pos.Line, Column: e.Pos.Column}},
+			Extensions: map[string]interface{}{"code": "GRAPHQL_PARSE_FAILED"},
+			Err:        e,
+		}
+	}
+	return errs
+}
+
+// isMutation reports if the operation to execute in doc is a mutation.
+func isMutation(doc *ast.Document, operationName string) bool {
+	for _, def := range doc.Defs {
+		oper, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || oper.Name.Text == operationName {
+			if oper.OperType.Text == ast.Stringify(ast.MUTATION) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSubscription reports if the operation to execute in doc is a subscription.
+func isSubscription(doc *ast.Document, operationName string) bool {
+	for _, def := range doc.Defs {
+		oper, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || oper.Name.Text == operationName {
+			return oper.OperType.Text == ast.Stringify(ast.SUBSCRIPTION)
+		}
+	}
+	return false
+}
+
+// executeStream executes req for the streaming transports. Subscriptions
+// deliver a Response for each event, queries and mutations a single one. Errors
+// are returned instead if req fails to validate or the operation cannot start.
+func executeStream(ctx context.Context, runtime *ql.Runtime, fset *token.FileSet, doc *ast.Document, req *Request) (<-chan *ql.Response, []error) {
+	if isSubscription(doc, req.OperationName) {
+		return runtime.Subscribe(ctx, fset, doc, req.OperationName, req.Variables)
+	}
+
+	rsp := runtime.ExecuteContext(ctx, fset, doc, req.OperationName, req.Variables)
+	if !rsp.Executed {
+		return nil, rsp.Errors
+	}
+	responses := make(chan *ql.Response, 1)
+	responses <- rsp
+	close(responses)
+	return responses, nil
+}
+
+// requestErrorStatus returns the status of a well-formed request which fails
+// to parse or validate.
+func requestErrorStatus(mediaType string) int {
+	if mediaType == mediaTypeGraphQLResponse {
+		return http.StatusBadRequest
+	}
+	return http.StatusOK
+}
+
+func writeRequestError(w http.ResponseWriter, mediaType string, err error) {
+	status := http.StatusBadRequest
+	var bad errBadRequest
+	if errors.As(err, &bad) {
+		status = bad.status
+	}
+	writeResponse(w, mediaType, status, &ql.Response{Errors: []error{err}}, false)
+}
+
+// writeResponse writes rsp with status, data is only included if the request
+// was executed.
+func writeResponse(w http.ResponseWriter, mediaType string, status int, rsp *ql.Response, executed bool) {
+	body := formatResponse(rsp)
+	if !executed {
+		delete(body, "data")
+	}
+
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// formatResponse returns rsp in the JSON shape of GraphQL responses.
+func formatResponse(rsp *ql.Response) map[string]interface{} {
+	body := map[string]interface{}{"data": rsp.Data}
+	if len(rsp.Errors) > 0 {
+		errs := make([]interface{}, len(rsp.Errors))
+		for i, err := range rsp.Errors {
+			errs[i] = formatError(err)
+		}
+		body["errors"] = errs
+	}
+	return body
+}
+
+// formatError returns err in the JSON shape of GraphQL errors.
+func formatError(err error) interface{} {
+	if m, ok := err.(json.Marshaler); ok {
+		return m
+	}
+	return map[string]interface{}{"message": err.Error()}
+}
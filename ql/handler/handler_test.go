@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/leesper/pureql/ql"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	query := &ql.Object{
+		Name: "Query",
+		Fields: []*ql.Field{
+			{
+				Name: "hello",
+				Typ:  ql.String,
+				Defs: []*ql.ArgDef{{Name: "name", Typ: ql.String, Defl: "world"}},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ql.ResolveInfo) (interface{}, error) {
+					return "hello " + args["name"].(string), nil
+				},
+			},
+			{
+				Name: "secret",
+				Typ:  &ql.NonNull{OfType: ql.String},
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ql.ResolveInfo) (interface{}, error) {
+					return nil, errors.New("forbidden")
+				},
+			},
+		},
+	}
+	mutation := &ql.Object{
+		Name: "Mutation",
+		Fields: []*ql.Field{
+			{
+				Name: "like",
+				Typ:  ql.Int,
+				Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ql.ResolveInfo) (interface{}, error) {
+					return 1, nil
+				},
+			},
+		},
+	}
+	runtime, err := ql.NewRuntime(&ql.Schema{Qry: query, Mut: mutation})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", New(runtime))
+	return httptest.NewServer(mux)
+}
+
+type result struct {
+	status      int
+	contentType string
+	body        map[string]interface{}
+	allow       string
+}
+
+func do(t *testing.T, req *http.Request) result {
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	res := result{
+		status:      rsp.StatusCode,
+		contentType: rsp.Header.Get("Content-Type"),
+		allow:       rsp.Header.Get("Allow"),
+	}
+	if strings.Contains(res.contentType, "json") {
+		if err := json.NewDecoder(rsp.Body).Decode(&res.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res
+}
+
+func newRequest(t *testing.T, method, target, contentType, body string) *http.Request {
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func TestServeGet(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	params := url.Values{}
+	params.Set("query", `query A { a: hello } query B($n: String) { b: hello(name: $n) }`)
+	params.Set("operationName", "B")
+	params.Set("variables", `{"n": "pureql"}`)
+	res := do(t, newRequest(t, http.MethodGet, srv.URL+"/graphql?"+params.Encode(), "", ""))
+
+	if res.status != http.StatusOK {
+		t.Errorf("expecting status 200, found %d", res.status)
+	}
+	if res.contentType != "application/json; charset=utf-8" {
+		t.Errorf("unexpected content type %s", res.contentType)
+	}
+	expected := map[string]interface{}{"data": map[string]interface{}{"b": "hello pureql"}}
+	if !equalJSON(expected, res.body) {
+		t.Errorf("expected %v, found %v", expected, res.body)
+	}
+
+	params = url.Values{}
+	params.Set("query", `mutation { like }`)
+	res = do(t, newRequest(t, http.MethodGet, srv.URL+"/graphql?"+params.Encode(), "", ""))
+	if res.status != http.StatusMethodNotAllowed || res.allow != "POST" {
+		t.Errorf("expecting status 405 allowing POST, found %d allowing %q", res.status, res.allow)
+	}
+}
+
+func TestServePost(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	res := do(t, newRequest(t, http.MethodPost, srv.URL+"/graphql", "application/json",
+		`{"query": "mutation M { like }", "operationName": "M"}`))
+	expected := map[string]interface{}{"data": map[string]interface{}{"like": 1.0}}
+	if res.status != http.StatusOK || !equalJSON(expected, res.body) {
+		t.Errorf("expected 200 %v, found %d %v", expected, res.status, res.body)
+	}
+
+	res = do(t, newRequest(t, http.MethodPost, srv.URL+"/graphql?variables=%7B%22n%22%3A%22x%22%7D",
+		"application/graphql", `query($n: String) { hello(name: $n) }`))
+	expected = map[string]interface{}{"data": map[string]interface{}{"hello": "hello x"}}
+	if res.status != http.StatusOK || !equalJSON(expected, res.body) {
+		t.Errorf("expected 200 %v, found %d %v", expected, res.status, res.body)
+	}
+
+	res = do(t, newRequest(t, http.MethodPost, srv.URL+"/graphql", "text/plain", `{ hello }`))
+	if res.status != http.StatusUnsupportedMediaType {
+		t.Errorf("expecting status 415, found %d", res.status)
+	}
+
+	res = do(t, newRequest(t, http.MethodPost, srv.URL+"/graphql", "application/json", `{"query": `))
+	if res.status != http.StatusBadRequest {
+		t.Errorf("expecting status 400, found %d", res.status)
+	}
+
+	res = do(t, newRequest(t, http.MethodPost, srv.URL+"/graphql", "application/graphql", "{ hello( }\n{ hello: }"))
+	expected = map[string]interface{}{"errors": []interface{}{
+		map[string]interface{}{
+			"message":    "expecting NAME, found '}'",
+			"locations":  []interface{}{map[string]interface{}{"line": 1, "column": 10}},
+			"extensions": map[string]interface{}{"code": "GRAPHQL_PARSE_FAILED"},
+		},
+		map[string]interface{}{
+			"message":    "expecting NAME, found '}'",
+			"locations":  []interface{}{map[string]interface{}{"line": 2, "column": 10}},
+			"extensions": map[string]interface{}{"code": "GRAPHQL_PARSE_FAILED"},
+		},
+	}}
+	if res.status != http.StatusOK || !equalJSON(expected, res.body) {
+		t.Errorf("expected 200 %v, found %d %v", expected, res.status, res.body)
+	}
+
+	res = do(t, newRequest(t, http.MethodPost, srv.URL+"/graphql", "application/graphql",
+		"{ hello }"+strings.Repeat(" ", DefaultMaxBodySize)))
+	if res.status != http.StatusRequestEntityTooLarge {
+		t.Errorf("expecting status 413, found %d", res.status)
+	}
+
+	res = do(t, newRequest(t, http.MethodPut, srv.URL+"/graphql", "application/json", `{"query": "{ hello }"}`))
+	if res.status != http.StatusMethodNotAllowed || res.allow != "GET, POST" {
+		t.Errorf("expecting status 405 allowing GET, POST, found %d allowing %q", res.status, res.allow)
+	}
+}
+
+func TestServeMediaTypes(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		accept      string
+		query       string
+		status      int
+		contentType string
+	}{
+		{"", `{ hello }`, http.StatusOK, "application/json; charset=utf-8"},
+		{"application/json", `{ hello(`, http.StatusOK, "application/json; charset=utf-8"},
+		{"application/json", `{ goodbye }`, http.StatusOK, "application/json; charset=utf-8"},
+		{"application/graphql-response+json, application/json;q=0.9", `{ hello }`, http.StatusOK, "application/graphql-response+json; charset=utf-8"},
+		{"application/graphql-response+json", `{ hello(`, http.StatusBadRequest, "application/graphql-response+json; charset=utf-8"},
+		{"application/graphql-response+json", `{ goodbye }`, http.StatusBadRequest, "application/graphql-response+json; charset=utf-8"},
+		{"text/html", `{ hello }`, http.StatusNotAcceptable, "text/plain; charset=utf-8"},
+	}
+
+	for _, test := range tests {
+		body, _ := json.Marshal(map[string]string{"query": test.query})
+		req := newRequest(t, http.MethodPost, srv.URL+"/graphql", "application/json", string(body))
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		res := do(t, req)
+		if res.status != test.status || res.contentType != test.contentType {
+			t.Errorf("%s %s: expected %d %s, found %d %s", test.accept, test.query,
+				test.status, test.contentType, res.status, res.contentType)
+		}
+		if res.status != http.StatusNotAcceptable && test.query != `{ hello }` {
+			if _, ok := res.body["data"]; ok {
+				t.Errorf("%s %s: expecting no data, found %v", test.accept, test.query, res.body)
+			}
+			if _, ok := res.body["errors"]; !ok {
+				t.Errorf("%s %s: expecting errors, found %v", test.accept, test.query, res.body)
+			}
+		}
+	}
+}
+
+func TestServeNullData(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	expected := map[string]interface{}{
+		"data": nil,
+		"errors": []interface{}{
+			map[string]interface{}{
+				"message":   "forbidden",
+				"locations": []interface{}{map[string]interface{}{"line": 1, "column": 3}},
+				"path":      []interface{}{"secret"},
+			},
+		},
+	}
+	for _, accept := range []string{mediaTypeJSON, mediaTypeGraphQLResponse} {
+		req := newRequest(t, http.MethodPost, srv.URL+"/graphql", "application/json", `{"query": "{ secret }"}`)
+		req.Header.Set("Accept", accept)
+		res := do(t, req)
+		if res.status != http.StatusOK || !equalJSON(expected, res.body) {
+			t.Errorf("%s: expected 200 %v, found %d %v", accept, expected, res.status, res.body)
+		}
+	}
+}
+
+func equalJSON(expected, found interface{}) bool {
+	a, _ := json.Marshal(expected)
+	b, _ := json.Marshal(found)
+	return string(a) == string(b)
+}
@@ -0,0 +1,436 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go/token"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leesper/pureql/ql"
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// mediaTypeEventStream is the media type of Server-Sent Events.
+const mediaTypeEventStream = "text/event-stream"
+
+// event types of GraphQL over SSE.
+const (
+	eventNext     = "next"
+	eventComplete = "complete"
+)
+
+// streamTokenHeader carries the token of a reserved event stream, it can also
+// be passed as the token URL query parameter.
+const streamTokenHeader = "X-GraphQL-Event-Stream-Token"
+
+// defaults of the limits of SSEHandler.
+const (
+	defaultReserveTimeout  = 30 * time.Second
+	defaultMaxStreams      = 1000
+	defaultMaxQueuedEvents = 100
+)
+
+// SSEHandler is an http.Handler executing GraphQL operations over Server-Sent
+// Events with the GraphQL over SSE protocol, for clients which cannot use
+// WebSocket. A next event is sent for each result and a complete event after
+// the last one.
+//
+// In the distinct connections mode every request accepting text/event-stream
+// gets an event stream of its own operation.
+//
+// In the single connection mode the client reserves a stream with PUT, which
+// responds with a token. The stream is then opened by a request accepting
+// text/event-stream with the token, and operations are sent with the token as
+// POST requests having an operationId extension, their events are delivered on
+// the stream with the operation id. DELETE with the token and the operationId
+// URL query parameter stops an operation. Closing the stream stops all of its
+// operations.
+//
+// MaxBodySize limits the size of request bodies as in Handler.
+type SSEHandler struct {
+	Runtime     *ql.Runtime
+	MaxBodySize int64
+	// ReserveTimeout is how long a reserved stream waits to be opened, it is
+	// released and its operations are stopped afterwards. 30 seconds if zero.
+	ReserveTimeout time.Duration
+	// MaxStreams limits the number of reserved streams, reserving more gets
+	// status 503. 1000 if zero.
+	MaxStreams int
+	// MaxQueuedEvents limits the events queued on a stream for the client,
+	// operations wait while the queue is full. 100 if zero.
+	MaxQueuedEvents int
+
+	mu      sync.Mutex // guards streams
+	streams map[string]*sseStream
+}
+
+// NewSSE returns an SSEHandler serving runtime.
+func NewSSE(runtime *ql.Runtime) *SSEHandler {
+	return &SSEHandler{Runtime: runtime}
+}
+
+// sseStream is a reserved stream of the single connection mode, events are
+// queued until the stream is open.
+type sseStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	events chan []byte
+
+	mu   sync.Mutex // guards fields below
+	open bool
+	ops  map[string]context.CancelFunc
+}
+
+// push queues event, waiting while the queue is full until the stream is
+// released.
+func (s *sseStream) push(event []byte) {
+	select {
+	case s.events <- event:
+	case <-s.ctx.Done():
+	}
+}
+
+// claim marks the stream as open, it reports false if it already was.
+func (s *sseStream) claim() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.open {
+		return false
+	}
+	s.open = true
+	return true
+}
+
+// ServeHTTP implements http.Handler.
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tok := r.Header.Get(streamTokenHeader)
+	if tok == "" {
+		tok = r.URL.Query().Get("token")
+	}
+
+	switch {
+	case r.Method == http.MethodPut:
+		h.reserve(w)
+	case acceptsEventStream(r.Header.Get("Accept")):
+		if tok != "" {
+			h.serveStream(w, r, tok)
+		} else {
+			h.serveDistinct(w, r)
+		}
+	case tok == "":
+		writeRequestError(w, mediaTypeJSON, badRequest(http.StatusBadRequest, "missing stream token"))
+	case r.Method == http.MethodDelete:
+		h.stop(w, r, tok)
+	default:
+		h.serveOperation(w, r, tok)
+	}
+}
+
+// acceptsEventStream reports if the Accept header accept includes
+// text/event-stream.
+func acceptsEventStream(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == mediaTypeEventStream && params["q"] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// reserve reserves a stream for the single connection mode, which is released
+// if it is not opened in time.
+func (h *SSEHandler) reserve(w http.ResponseWriter) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		http.Error(w, "generating stream token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tok := hex.EncodeToString(b[:])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &sseStream{
+		ctx:    ctx,
+		cancel: cancel,
+		events: make(chan []byte, h.maxQueuedEvents()),
+		ops:    map[string]context.CancelFunc{},
+	}
+	h.mu.Lock()
+	if len(h.streams) >= h.maxStreams() {
+		h.mu.Unlock()
+		cancel()
+		http.Error(w, "too many streams", http.StatusServiceUnavailable)
+		return
+	}
+	if h.streams == nil {
+		h.streams = map[string]*sseStream{}
+	}
+	h.streams[tok] = s
+	h.mu.Unlock()
+
+	time.AfterFunc(h.reserveTimeout(), func() {
+		if s.claim() {
+			h.release(tok, s)
+		}
+	})
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(tok))
+}
+
+func (h *SSEHandler) reserveTimeout() time.Duration {
+	if h.ReserveTimeout > 0 {
+		return h.ReserveTimeout
+	}
+	return defaultReserveTimeout
+}
+
+func (h *SSEHandler) maxStreams() int {
+	if h.MaxStreams > 0 {
+		return h.MaxStreams
+	}
+	return defaultMaxStreams
+}
+
+func (h *SSEHandler) maxQueuedEvents() int {
+	if h.MaxQueuedEvents > 0 {
+		return h.MaxQueuedEvents
+	}
+	return defaultMaxQueuedEvents
+}
+
+func (h *SSEHandler) stream(tok string) *sseStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.streams[tok]
+}
+
+// release removes the stream s reserved as tok and stops its operations.
+func (h *SSEHandler) release(tok string, s *sseStream) {
+	h.mu.Lock()
+	if h.streams[tok] == s {
+		delete(h.streams, tok)
+	}
+	h.mu.Unlock()
+	s.cancel()
+}
+
+// serveStream serves the reserved stream tok until the client goes away, which
+// releases the stream and stops its operations.
+func (h *SSEHandler) serveStream(w http.ResponseWriter, r *http.Request, tok string) {
+	s := h.stream(tok)
+	if s == nil {
+		http.Error(w, "stream not found", http.StatusNotFound)
+		return
+	}
+	if !s.claim() {
+		http.Error(w, "stream already open", http.StatusConflict)
+		return
+	}
+	defer h.release(tok, s)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	writeEventStreamHeader(w)
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-s.events:
+			if _, err := w.Write(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// serveOperation starts the operation of r on the reserved stream tok.
+func (h *SSEHandler) serveOperation(w http.ResponseWriter, r *http.Request, tok string) {
+	req, err := readRequest(w, r, h.MaxBodySize)
+	if err != nil {
+		var bad errBadRequest
+		if errors.As(err, &bad) && bad.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+		}
+		writeRequestError(w, mediaTypeJSON, err)
+		return
+	}
+	id, _ := req.Extensions["operationId"].(string)
+	if id == "" {
+		writeRequestError(w, mediaTypeJSON, badRequest(http.StatusBadRequest, "missing operationId extension"))
+		return
+	}
+
+	s := h.stream(tok)
+	if s == nil {
+		writeRequestError(w, mediaTypeJSON, badRequest(http.StatusNotFound, "stream not found"))
+		return
+	}
+
+	fset, doc, ok := parseRequest(w, r, req)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		writeRequestError(w, mediaTypeJSON, badRequest(http.StatusNotFound, "stream not found"))
+		return
+	}
+	if _, ok := s.ops[id]; ok {
+		s.mu.Unlock()
+		writeRequestError(w, mediaTypeJSON, badRequest(http.StatusConflict, "operation %s already exists", id))
+		return
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.ops[id] = cancel
+	s.mu.Unlock()
+
+	responses, errs := executeStream(ctx, h.Runtime, fset, doc, req)
+	if len(errs) > 0 {
+		s.done(id, cancel)
+		writeResponse(w, mediaTypeJSON, http.StatusBadRequest, &ql.Response{Errors: errs}, false)
+		return
+	}
+
+	go func() {
+		defer func() {
+			if s.done(id, cancel) {
+				s.push(formatEvent(eventComplete, map[string]interface{}{"id": id}))
+			}
+		}()
+		for rsp := range responses {
+			s.push(formatEvent(eventNext, map[string]interface{}{"id": id, "payload": formatResponse(rsp)}))
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// done removes operation id, it reports if the operation was not stopped by
+// the client or by closing the stream.
+func (s *sseStream) done(id string, cancel context.CancelFunc) bool {
+	s.mu.Lock()
+	_, ok := s.ops[id]
+	delete(s.ops, id)
+	s.mu.Unlock()
+	cancel()
+	return ok && s.ctx.Err() == nil
+}
+
+// stop stops the operation given by the operationId URL query parameter.
+func (h *SSEHandler) stop(w http.ResponseWriter, r *http.Request, tok string) {
+	id := r.URL.Query().Get("operationId")
+	if id == "" {
+		writeRequestError(w, mediaTypeJSON, badRequest(http.StatusBadRequest, "missing operationId"))
+		return
+	}
+	s := h.stream(tok)
+	if s == nil {
+		writeRequestError(w, mediaTypeJSON, badRequest(http.StatusNotFound, "stream not found"))
+		return
+	}
+
+	s.mu.Lock()
+	if cancel, ok := s.ops[id]; ok {
+		cancel()
+		delete(s.ops, id)
+	}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveDistinct serves the operation of r on an event stream of its own.
+func (h *SSEHandler) serveDistinct(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(w, r, h.MaxBodySize)
+	if err != nil {
+		var bad errBadRequest
+		if errors.As(err, &bad) && bad.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", "GET, POST")
+		}
+		writeRequestError(w, mediaTypeJSON, err)
+		return
+	}
+	fset, doc, ok := parseRequest(w, r, req)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	responses, errs := executeStream(r.Context(), h.Runtime, fset, doc, req)
+	if len(errs) > 0 {
+		writeResponse(w, mediaTypeJSON, http.StatusBadRequest, &ql.Response{Errors: errs}, false)
+		return
+	}
+
+	writeEventStreamHeader(w)
+	flusher.Flush()
+	for rsp := range responses {
+		if _, err := w.Write(formatEvent(eventNext, formatResponse(rsp))); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+	if r.Context().Err() == nil {
+		w.Write(formatEvent(eventComplete, nil))
+		flusher.Flush()
+	}
+}
+
+// parseRequest parses the query of req, the error response is written if it
+// fails, or if a mutation is requested with GET.
+func parseRequest(w http.ResponseWriter, r *http.Request, req *Request) (*token.FileSet, *ast.Document, bool) {
+	fset := token.NewFileSet()
+	doc, err := ast.ParseDocument([]byte(req.Query), "", fset)
+	if err != nil {
+		writeResponse(w, mediaTypeJSON, http.StatusBadRequest, &ql.Response{Errors: syntaxErrors(err)}, false)
+		return nil, nil, false
+	}
+	if r.Method == http.MethodGet && isMutation(doc, req.OperationName) {
+		w.Header().Set("Allow", "POST")
+		writeRequestError(w, mediaTypeJSON, badRequest(http.StatusMethodNotAllowed, "mutations can only be executed with POST"))
+		return nil, nil, false
+	}
+	return fset, doc, true
+}
+
+func writeEventStreamHeader(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", mediaTypeEventStream+"; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+}
+
+// formatEvent returns an event of type event with data encoded as JSON, data
+// is empty if nil.
+func formatEvent(event string, data interface{}) []byte {
+	var b bytes.Buffer
+	b.WriteString("event: " + event + "\ndata:")
+	if data != nil {
+		encoded, _ := json.Marshal(data)
+		b.WriteByte(' ')
+		b.Write(encoded)
+	}
+	b.WriteString("\n\n")
+	return b.Bytes()
+}
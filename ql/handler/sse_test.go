@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leesper/pureql/ql"
+)
+
+// eventReader reads events of a text/event-stream body.
+type eventReader struct {
+	t  *testing.T
+	br *bufio.Reader
+}
+
+func openEvents(t *testing.T, req *http.Request) (*eventReader, io.Closer) {
+	req.Header.Set("Accept", mediaTypeEventStream)
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.StatusCode != http.StatusOK || !strings.HasPrefix(rsp.Header.Get("Content-Type"), mediaTypeEventStream) {
+		rsp.Body.Close()
+		t.Fatalf("expecting event stream, found %d %s", rsp.StatusCode, rsp.Header.Get("Content-Type"))
+	}
+	return &eventReader{t: t, br: bufio.NewReader(rsp.Body)}, rsp.Body
+}
+
+func (r *eventReader) expect(event, data string) {
+	var foundEvent, foundData string
+	for {
+		line, err := r.br.ReadString('\n')
+		if err != nil {
+			r.t.Fatalf("expecting event %s, found %v", event, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "event:") {
+			foundEvent = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		} else if strings.HasPrefix(line, "data:") {
+			foundData = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+
+	if foundEvent != event {
+		r.t.Fatalf("expecting event %s, found %s", event, foundEvent)
+	}
+	if data == "" || foundData == "" {
+		if data != foundData {
+			r.t.Fatalf("expecting data %q, found %q", data, foundData)
+		}
+		return
+	}
+	var exp, found map[string]interface{}
+	json.Unmarshal([]byte(data), &exp)
+	if err := json.Unmarshal([]byte(foundData), &found); err != nil {
+		r.t.Fatal(err)
+	}
+	if !equalJSON(exp, found) {
+		r.t.Fatalf("expecting data %s, found %s", data, foundData)
+	}
+}
+
+func TestSSEDistinctConnections(t *testing.T) {
+	srv := httptest.NewServer(NewSSE(newSubscriptionRuntime(t)))
+	defer srv.Close()
+
+	events, body := openEvents(t, newRequest(t, http.MethodPost, srv.URL, mediaTypeJSON,
+		`{"query": "subscription($to: Int) { count(to: $to) }", "variables": {"to": 2}}`))
+	events.expect(eventNext, `{"data": {"count": 1}}`)
+	events.expect(eventNext, `{"data": {"count": 2}}`)
+	events.expect(eventComplete, "")
+	body.Close()
+
+	events, body = openEvents(t, newRequest(t, http.MethodGet, srv.URL+"?query="+url.QueryEscape("{ user }"), "", ""))
+	events.expect(eventNext, `{"data": {"user": "anonymous"}}`)
+	events.expect(eventComplete, "")
+	body.Close()
+
+	events, body = openEvents(t, newRequest(t, http.MethodGet, srv.URL+"?query="+url.QueryEscape("{ secret }"), "", ""))
+	events.expect(eventNext, `{"data": null, "errors": [{"message": "forbidden", "locations": [{"line": 1, "column": 3}], "path": ["secret"]}]}`)
+	events.expect(eventComplete, "")
+	body.Close()
+
+	req := newRequest(t, http.MethodPost, srv.URL, mediaTypeJSON, `{"query": "subscription { count(to: \"a\") }"}`)
+	req.Header.Set("Accept", mediaTypeEventStream)
+	res := do(t, req)
+	expected := map[string]interface{}{"errors": []interface{}{
+		map[string]interface{}{
+			"message":    `expected value of type "Int", found "a"`,
+			"locations":  []interface{}{map[string]interface{}{"line": 1, "column": 26}},
+			"extensions": map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+		},
+	}}
+	if res.status != http.StatusBadRequest || !equalJSON(expected, res.body) {
+		t.Errorf("expected 400 %v, found %d %v", expected, res.status, res.body)
+	}
+}
+
+func TestSSESingleConnection(t *testing.T) {
+	srv := httptest.NewServer(NewSSE(newSubscriptionRuntime(t)))
+	defer srv.Close()
+
+	rsp, err := http.DefaultClient.Do(newRequest(t, http.MethodPut, srv.URL, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	tok := string(b)
+	if rsp.StatusCode != http.StatusCreated || tok == "" {
+		t.Fatalf("expecting status 201 with token, found %d %q", rsp.StatusCode, tok)
+	}
+
+	post := func(body string) result {
+		req := newRequest(t, http.MethodPost, srv.URL, mediaTypeJSON, body)
+		req.Header.Set(streamTokenHeader, tok)
+		return do(t, req)
+	}
+
+	// events are queued until the stream is open
+	if res := post(`{"query": "{ user }", "extensions": {"operationId": "1"}}`); res.status != http.StatusAccepted {
+		t.Fatalf("expecting status 202, found %d %v", res.status, res.body)
+	}
+
+	events, body := openEvents(t, newRequest(t, http.MethodGet, srv.URL+"?token="+tok, "", ""))
+	defer body.Close()
+	events.expect(eventNext, `{"id": "1", "payload": {"data": {"user": "anonymous"}}}`)
+	events.expect(eventComplete, `{"id": "1"}`)
+
+	req := newRequest(t, http.MethodGet, srv.URL+"?token="+tok, "", "")
+	req.Header.Set("Accept", mediaTypeEventStream)
+	if res := do(t, req); res.status != http.StatusConflict {
+		t.Errorf("expecting status 409 for open stream, found %d", res.status)
+	}
+
+	if res := post(`{"query": "subscription { count }", "extensions": {"operationId": "2"}}`); res.status != http.StatusAccepted {
+		t.Fatalf("expecting status 202, found %d %v", res.status, res.body)
+	}
+	events.expect(eventNext, `{"id": "2", "payload": {"data": {"count": 1}}}`)
+	if res := post(`{"query": "subscription { count }", "extensions": {"operationId": "2"}}`); res.status != http.StatusConflict {
+		t.Errorf("expecting status 409 for existing operation, found %d", res.status)
+	}
+
+	req = newRequest(t, http.MethodDelete, srv.URL+"?operationId=2", "", "")
+	req.Header.Set(streamTokenHeader, tok)
+	if res := do(t, req); res.status != http.StatusOK {
+		t.Errorf("expecting status 200, found %d", res.status)
+	}
+
+	if res := post(`{"query": "{ user }"}`); res.status != http.StatusBadRequest {
+		t.Errorf("expecting status 400 without operationId, found %d", res.status)
+	}
+	if res := post(`{"query": "{ nobody }", "extensions": {"operationId": "3"}}`); res.status != http.StatusBadRequest {
+		t.Errorf("expecting status 400 for invalid query, found %d", res.status)
+	}
+
+	// events of 2 sent before it stopped are skipped, then 4 is delivered
+	if res := post(`{"query": "subscription { count(to: 1) }", "extensions": {"operationId": "4"}}`); res.status != http.StatusAccepted {
+		t.Fatalf("expecting status 202, found %d %v", res.status, res.body)
+	}
+	for {
+		if line, err := events.br.ReadString('\n'); err != nil || strings.Contains(line, `"id":"4"`) {
+			break
+		}
+	}
+	events.br.ReadString('\n')
+	events.expect(eventComplete, `{"id": "4"}`)
+
+	req = newRequest(t, http.MethodGet, srv.URL+"?token=unknown", "", "")
+	req.Header.Set("Accept", mediaTypeEventStream)
+	if res := do(t, req); res.status != http.StatusNotFound {
+		t.Errorf("expecting status 404 for unknown token, found %d", res.status)
+	}
+}
+
+func TestSSEReservationLimits(t *testing.T) {
+	stopped := make(chan struct{})
+	query := &ql.Object{Name: "Query", Fields: []*ql.Field{{Name: "user", Typ: ql.String}}}
+	sub := &ql.Object{Name: "Subscription", Fields: []*ql.Field{{
+		Name: "count",
+		Typ:  ql.Int,
+		Subscribe: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ql.ResolveInfo) (<-chan interface{}, error) {
+			events := make(chan interface{})
+			go func() {
+				defer close(events)
+				for i := 1; ; i++ {
+					select {
+					case events <- i:
+					case <-ctx.Done():
+						close(stopped)
+						return
+					}
+				}
+			}()
+			return events, nil
+		},
+	}}}
+	runtime, err := ql.NewRuntime(&ql.Schema{Qry: query, Sub: sub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &SSEHandler{Runtime: runtime, ReserveTimeout: 100 * time.Millisecond, MaxStreams: 1, MaxQueuedEvents: 2}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	reserve := func() (int, string) {
+		rsp, err := http.DefaultClient.Do(newRequest(t, http.MethodPut, srv.URL, "", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rsp.Body.Close()
+		b, _ := io.ReadAll(rsp.Body)
+		return rsp.StatusCode, strings.TrimSpace(string(b))
+	}
+	status, tok := reserve()
+	if status != http.StatusCreated {
+		t.Fatalf("expecting status 201, found %d", status)
+	}
+	if status, _ := reserve(); status != http.StatusServiceUnavailable {
+		t.Errorf("expecting status 503 beyond MaxStreams, found %d", status)
+	}
+
+	req := newRequest(t, http.MethodPost, srv.URL, mediaTypeJSON, `{"query": "subscription { count }", "extensions": {"operationId": "1"}}`)
+	req.Header.Set(streamTokenHeader, tok)
+	if res := do(t, req); res.status != http.StatusAccepted {
+		t.Fatalf("expecting status 202, found %d %v", res.status, res.body)
+	}
+
+	// the operation waits on the full queue until the stream is released
+	s := h.stream(tok)
+	for len(s.events) < cap(s.events) {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expecting operation of stream never opened to stop")
+	}
+	if n := len(s.events); n != 2 {
+		t.Errorf("expecting 2 events queued, found %d", n)
+	}
+
+	req = newRequest(t, http.MethodGet, srv.URL+"?token="+tok, "", "")
+	req.Header.Set("Accept", mediaTypeEventStream)
+	if res := do(t, req); res.status != http.StatusNotFound {
+		t.Errorf("expecting status 404 for released stream, found %d", res.status)
+	}
+	status, tok = reserve()
+	if status != http.StatusCreated {
+		t.Fatalf("expecting status 201 after release, found %d", status)
+	}
+
+	// the stream is being released, it is cancelled but not yet removed
+	h.stream(tok).cancel()
+	req = newRequest(t, http.MethodPost, srv.URL, mediaTypeJSON, `{"query": "{ user }", "extensions": {"operationId": "1"}}`)
+	req.Header.Set(streamTokenHeader, tok)
+	if res := do(t, req); res.status != http.StatusNotFound {
+		t.Errorf("expecting status 404 for stream being released, found %d %v", res.status, res.body)
+	}
+}
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// WebSocket close codes.
+const (
+	closeNormal        = 1000
+	closeGoingAway     = 1001
+	closeProtocolError = 1002
+	closeMessageTooBig = 1009
+	closeInternalError = 1011
+)
+
+// websocketGUID is used to compute Sec-WebSocket-Accept.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize is the default limit of message size.
+const maxMessageSize = 1 << 20
+
+var errClosed = errors.New("websocket: connection closed")
+
+// wsConn is a server side WebSocket connection as defined in RFC 6455. Messages
+// are read by one goroutine, and can be written by many.
+type wsConn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	maxSize  int64
+	protocol string
+
+	mu     sync.Mutex // guards writes and closed
+	closed bool
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgrade performs the opening handshake, choosing protocol if the client
+// offers it. An error is replied and returned if r is not a valid WebSocket
+// handshake.
+func upgrade(w http.ResponseWriter, r *http.Request, protocol string) (*wsConn, error) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "websocket: method not allowed", http.StatusMethodNotAllowed)
+		return nil, errors.New("websocket: method not GET")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket: not a websocket handshake", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing upgrade headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "websocket: unsupported version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "websocket: missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket: hijacking not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response does not implement http.Hijacker")
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &wsConn{conn: conn, br: brw.Reader, maxSize: maxMessageSize}
+	if headerContains(r.Header, "Sec-WebSocket-Protocol", protocol) {
+		ws.protocol = protocol
+	}
+
+	h := sha1.New()
+	io.WriteString(h, key+websocketGUID)
+	accept := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	b.WriteString("Upgrade: websocket\r\n")
+	b.WriteString("Connection: Upgrade\r\n")
+	fmt.Fprintf(&b, "Sec-WebSocket-Accept: %s\r\n", accept)
+	if ws.protocol != "" {
+		fmt.Fprintf(&b, "Sec-WebSocket-Protocol: %s\r\n", ws.protocol)
+	}
+	b.WriteString("\r\n")
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// readMessage returns the next text or binary message. Ping frames are
+// answered, pong frames are ignored. errClosed is returned after a close frame
+// is received and answered.
+func (c *wsConn) readMessage() (int, []byte, error) {
+	var opcode int
+	var message []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case opPing:
+			c.writeFrame(opPong, payload)
+			continue
+		case opPong:
+			continue
+		case opClose:
+			// a close frame without status is answered without one, since
+			// 1005 must not be sent
+			code := 0
+			if len(payload) > 0 {
+				if len(payload) < 2 || !isValidCloseCode(int(binary.BigEndian.Uint16(payload))) {
+					c.close(closeProtocolError, "invalid close frame")
+					return 0, nil, errClosed
+				}
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.close(code, "")
+			return 0, nil, errClosed
+		case opText, opBinary:
+			if message != nil {
+				c.close(closeProtocolError, "expecting continuation frame")
+				return 0, nil, errClosed
+			}
+			opcode = op
+			message = payload
+		case opContinuation:
+			if message == nil {
+				c.close(closeProtocolError, "unexpected continuation frame")
+				return 0, nil, errClosed
+			}
+			message = append(message, payload...)
+		default:
+			c.close(closeProtocolError, "unknown opcode")
+			return 0, nil, errClosed
+		}
+
+		if int64(len(message)) > c.maxSize {
+			c.close(closeMessageTooBig, "message too big")
+			return 0, nil, errClosed
+		}
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		// no extension is negotiated, so RSV1-3 must be 0
+		c.close(closeProtocolError, "reserved bits set")
+		err = errClosed
+		return
+	}
+
+	length := int64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if !masked {
+		c.close(closeProtocolError, "client frames must be masked")
+		err = errClosed
+		return
+	}
+	if opcode&0x8 != 0 && (!fin || length > 125) {
+		c.close(closeProtocolError, "invalid control frame")
+		err = errClosed
+		return
+	}
+	if length < 0 || length > c.maxSize {
+		c.close(closeMessageTooBig, "message too big")
+		err = errClosed
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// writeFrame writes a single unmasked frame.
+func (c *wsConn) writeFrame(opcode int, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+func (c *wsConn) writeFrameLocked(opcode int, payload []byte) error {
+	header := []byte{0x80 | byte(opcode), 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeText writes data as a text message.
+func (c *wsConn) writeText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// close sends a close frame with code and reason, and closes the underlying
+// connection. The close frame has no payload if code is 0. It is safe to call
+// close more than once.
+func (c *wsConn) close(code int, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	var payload []byte
+	if code != 0 {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+	c.writeFrameLocked(opClose, payload)
+	return c.conn.Close()
+}
+
+// isValidCloseCode reports if code can be sent in a close frame, codes such as
+// 1005 and 1006 are reserved for reporting the status of a closed connection.
+func isValidCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	default:
+		return code >= 3000 && code <= 4999
+	}
+}
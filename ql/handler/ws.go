@@ -0,0 +1,370 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/leesper/pureql/ql"
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// graphqlTransportWS is the WebSocket sub-protocol implemented by WSHandler.
+const graphqlTransportWS = "graphql-transport-ws"
+
+// message types of graphql-transport-ws.
+const (
+	msgConnectionInit = "connection_init"
+	msgConnectionAck  = "connection_ack"
+	msgPing           = "ping"
+	msgPong           = "pong"
+	msgSubscribe      = "subscribe"
+	msgNext           = "next"
+	msgError          = "error"
+	msgComplete       = "complete"
+)
+
+// close codes of graphql-transport-ws.
+const (
+	closeBadRequest          = 4400
+	closeUnauthorized        = 4401
+	closeForbidden           = 4403
+	closeSubprotocolNotOK    = 4406
+	closeInitTimeout         = 4408
+	closeSubscriberExists    = 4409
+	closeTooManyInitRequests = 4429
+)
+
+// defaultInitTimeout is used if InitTimeout of WSHandler is zero.
+const defaultInitTimeout = 3 * time.Second
+
+// WSHandler is an http.Handler executing GraphQL operations over WebSocket with
+// the graphql-transport-ws protocol. Subscriptions deliver a next message for
+// each event, queries and mutations deliver a single one, then complete.
+type WSHandler struct {
+	Runtime *ql.Runtime
+	// OnInit is called with the payload of connection_init, the connection is
+	// closed as forbidden if it returns an error. The values of the returned
+	// context are visible to all operations of the connection, so values such
+	// as the authenticated user can be passed to resolvers, while operations
+	// are still cancelled when the connection is closed. The payload returned
+	// is sent with connection_ack.
+	OnInit func(ctx context.Context, payload map[string]interface{}) (context.Context, map[string]interface{}, error)
+	// InitTimeout is how long to wait for connection_init, 3 seconds if zero.
+	InitTimeout time.Duration
+	// KeepAlive is the interval of sending ping messages, a connection is
+	// closed if no pong is received before the next ping. No ping is sent if
+	// KeepAlive is zero.
+	KeepAlive time.Duration
+}
+
+// NewWS returns a WSHandler serving runtime.
+func NewWS(runtime *ql.Runtime) *WSHandler {
+	return &WSHandler{Runtime: runtime}
+}
+
+// wsMessage is a message of graphql-transport-ws.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsSession is the state of a graphql-transport-ws connection.
+type wsSession struct {
+	handler *WSHandler
+	conn    *wsConn
+	ctx     context.Context
+
+	mu     sync.Mutex // guards fields below
+	acked  bool
+	opCtx  context.Context
+	initCh chan struct{}
+	pong   bool
+	subs   map[string]context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrade(w, r, graphqlTransportWS)
+	if err != nil {
+		return
+	}
+	if conn.protocol != graphqlTransportWS {
+		conn.close(closeSubprotocolNotOK, "Subprotocol not acceptable")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	s := &wsSession{
+		handler: h,
+		conn:    conn,
+		ctx:     ctx,
+		initCh:  make(chan struct{}),
+		pong:    true,
+		subs:    map[string]context.CancelFunc{},
+	}
+	defer func() {
+		cancel()
+		s.wg.Wait()
+		conn.close(closeNormal, "")
+	}()
+
+	go s.waitInit(h.initTimeout())
+	if h.KeepAlive > 0 {
+		go s.keepAlive(h.KeepAlive)
+	}
+
+	for {
+		_, data, err := conn.readMessage()
+		if err != nil {
+			return
+		}
+		if !s.handle(data) {
+			return
+		}
+	}
+}
+
+func (h *WSHandler) initTimeout() time.Duration {
+	if h.InitTimeout > 0 {
+		return h.InitTimeout
+	}
+	return defaultInitTimeout
+}
+
+// waitInit closes the connection if connection_init is not received in time.
+func (s *wsSession) waitInit(timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-s.initCh:
+	case <-s.ctx.Done():
+	case <-timer.C:
+		s.conn.close(closeInitTimeout, "Connection initialisation timeout")
+	}
+}
+
+// keepAlive sends ping every interval, and closes the connection if pong of
+// the previous ping has not been received.
+func (s *wsSession) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		pong := s.pong
+		s.pong = false
+		s.mu.Unlock()
+		if !pong {
+			s.conn.close(closeGoingAway, "Keep alive timeout")
+			return
+		}
+		if err := s.send(wsMessage{Type: msgPing}); err != nil {
+			return
+		}
+	}
+}
+
+func (s *wsSession) send(msg wsMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.conn.writeText(data)
+}
+
+func (s *wsSession) sendPayload(id, typ string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return s.send(wsMessage{ID: id, Type: typ, Payload: data})
+}
+
+// handle handles a message, it returns false if the connection is closed.
+func (s *wsSession) handle(data []byte) bool {
+	var msg wsMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type == "" {
+		s.conn.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+
+	switch msg.Type {
+	case msgConnectionInit:
+		return s.init(msg)
+	case msgPing:
+		s.send(wsMessage{Type: msgPong, Payload: msg.Payload})
+	case msgPong:
+		s.mu.Lock()
+		s.pong = true
+		s.mu.Unlock()
+	case msgSubscribe:
+		return s.subscribe(msg)
+	case msgComplete:
+		s.mu.Lock()
+		if cancel, ok := s.subs[msg.ID]; ok {
+			cancel()
+			delete(s.subs, msg.ID)
+		}
+		s.mu.Unlock()
+	default:
+		s.conn.close(closeBadRequest, fmt.Sprintf("Unexpected message of type %s received", msg.Type))
+		return false
+	}
+	return true
+}
+
+func (s *wsSession) init(msg wsMessage) bool {
+	s.mu.Lock()
+	select {
+	case <-s.initCh:
+		s.mu.Unlock()
+		s.conn.close(closeTooManyInitRequests, "Too many initialisation requests")
+		return false
+	default:
+		close(s.initCh)
+	}
+	s.mu.Unlock()
+
+	var payload map[string]interface{}
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			s.conn.close(closeBadRequest, "Invalid connection_init payload")
+			return false
+		}
+	}
+
+	opCtx := s.ctx
+	var ackPayload map[string]interface{}
+	if s.handler.OnInit != nil {
+		ctx, p, err := s.handler.OnInit(s.ctx, payload)
+		if err != nil {
+			s.conn.close(closeForbidden, "Forbidden")
+			return false
+		}
+		if ctx != nil {
+			opCtx = valuesContext{s.ctx, ctx}
+		}
+		ackPayload = p
+	}
+
+	s.mu.Lock()
+	s.acked = true
+	s.opCtx = opCtx
+	s.mu.Unlock()
+	if ackPayload != nil {
+		s.sendPayload("", msgConnectionAck, ackPayload)
+	} else {
+		s.send(wsMessage{Type: msgConnectionAck})
+	}
+	return true
+}
+
+// valuesContext is cancelled along with its Context, and looks up values in
+// values first.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key interface{}) interface{} {
+	if v := c.values.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+func (s *wsSession) subscribe(msg wsMessage) bool {
+	var req Request
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil || req.Query == "" {
+		s.conn.close(closeBadRequest, "Invalid subscribe message")
+		return false
+	}
+
+	s.mu.Lock()
+	if !s.acked {
+		s.mu.Unlock()
+		s.conn.close(closeUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, ok := s.subs[msg.ID]; ok {
+		s.mu.Unlock()
+		s.conn.close(closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+		return false
+	}
+	ctx, cancel := context.WithCancel(s.opCtx)
+	s.subs[msg.ID] = cancel
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.wg.Done()
+		defer s.done(msg.ID, cancel)
+		s.execute(ctx, msg.ID, &req)
+	}()
+	return true
+}
+
+// done removes subscription id, complete is sent unless the client completed
+// it, or the connection is closed.
+func (s *wsSession) done(id string, cancel context.CancelFunc) {
+	s.mu.Lock()
+	_, ok := s.subs[id]
+	delete(s.subs, id)
+	s.mu.Unlock()
+	cancel()
+
+	if ok && s.ctx.Err() == nil {
+		s.send(wsMessage{ID: id, Type: msgComplete})
+	}
+}
+
+// execute executes req, it returns after all results are sent.
+func (s *wsSession) execute(ctx context.Context, id string, req *Request) {
+	fset := token.NewFileSet()
+	doc, err := ast.ParseDocument([]byte(req.Query), "", fset)
+	if err != nil {
+		s.sendErrors(id, syntaxErrors(err))
+		return
+	}
+
+	responses, errs := executeStream(ctx, s.handler.Runtime, fset, doc, req)
+	if len(errs) > 0 {
+		s.sendErrors(id, errs)
+		return
+	}
+	for rsp := range responses {
+		if err := s.sendPayload(id, msgNext, formatResponse(rsp)); err != nil {
+			return
+		}
+	}
+}
+
+// sendErrors sends errs as error message and removes the subscription, so no
+// complete is sent.
+func (s *wsSession) sendErrors(id string, errs []error) {
+	s.mu.Lock()
+	_, ok := s.subs[id]
+	delete(s.subs, id)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	payload := make([]interface{}, len(errs))
+	for i, err := range errs {
+		payload[i] = formatError(err)
+	}
+	s.sendPayload(id, msgError, payload)
+}
@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leesper/pureql/ql"
+)
+
+// newSubscriptionRuntime returns a runtime with a count subscription delivering
+// 1 to the to argument, or counting until cancelled, a non-null secret query
+// field failing to resolve, and a block query field resolving once cancelled.
+func newSubscriptionRuntime(t *testing.T) *ql.Runtime {
+	query := &ql.Object{Name: "Query", Fields: []*ql.Field{
+		{
+			Name: "user",
+			Typ:  ql.String,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ql.ResolveInfo) (interface{}, error) {
+				return "anonymous", nil
+			},
+		},
+		{
+			Name: "secret",
+			Typ:  &ql.NonNull{OfType: ql.String},
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ql.ResolveInfo) (interface{}, error) {
+				return nil, errors.New("forbidden")
+			},
+		},
+		{
+			Name: "block",
+			Typ:  ql.String,
+			Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ql.ResolveInfo) (interface{}, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+	}}
+	sub := &ql.Object{Name: "Subscription", Fields: []*ql.Field{{
+		Name: "count",
+		Typ:  ql.Int,
+		Defs: []*ql.ArgDef{{Name: "to", Typ: ql.Int}},
+		Subscribe: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ql.ResolveInfo) (<-chan interface{}, error) {
+			events := make(chan interface{})
+			go func() {
+				defer close(events)
+				for i := 1; args["to"] == nil || i <= args["to"].(int); i++ {
+					select {
+					case events <- i:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return events, nil
+		},
+	}}}
+	runtime, err := ql.NewRuntime(&ql.Schema{Qry: query, Sub: sub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime
+}
+
+func newWSServer(t *testing.T, h *WSHandler) *httptest.Server {
+	h.Runtime = newSubscriptionRuntime(t)
+	return httptest.NewServer(h)
+}
+
+// wsClient is a minimal WebSocket client for testing.
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, srv *httptest.Server, protocol string) *wsClient {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Protocol", protocol)
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	rsp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expecting status 101, found %d", rsp.StatusCode)
+	}
+	if accept := rsp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected Sec-WebSocket-Accept %s", accept)
+	}
+	return &wsClient{t: t, conn: conn, br: br}
+}
+
+func (c *wsClient) writeFrame(opcode byte, payload []byte) {
+	c.writeRawFrame(0x80|opcode, payload)
+}
+
+// writeRawFrame writes a frame with the first byte b0 of header, which holds
+// FIN and opcode.
+func (c *wsClient) writeRawFrame(b0 byte, payload []byte) {
+	mask := []byte{1, 2, 3, 4}
+	header := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	default:
+		header = append(header, 0x80|126, byte(n>>8), byte(n))
+	}
+	masked := make([]byte, len(payload))
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+	frame := append(append(header, mask...), masked...)
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsClient) send(msg string) {
+	c.writeFrame(opText, []byte(msg))
+}
+
+// read returns the next message, or the close code and reason.
+func (c *wsClient) read() (map[string]interface{}, int, string) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatal(err)
+	}
+
+	switch header[0] & 0x0F {
+	case opClose:
+		if len(payload) == 0 {
+			return nil, 0, ""
+		}
+		return nil, int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+	case opText:
+		var msg map[string]interface{}
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			c.t.Fatal(err)
+		}
+		return msg, 0, ""
+	default:
+		c.t.Fatalf("unexpected opcode %d", header[0]&0x0F)
+		return nil, 0, ""
+	}
+}
+
+func (c *wsClient) expect(expected string) {
+	msg, code, reason := c.read()
+	if msg == nil {
+		c.t.Fatalf("expecting %s, found close %d %s", expected, code, reason)
+	}
+	var exp map[string]interface{}
+	json.Unmarshal([]byte(expected), &exp)
+	if !equalJSON(exp, msg) {
+		c.t.Fatalf("expected %s, found %v", expected, msg)
+	}
+}
+
+func (c *wsClient) expectClose(code int, reason string) {
+	msg, foundCode, foundReason := c.read()
+	if msg != nil || foundCode != code || foundReason != reason {
+		c.t.Fatalf("expecting close %d %s, found %v %d %s", code, reason, msg, foundCode, foundReason)
+	}
+}
+
+func TestWSSubscribe(t *testing.T) {
+	type userKey struct{}
+	srv := newWSServer(t, &WSHandler{
+		OnInit: func(ctx context.Context, payload map[string]interface{}) (context.Context, map[string]interface{}, error) {
+			if payload["token"] != "secret" {
+				return nil, nil, errors.New("bad token")
+			}
+			return context.WithValue(ctx, userKey{}, "me"), map[string]interface{}{"user": "me"}, nil
+		},
+	})
+	defer srv.Close()
+
+	c := dial(t, srv, graphqlTransportWS)
+	c.send(`{"type": "connection_init", "payload": {"token": "secret"}}`)
+	c.expect(`{"type": "connection_ack", "payload": {"user": "me"}}`)
+
+	c.send(`{"type": "ping"}`)
+	c.expect(`{"type": "pong"}`)
+
+	c.send(`{"id": "1", "type": "subscribe", "payload": {"query": "subscription($to: Int) { count(to: $to) }", "variables": {"to": 2}}}`)
+	c.expect(`{"id": "1", "type": "next", "payload": {"data": {"count": 1}}}`)
+	c.expect(`{"id": "1", "type": "next", "payload": {"data": {"count": 2}}}`)
+	c.expect(`{"id": "1", "type": "complete"}`)
+
+	c.send(`{"id": "2", "type": "subscribe", "payload": {"query": "{ user }"}}`)
+	c.expect(`{"id": "2", "type": "next", "payload": {"data": {"user": "anonymous"}}}`)
+	c.expect(`{"id": "2", "type": "complete"}`)
+
+	c.send(`{"id": "6", "type": "subscribe", "payload": {"query": "{ secret }"}}`)
+	c.expect(`{"id": "6", "type": "next", "payload": {"data": null, "errors": [{"message": "forbidden", "locations": [{"line": 1, "column": 3}], "path": ["secret"]}]}}`)
+	c.expect(`{"id": "6", "type": "complete"}`)
+
+	c.send(`{"id": "3", "type": "subscribe", "payload": {"query": "subscription { count(to: \"a\") }"}}`)
+	c.expect(`{"id": "3", "type": "error", "payload": [{"message": "expected value of type \"Int\", found \"a\"", "locations": [{"line": 1, "column": 26}], "extensions": {"code": "GRAPHQL_VALIDATION_FAILED"}}]}`)
+
+	c.send(`{"id": "4", "type": "subscribe", "payload": {"query": "subscription { count }"}}`)
+	c.expect(`{"id": "4", "type": "next", "payload": {"data": {"count": 1}}}`)
+	c.send(`{"id": "4", "type": "complete"}`)
+
+	c.send(`{"id": "5", "type": "subscribe", "payload": {"query": "subscription { count(to: 1) }"}}`)
+	c.send(`{"id": "5", "type": "subscribe", "payload": {"query": "subscription { count(to: 1) }"}}`)
+	for {
+		msg, code, reason := c.read()
+		if msg != nil {
+			continue // events of 4 sent before complete, or of 5
+		}
+		if code != closeSubscriberExists || reason != "Subscriber for 5 already exists" {
+			t.Fatalf("unexpected close %d %s", code, reason)
+		}
+		break
+	}
+}
+
+func TestWSClose(t *testing.T) {
+	srv := newWSServer(t, &WSHandler{
+		InitTimeout: 50 * time.Millisecond,
+		OnInit: func(ctx context.Context, payload map[string]interface{}) (context.Context, map[string]interface{}, error) {
+			if payload["token"] != "secret" {
+				return nil, nil, errors.New("bad token")
+			}
+			return ctx, nil, nil
+		},
+	})
+	defer srv.Close()
+
+	c := dial(t, srv, "graphql-ws")
+	c.expectClose(closeSubprotocolNotOK, "Subprotocol not acceptable")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.expectClose(closeInitTimeout, "Connection initialisation timeout")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.send(`{"type": "connection_init"}`)
+	c.expectClose(closeForbidden, "Forbidden")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.send(`{"id": "1", "type": "subscribe", "payload": {"query": "subscription { count }"}}`)
+	c.expectClose(closeUnauthorized, "Unauthorized")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.send(`{"type": "connection_init", "payload": {"token": "secret"}}`)
+	c.expect(`{"type": "connection_ack"}`)
+	c.send(`{"type": "connection_init", "payload": {"token": "secret"}}`)
+	c.expectClose(closeTooManyInitRequests, "Too many initialisation requests")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.send(`not json`)
+	c.expectClose(closeBadRequest, "Invalid message received")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.writeFrame(opClose, nil)
+	c.expectClose(0, "")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.writeFrame(opClose, []byte{0x03, 0xE8})
+	c.expectClose(closeNormal, "")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.writeFrame(opClose, []byte{0x03, 0xED}) // 1005
+	c.expectClose(closeProtocolError, "invalid close frame")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.writeRawFrame(opPing, []byte("unfinished"))
+	c.expectClose(closeProtocolError, "invalid control frame")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.writeFrame(opPing, make([]byte, 126))
+	c.expectClose(closeProtocolError, "invalid control frame")
+
+	c = dial(t, srv, graphqlTransportWS)
+	c.writeRawFrame(0x80|0x40|opText, []byte(`{"type": "ping"}`))
+	c.expectClose(closeProtocolError, "reserved bits set")
+}
+
+func TestWSCloseCancelsOperations(t *testing.T) {
+	h := &WSHandler{
+		Runtime: newSubscriptionRuntime(t),
+		OnInit: func(ctx context.Context, payload map[string]interface{}) (context.Context, map[string]interface{}, error) {
+			return context.Background(), nil, nil
+		},
+	}
+	served := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(served)
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	c := dial(t, srv, graphqlTransportWS)
+	c.send(`{"type": "connection_init"}`)
+	c.expect(`{"type": "connection_ack"}`)
+	c.send(`{"id": "1", "type": "subscribe", "payload": {"query": "{ block }"}}`)
+	c.conn.Close()
+
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expecting the operation to be cancelled when the connection is closed")
+	}
+}
+
+func TestWSKeepAlive(t *testing.T) {
+	srv := newWSServer(t, &WSHandler{KeepAlive: 20 * time.Millisecond})
+	defer srv.Close()
+
+	c := dial(t, srv, graphqlTransportWS)
+	c.send(`{"type": "connection_init"}`)
+	c.expect(`{"type": "connection_ack"}`)
+	c.expect(`{"type": "ping"}`)
+	c.send(`{"type": "pong"}`)
+	c.expect(`{"type": "ping"}`)
+	c.expectClose(closeGoingAway, "Keep alive timeout")
+}
@@ -0,0 +1,424 @@
+package ql
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// builtinDirectives are the directives every executor supports.
+var builtinDirectives = []*Directive{
+	{
+		Name: "include",
+		Desc: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+		Locs: []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Defs: []*ArgDef{{Name: "if", Desc: "Included when true.", Typ: &NonNull{OfType: Boolean}}},
+	},
+	{
+		Name: "skip",
+		Desc: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+		Locs: []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Defs: []*ArgDef{{Name: "if", Desc: "Skipped when true.", Typ: &NonNull{OfType: Boolean}}},
+	},
+	{
+		Name: "deprecated",
+		Desc: "Marks an element of a GraphQL schema as no longer supported.",
+		Locs: []string{"FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INPUT_FIELD_DEFINITION", "ENUM_VALUE"},
+		Defs: []*ArgDef{{Name: "reason", Typ: String, Defl: DefaultDeprecationReason}},
+	},
+	{
+		Name: "specifiedBy",
+		Desc: "Exposes a URL that specifies the behavior of this scalar.",
+		Locs: []string{"SCALAR"},
+		Defs: []*ArgDef{{Name: "url", Desc: "The URL that specifies the behavior of this scalar.", Typ: &NonNull{OfType: String}}},
+	},
+}
+
+// directiveLocations are the names of the locations directives can be used at.
+var directiveLocations = []string{
+	"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD",
+	"INLINE_FRAGMENT", "VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION",
+	"ARGUMENT_DEFINITION", "INTERFACE", "UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION",
+}
+
+// introspection types.
+var (
+	schemaType            = &Object{Name: "__Schema"}
+	typeType              = &Object{Name: "__Type"}
+	fieldType             = &Object{Name: "__Field"}
+	inputValueType        = &Object{Name: "__InputValue"}
+	enumValueType         = &Object{Name: "__EnumValue"}
+	directiveType         = &Object{Name: "__Directive"}
+	typeKindType          = &Enum{Name: "__TypeKind"}
+	directiveLocationType = &Enum{Name: "__DirectiveLocation"}
+)
+
+// meta fields.
+var (
+	typenameField = &Field{
+		Name: "__typename",
+		Desc: "The name of the current Object type at runtime.",
+		Typ:  &NonNull{OfType: String},
+		Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+			return info.ParentType.Name, nil
+		},
+	}
+	schemaField = &Field{
+		Name: "__schema",
+		Desc: "Access the current type schema of this server.",
+		Typ:  &NonNull{OfType: schemaType},
+		Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+			return info.Runtime, nil
+		},
+	}
+	typeField = &Field{
+		Name: "__type",
+		Desc: "Request the type information of a single type.",
+		Typ:  typeType,
+		Defs: []*ArgDef{{Name: "name", Typ: &NonNull{OfType: String}}},
+		Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+			name, _ := args["name"].(string)
+			return info.Runtime.findType(name), nil
+		},
+	}
+)
+
+// metaField returns the introspection field name on typ, or nil if there is
+// none. __typename is on every composite type, __schema and __type are only on
+// the query root type.
+func (runtime *Runtime) metaField(typ Type, name string) *Field {
+	switch name {
+	case typenameField.Name:
+		if isCompositeType(typ) {
+			return typenameField
+		}
+	case schemaField.Name, typeField.Name:
+		if runtime.Schema == nil || runtime.Schema.Qry == nil || typ != runtime.Schema.Qry {
+			return nil
+		}
+		if name == schemaField.Name {
+			return schemaField
+		}
+		return typeField
+	}
+	return nil
+}
+
+// resolveWith returns a ResolveFunc resolving with fn, which never fails.
+func resolveWith(fn func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{}) ResolveFunc {
+	return func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+		return fn(source, args, info), nil
+	}
+}
+
+// inputValues returns the defs which are not deprecated, or all of them if the
+// argument includeDeprecated in args is true.
+func inputValues(defs []*ArgDef, args map[string]interface{}) []*ArgDef {
+	values := []*ArgDef{}
+	for _, def := range defs {
+		if def.Deprecated == "" || args["includeDeprecated"] == true {
+			values = append(values, def)
+		}
+	}
+	return values
+}
+
+// nonEmpty returns s as a value of nullable String, which is nil if s is empty.
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func init() {
+	for _, kind := range []string{"SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL"} {
+		typeKindType.Values = append(typeKindType.Values, &EnumValue{Name: kind})
+	}
+	typeKindType.Desc = "An enum describing what kind of type a given `__Type` is."
+
+	for _, loc := range directiveLocations {
+		directiveLocationType.Values = append(directiveLocationType.Values, &EnumValue{Name: loc})
+	}
+	directiveLocationType.Desc = "A Directive can be adjacent to many parts of the GraphQL language."
+
+	includeDeprecated := []*ArgDef{{Name: "includeDeprecated", Typ: Boolean, Defl: false}}
+	typeList := &List{OfType: &NonNull{OfType: typeType}}
+
+	schemaType.Desc = "A GraphQL Schema defines the capabilities of a GraphQL server."
+	schemaType.Fields = []*Field{
+		{Name: "description", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nil
+		})},
+		{Name: "types", Typ: &NonNull{OfType: typeList}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Runtime).allTypes()
+		})},
+		{Name: "queryType", Typ: &NonNull{OfType: typeType}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Runtime).Schema.Qry
+		})},
+		{Name: "mutationType", Typ: typeType, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Runtime).Schema.Mut
+		})},
+		{Name: "subscriptionType", Typ: typeType, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Runtime).Schema.Sub
+		})},
+		{Name: "directives", Typ: &NonNull{OfType: &List{OfType: &NonNull{OfType: directiveType}}}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Runtime).directives()
+		})},
+	}
+
+	typeType.Desc = "The fundamental unit of any GraphQL Schema is the type."
+	typeType.Fields = []*Field{
+		{Name: "kind", Typ: &NonNull{OfType: typeKindType}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return typeKind(source.(Type))
+		})},
+		{Name: "name", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			switch typ := source.(type) {
+			case *List, *NonNull:
+				return nil
+			default:
+				return typeName(typ.(Type))
+			}
+		})},
+		{Name: "description", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nonEmpty(typeDesc(source.(Type)))
+		})},
+		{Name: "specifiedByURL", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			if scalar, ok := source.(*Scalar); ok {
+				return nonEmpty(scalar.SpecifiedBy)
+			}
+			return nil
+		})},
+		{Name: "fields", Typ: &List{OfType: &NonNull{OfType: fieldType}}, Defs: includeDeprecated, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			switch source.(type) {
+			case *Object, *Interface:
+			default:
+				return nil
+			}
+			fields := []*Field{}
+			for _, f := range fieldsOf(source.(Type)) {
+				if f.Deprecated == "" || args["includeDeprecated"] == true {
+					fields = append(fields, f)
+				}
+			}
+			return fields
+		})},
+		{Name: "interfaces", Typ: typeList, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			switch typ := source.(type) {
+			case *Object:
+				return append([]*Interface{}, typ.Ifaces...)
+			case *Interface:
+				return append([]*Interface{}, typ.Ifaces...)
+			default:
+				return nil
+			}
+		})},
+		{Name: "possibleTypes", Typ: typeList, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			switch typ := source.(type) {
+			case *Interface, *Union:
+				return info.Runtime.possibleTypes(typ.(Type))
+			default:
+				return nil
+			}
+		})},
+		{Name: "enumValues", Typ: &List{OfType: &NonNull{OfType: enumValueType}}, Defs: includeDeprecated, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			enum, ok := source.(*Enum)
+			if !ok {
+				return nil
+			}
+			values := []*EnumValue{}
+			for _, ev := range enum.Values {
+				if ev.Deprecated == "" || args["includeDeprecated"] == true {
+					values = append(values, ev)
+				}
+			}
+			return values
+		})},
+		{Name: "inputFields", Typ: &List{OfType: &NonNull{OfType: inputValueType}}, Defs: includeDeprecated, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			iobj, ok := source.(*InputObject)
+			if !ok {
+				return nil
+			}
+			var inputFields []*ArgDef
+			for _, f := range iobj.Fields {
+				inputFields = append(inputFields, &ArgDef{Name: f.Name, Desc: f.Desc, Typ: f.Typ, Defl: f.Defl, Deprecated: f.Deprecated})
+			}
+			return inputValues(inputFields, args)
+		})},
+		{Name: "ofType", Typ: typeType, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			switch typ := source.(type) {
+			case *List:
+				return typ.OfType
+			case *NonNull:
+				return typ.OfType
+			default:
+				return nil
+			}
+		})},
+	}
+
+	fieldType.Desc = "Object and Interface types are described by a list of Fields, each of which has a name, potentially a list of arguments, and a return type."
+	fieldType.Fields = []*Field{
+		{Name: "name", Typ: &NonNull{OfType: String}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Field).Name
+		})},
+		{Name: "description", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nonEmpty(source.(*Field).Desc)
+		})},
+		{Name: "args", Typ: &NonNull{OfType: &List{OfType: &NonNull{OfType: inputValueType}}}, Defs: includeDeprecated, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return inputValues(source.(*Field).Defs, args)
+		})},
+		{Name: "type", Typ: &NonNull{OfType: typeType}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Field).Typ
+		})},
+		{Name: "isDeprecated", Typ: &NonNull{OfType: Boolean}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Field).Deprecated != ""
+		})},
+		{Name: "deprecationReason", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nonEmpty(source.(*Field).Deprecated)
+		})},
+	}
+
+	inputValueType.Desc = "Arguments provided to Fields or Directives and the input fields of an InputObject are represented as Input Values which describe their type and optionally a default value."
+	inputValueType.Fields = []*Field{
+		{Name: "name", Typ: &NonNull{OfType: String}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*ArgDef).Name
+		})},
+		{Name: "description", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nonEmpty(source.(*ArgDef).Desc)
+		})},
+		{Name: "type", Typ: &NonNull{OfType: typeType}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*ArgDef).Typ
+		})},
+		{Name: "defaultValue", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			def := source.(*ArgDef)
+			if def.Defl == nil {
+				return nil
+			}
+			return printValue(def.Defl, def.Typ)
+		})},
+		{Name: "isDeprecated", Typ: &NonNull{OfType: Boolean}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*ArgDef).Deprecated != ""
+		})},
+		{Name: "deprecationReason", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nonEmpty(source.(*ArgDef).Deprecated)
+		})},
+	}
+
+	enumValueType.Desc = "One possible value for a given Enum."
+	enumValueType.Fields = []*Field{
+		{Name: "name", Typ: &NonNull{OfType: String}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*EnumValue).Name
+		})},
+		{Name: "description", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nonEmpty(source.(*EnumValue).Desc)
+		})},
+		{Name: "isDeprecated", Typ: &NonNull{OfType: Boolean}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*EnumValue).Deprecated != ""
+		})},
+		{Name: "deprecationReason", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nonEmpty(source.(*EnumValue).Deprecated)
+		})},
+	}
+
+	directiveType.Desc = "A Directive provides a way to describe alternate runtime execution and type validation behavior in a GraphQL document."
+	directiveType.Fields = []*Field{
+		{Name: "name", Typ: &NonNull{OfType: String}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Directive).Name
+		})},
+		{Name: "description", Typ: String, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return nonEmpty(source.(*Directive).Desc)
+		})},
+		{Name: "isRepeatable", Typ: &NonNull{OfType: Boolean}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Directive).Repeatable
+		})},
+		{Name: "locations", Typ: &NonNull{OfType: &List{OfType: &NonNull{OfType: directiveLocationType}}}, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return source.(*Directive).Locs
+		})},
+		{Name: "args", Typ: &NonNull{OfType: &List{OfType: &NonNull{OfType: inputValueType}}}, Defs: includeDeprecated, Resolve: resolveWith(func(source interface{}, args map[string]interface{}, info *ResolveInfo) interface{} {
+			return inputValues(source.(*Directive).Defs, args)
+		})},
+	}
+}
+
+func typeKind(typ Type) string {
+	switch typ.(type) {
+	case *Scalar:
+		return "SCALAR"
+	case *Object:
+		return "OBJECT"
+	case *Interface:
+		return "INTERFACE"
+	case *Union:
+		return "UNION"
+	case *Enum:
+		return "ENUM"
+	case *InputObject:
+		return "INPUT_OBJECT"
+	case *List:
+		return "LIST"
+	default:
+		return "NON_NULL"
+	}
+}
+
+func typeDesc(typ Type) string {
+	switch typ := typ.(type) {
+	case *Scalar:
+		return typ.Desc
+	case *Object:
+		return typ.Desc
+	case *Interface:
+		return typ.Desc
+	case *Union:
+		return typ.Desc
+	case *Enum:
+		return typ.Desc
+	case *InputObject:
+		return typ.Desc
+	default:
+		return ""
+	}
+}
+
+// directives returns the built-in directives followed by the ones defined by
+// the schema.
+func (runtime *Runtime) directives() []*Directive {
+	directs := append([]*Directive{}, builtinDirectives...)
+	if runtime.Schema != nil {
+		directs = append(directs, runtime.Schema.Directives...)
+	}
+	return directs
+}
+
+// allTypes returns all named types in runtime sorted by name, introspection
+// types included.
+func (runtime *Runtime) allTypes() []Type {
+	var typs []Type
+	for _, scalar := range runtime.Scalars {
+		typs = append(typs, scalar)
+	}
+	for _, obj := range runtime.Objects {
+		typs = append(typs, obj)
+	}
+	for _, iface := range runtime.Ifaces {
+		typs = append(typs, iface)
+	}
+	for _, union := range runtime.Unions {
+		typs = append(typs, union)
+	}
+	for _, enum := range runtime.Enums {
+		typs = append(typs, enum)
+	}
+	for _, iobj := range runtime.InputObjs {
+		typs = append(typs, iobj)
+	}
+	sort.Slice(typs, func(i, j int) bool {
+		return typeName(typs[i]) < typeName(typs[j])
+	})
+	return typs
+}
+
+func isIntrospectionType(typ Type) bool {
+	return strings.HasPrefix(typeName(typ), "__")
+}
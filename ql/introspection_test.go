@@ -0,0 +1,212 @@
+package ql
+
+import (
+	"testing"
+)
+
+func TestIntrospectSchema(t *testing.T) {
+	runtime := newRuntime(humanSchema())
+	rsp := execute(t, runtime, `{
+	__schema {
+		queryType { name }
+		mutationType { name }
+		types { name kind }
+		directives { name locations args { name defaultValue type { kind ofType { name } } } }
+	}
+}`, "", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+
+	schema := plainResult(rsp.Data.Get("__schema")).(map[string]interface{})
+	assertEqual(t, map[string]interface{}{"name": "Query"}, schema["queryType"])
+	assertEqual(t, nil, schema["mutationType"])
+
+	var names []interface{}
+	for _, typ := range schema["types"].([]interface{}) {
+		names = append(names, typ.(map[string]interface{})["name"])
+	}
+	expected := []interface{}{
+		"Boolean", "Episode", "Float", "Human", "Query", "String",
+		"__Directive", "__DirectiveLocation", "__EnumValue", "__Field",
+		"__InputValue", "__Schema", "__Type", "__TypeKind",
+	}
+	assertEqual(t, expected, names)
+
+	directives := schema["directives"].([]interface{})
+	assertEqual(t, map[string]interface{}{
+		"name":      "skip",
+		"locations": []interface{}{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		"args": []interface{}{
+			map[string]interface{}{
+				"name":         "if",
+				"defaultValue": nil,
+				"type": map[string]interface{}{
+					"kind":   "NON_NULL",
+					"ofType": map[string]interface{}{"name": "Boolean"},
+				},
+			},
+		},
+	}, directives[1])
+
+	runtime = newRuntime(buildSchema(t, validateSDL))
+	rsp = execute(t, runtime, `{ __schema { directives { name isRepeatable } } }`, "", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+	directives = plainResult(rsp.Data.Get("__schema")).(map[string]interface{})["directives"].([]interface{})
+	assertEqual(t, map[string]interface{}{"name": "trace", "isRepeatable": true}, directives[len(directives)-1])
+}
+
+func TestIntrospectType(t *testing.T) {
+	schema := humanSchema()
+	episode := findField(schema.Qry.Fields[0].Typ.(*Object), "appearsIn").Typ.(*List).OfType.(*Enum)
+	episode.Values[0].Deprecated = DefaultDeprecationReason
+
+	runtime := newRuntime(schema)
+	rsp := execute(t, runtime, `{
+	human: __type(name: "Human") {
+		kind
+		name
+		fields { name type { kind name ofType { kind name } } }
+		interfaces { name }
+		enumValues { name }
+	}
+	episode: __type(name: "Episode") {
+		values: enumValues(includeDeprecated: true) { name isDeprecated deprecationReason }
+		active: enumValues { name }
+		fields { name }
+	}
+	missing: __type(name: "Droid") { name }
+}`, "", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+
+	field := func(name, kind string, typ map[string]interface{}) interface{} {
+		return map[string]interface{}{"name": name, "type": map[string]interface{}{"kind": kind, "name": nil, "ofType": typ}}
+	}
+	expected := map[string]interface{}{
+		"human": map[string]interface{}{
+			"kind": "OBJECT",
+			"name": "Human",
+			"fields": []interface{}{
+				field("name", "NON_NULL", map[string]interface{}{"kind": "SCALAR", "name": "String"}),
+				map[string]interface{}{"name": "height", "type": map[string]interface{}{"kind": "SCALAR", "name": "Float", "ofType": nil}},
+				field("friends", "LIST", map[string]interface{}{"kind": "OBJECT", "name": "Human"}),
+				field("appearsIn", "LIST", map[string]interface{}{"kind": "ENUM", "name": "Episode"}),
+				map[string]interface{}{"name": "secret", "type": map[string]interface{}{"kind": "SCALAR", "name": "String", "ofType": nil}},
+			},
+			"interfaces": []interface{}{},
+			"enumValues": nil,
+		},
+		"episode": map[string]interface{}{
+			"values": []interface{}{
+				map[string]interface{}{"name": "NEWHOPE", "isDeprecated": true, "deprecationReason": DefaultDeprecationReason},
+				map[string]interface{}{"name": "EMPIRE", "isDeprecated": false, "deprecationReason": nil},
+				map[string]interface{}{"name": "JEDI", "isDeprecated": false, "deprecationReason": nil},
+			},
+			"active": []interface{}{
+				map[string]interface{}{"name": "EMPIRE"},
+				map[string]interface{}{"name": "JEDI"},
+			},
+			"fields": nil,
+		},
+		"missing": nil,
+	}
+	assertEqual(t, expected, rsp.Data)
+}
+
+func TestIntrospectTypename(t *testing.T) {
+	runtime := newRuntime(humanSchema())
+	rsp := execute(t, runtime, `{ __typename hero { __typename kind: __typename } }`, "", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+	expected := map[string]interface{}{
+		"__typename": "Query",
+		"hero":       map[string]interface{}{"__typename": "Human", "kind": "Human"},
+	}
+	assertEqual(t, expected, rsp.Data)
+
+	rsp = execute(t, runtime, `{ hero { __schema { queryType { name } } } }`, "", nil)
+	if len(rsp.Errors) != 1 {
+		t.Fatalf("expecting 1 error, found %v", rsp.Errors)
+	}
+	assertEqual(t, `1:10: cannot query field "__schema" on type "Human"`, rsp.Errors[0].Error())
+}
+
+func TestIntrospectDeprecatedInputValues(t *testing.T) {
+	runtime := newRuntime(buildSchema(t, `
+directive @cached(ttl: Int, maxAge: Int @deprecated(reason: "Use ttl.")) on FIELD_DEFINITION
+
+input Filter {
+  name: String
+  tag: String @deprecated
+}
+
+scalar URL @specifiedBy(url: "https://url.spec.whatwg.org/")
+
+type Query {
+  search(filter: Filter, query: String @deprecated(reason: "Use filter.")): URL
+}
+`))
+	rsp := execute(t, runtime, `{
+	query: __type(name: "Query") {
+		fields {
+			active: args { name }
+			args(includeDeprecated: true) { name isDeprecated deprecationReason }
+		}
+	}
+	filter: __type(name: "Filter") {
+		active: inputFields { name }
+		inputFields(includeDeprecated: true) { name isDeprecated deprecationReason }
+	}
+	url: __type(name: "URL") { specifiedByURL }
+	__schema {
+		directives { name active: args { name } args(includeDeprecated: true) { name } }
+	}
+}`, "", nil)
+	if len(rsp.Errors) > 0 {
+		t.Fatal(rsp.Errors)
+	}
+
+	names := func(names ...string) []interface{} {
+		var values []interface{}
+		for _, name := range names {
+			values = append(values, map[string]interface{}{"name": name})
+		}
+		return values
+	}
+	assertEqual(t, map[string]interface{}{
+		"fields": []interface{}{
+			map[string]interface{}{
+				"active": names("filter"),
+				"args": []interface{}{
+					map[string]interface{}{"name": "filter", "isDeprecated": false, "deprecationReason": nil},
+					map[string]interface{}{"name": "query", "isDeprecated": true, "deprecationReason": "Use filter."},
+				},
+			},
+		},
+	}, rsp.Data.Get("query"))
+	assertEqual(t, map[string]interface{}{
+		"active": names("name"),
+		"inputFields": []interface{}{
+			map[string]interface{}{"name": "name", "isDeprecated": false, "deprecationReason": nil},
+			map[string]interface{}{"name": "tag", "isDeprecated": true, "deprecationReason": DefaultDeprecationReason},
+		},
+	}, rsp.Data.Get("filter"))
+	assertEqual(t, map[string]interface{}{"specifiedByURL": "https://url.spec.whatwg.org/"}, rsp.Data.Get("url"))
+
+	directives := plainResult(rsp.Data.Get("__schema")).(map[string]interface{})["directives"].([]interface{})
+	assertEqual(t, map[string]interface{}{
+		"name":   "specifiedBy",
+		"active": names("url"),
+		"args":   names("url"),
+	}, directives[3])
+	assertEqual(t, map[string]interface{}{
+		"name":   "cached",
+		"active": names("ttl"),
+		"args":   names("ttl", "maxAge"),
+	}, directives[4])
+}
@@ -0,0 +1,282 @@
+package ql
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// PrintSchema returns schema in the schema definition language. Named types
+// are printed in the order of their names after the directives defined by
+// schema, built-in scalars are omitted, and the schema definition is only
+// printed if the root types are not named Query, Mutation and Subscription.
+func PrintSchema(schema *Schema) string {
+	if schema == nil {
+		return ""
+	}
+	runtime := newRuntime(schema)
+
+	var blocks []string
+	if def := printSchemaDefinition(schema); def != "" {
+		blocks = append(blocks, def)
+	}
+	for _, direct := range schema.Directives {
+		blocks = append(blocks, printDirective(direct))
+	}
+	for _, typ := range namedTypes(runtime) {
+		blocks = append(blocks, printType(typ))
+	}
+	if len(blocks) == 0 {
+		return ""
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// namedTypes returns all user-defined named types in runtime, sorted by name.
+func namedTypes(runtime *Runtime) []Type {
+	var typs []Type
+	for _, typ := range runtime.allTypes() {
+		if scalar, ok := typ.(*Scalar); ok && isBuiltinScalar(scalar) {
+			continue
+		}
+		if !isIntrospectionType(typ) {
+			typs = append(typs, typ)
+		}
+	}
+	return typs
+}
+
+func isBuiltinScalar(scalar *Scalar) bool {
+	switch scalar.Name {
+	case Int.Name, Float.Name, String.Name, Boolean.Name, ID.Name:
+		return true
+	}
+	return false
+}
+
+func printSchemaDefinition(schema *Schema) string {
+	if (schema.Qry == nil || schema.Qry.Name == "Query") &&
+		(schema.Mut == nil || schema.Mut.Name == "Mutation") &&
+		(schema.Sub == nil || schema.Sub.Name == "Subscription") {
+		return ""
+	}
+
+	var b bytes.Buffer
+	b.WriteString("schema {\n")
+	if schema.Qry != nil {
+		fmt.Fprintf(&b, "  query: %s\n", schema.Qry.Name)
+	}
+	if schema.Mut != nil {
+		fmt.Fprintf(&b, "  mutation: %s\n", schema.Mut.Name)
+	}
+	if schema.Sub != nil {
+		fmt.Fprintf(&b, "  subscription: %s\n", schema.Sub.Name)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func printType(typ Type) string {
+	var b bytes.Buffer
+	switch typ := typ.(type) {
+	case *Scalar:
+		printDescription(&b, typ.Desc, "")
+		fmt.Fprintf(&b, "scalar %s", typ.Name)
+		if typ.SpecifiedBy != "" {
+			fmt.Fprintf(&b, " @specifiedBy(url: %s)", ast.QuoteString(typ.SpecifiedBy))
+		}
+	case *Object:
+		printDescription(&b, typ.Desc, "")
+		fmt.Fprintf(&b, "type %s", typ.Name)
+		printImplements(&b, typ.Ifaces)
+		printFields(&b, typ.Fields)
+	case *Interface:
+		printDescription(&b, typ.Desc, "")
+		fmt.Fprintf(&b, "interface %s", typ.Name)
+		printImplements(&b, typ.Ifaces)
+		printFields(&b, typ.Fields)
+	case *Union:
+		printDescription(&b, typ.Desc, "")
+		var names []string
+		for _, t := range typ.Typs {
+			names = append(names, typeName(t))
+		}
+		fmt.Fprintf(&b, "union %s = %s", typ.Name, strings.Join(names, " | "))
+	case *Enum:
+		printDescription(&b, typ.Desc, "")
+		fmt.Fprintf(&b, "enum %s {\n", typ.Name)
+		for _, ev := range typ.Values {
+			printDescription(&b, ev.Desc, "  ")
+			fmt.Fprintf(&b, "  %s%s\n", ev.Name, printDeprecated(ev.Deprecated))
+		}
+		b.WriteString("}")
+	case *InputObject:
+		printDescription(&b, typ.Desc, "")
+		fmt.Fprintf(&b, "input %s {\n", typ.Name)
+		for _, f := range typ.Fields {
+			printDescription(&b, f.Desc, "  ")
+			fmt.Fprintf(&b, "  %s: %s", f.Name, typeName(f.Typ))
+			if f.Defl != nil {
+				fmt.Fprintf(&b, " = %s", printValue(f.Defl, f.Typ))
+			}
+			fmt.Fprintf(&b, "%s\n", printDeprecated(f.Deprecated))
+		}
+		b.WriteString("}")
+	}
+	return b.String()
+}
+
+func printDirective(direct *Directive) string {
+	var b bytes.Buffer
+	printDescription(&b, direct.Desc, "")
+	fmt.Fprintf(&b, "directive @%s%s", direct.Name, printArgDefs(direct.Defs, ""))
+	if direct.Repeatable {
+		b.WriteString(" repeatable")
+	}
+	fmt.Fprintf(&b, " on %s", strings.Join(direct.Locs, " | "))
+	return b.String()
+}
+
+func printImplements(b *bytes.Buffer, ifaces []*Interface) {
+	if len(ifaces) == 0 {
+		return
+	}
+	var names []string
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	fmt.Fprintf(b, " implements %s", strings.Join(names, " & "))
+}
+
+func printFields(b *bytes.Buffer, fields []*Field) {
+	b.WriteString(" {\n")
+	for _, f := range fields {
+		printDescription(b, f.Desc, "  ")
+		fmt.Fprintf(b, "  %s%s: %s%s\n", f.Name, printArgDefs(f.Defs, "  "), typeName(f.Typ), printDeprecated(f.Deprecated))
+	}
+	b.WriteString("}")
+}
+
+// printArgDefs prints argument definitions in one line, or one per line if any
+// of them has a description, indented one more level than indent.
+func printArgDefs(defs []*ArgDef, indent string) string {
+	if len(defs) == 0 {
+		return ""
+	}
+
+	multiline := false
+	for _, def := range defs {
+		if def.Desc != "" {
+			multiline = true
+		}
+	}
+
+	var args []string
+	for _, def := range defs {
+		var b bytes.Buffer
+		if multiline {
+			printDescription(&b, def.Desc, indent+"  ")
+			b.WriteString(indent + "  ")
+		}
+		fmt.Fprintf(&b, "%s: %s", def.Name, typeName(def.Typ))
+		if def.Defl != nil {
+			fmt.Fprintf(&b, " = %s", printValue(def.Defl, def.Typ))
+		}
+		b.WriteString(printDeprecated(def.Deprecated))
+		args = append(args, b.String())
+	}
+
+	if multiline {
+		return "(\n" + strings.Join(args, "\n") + "\n" + indent + ")"
+	}
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func printDeprecated(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case DefaultDeprecationReason:
+		return " @deprecated"
+	default:
+		return fmt.Sprintf(" @deprecated(reason: %s)", ast.QuoteString(reason))
+	}
+}
+
+// printDescription prints desc as a string, or a block string if it spans
+// multiple lines.
+func printDescription(b *bytes.Buffer, desc, indent string) {
+	if desc == "" {
+		return
+	}
+	if !strings.Contains(desc, "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, ast.QuoteString(desc))
+		return
+	}
+	fmt.Fprintf(b, "%s%s\n", indent, ast.QuoteBlockString(desc, indent))
+}
+
+// printValue prints a Go value as a GraphQL value of type typ.
+func printValue(value interface{}, typ Type) string {
+	if isNil(value) {
+		return "null"
+	}
+
+	switch typ := typ.(type) {
+	case *NonNull:
+		return printValue(value, typ.OfType)
+	case *List:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return printValue(value, typ.OfType)
+		}
+		var items []string
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, printValue(v.Index(i).Interface(), typ.OfType))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *InputObject:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		var fields []string
+		for _, f := range typ.Fields {
+			if v, ok := m[f.Name]; ok {
+				fields = append(fields, fmt.Sprintf("%s: %s", f.Name, printValue(v, f.Typ)))
+			}
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case *Enum:
+		for _, ev := range typ.Values {
+			if reflect.DeepEqual(ev.value(), value) {
+				return ev.Name
+			}
+		}
+		for _, ev := range typ.Values {
+			if ev.Name == value {
+				return ev.Name
+			}
+		}
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		return ast.QuoteString(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return ast.QuoteString(fmt.Sprint(value))
+	}
+}
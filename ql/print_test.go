@@ -0,0 +1,154 @@
+package ql
+
+import (
+	"testing"
+)
+
+func TestPrintSchema(t *testing.T) {
+	color := &Enum{
+		Name: "Color",
+		Desc: "Primary colors.",
+		Values: []*EnumValue{
+			{Name: "RED", Value: 0},
+			{Name: "GREEN", Value: 1, Deprecated: DefaultDeprecationReason},
+			{Name: "BLUE", Value: 2, Desc: "The color of sky."},
+		},
+	}
+	filter := &InputObject{
+		Name: "Filter",
+		Fields: []*Field{
+			{Name: "color", Typ: color, Defl: 2},
+			{Name: "tags", Typ: &List{OfType: &NonNull{OfType: String}}, Defl: []string{"a", "b"}},
+		},
+	}
+	node := &Interface{
+		Name: "Node",
+		Fields: []*Field{
+			{Name: "id", Typ: &NonNull{OfType: ID}},
+		},
+	}
+	date := &Scalar{Name: "Date", Desc: "Date in\nRFC 3339."}
+	item := &Object{
+		Name:   "Item",
+		Ifaces: []*Interface{node},
+		Fields: []*Field{
+			{Name: "id", Typ: &NonNull{OfType: ID}},
+			{Name: "name", Typ: String, Desc: `Name of "item".`},
+			{Name: "created", Typ: date, Deprecated: "Use date."},
+		},
+	}
+	result := &Union{Name: "Result", Typs: []Type{item}}
+	root := &Object{
+		Name: "Root",
+		Fields: []*Field{
+			{
+				Name: "items",
+				Typ:  &NonNull{OfType: &List{OfType: result}},
+				Defs: []*ArgDef{
+					{Name: "filter", Typ: filter, Defl: map[string]interface{}{"color": 0}},
+					{Name: "first", Typ: Int, Defl: 10},
+				},
+			},
+			{
+				Name: "node",
+				Typ:  node,
+				Defs: []*ArgDef{
+					{Name: "id", Typ: &NonNull{OfType: ID}, Desc: "ID of node."},
+				},
+			},
+		},
+	}
+
+	expected := `schema {
+  query: Root
+}
+
+"Primary colors."
+enum Color {
+  RED
+  GREEN @deprecated
+  "The color of sky."
+  BLUE
+}
+
+"""
+Date in
+RFC 3339.
+"""
+scalar Date
+
+input Filter {
+  color: Color = BLUE
+  tags: [String!] = ["a", "b"]
+}
+
+type Item implements Node {
+  id: ID!
+  "Name of \"item\"."
+  name: String
+  created: Date @deprecated(reason: "Use date.")
+}
+
+interface Node {
+  id: ID!
+}
+
+union Result = Item
+
+type Root {
+  items(filter: Filter = {color: RED}, first: Int = 10): [Result]!
+  node(
+    "ID of node."
+    id: ID!
+  ): Node
+}
+`
+	found := PrintSchema(&Schema{Qry: root})
+	if found != expected {
+		t.Errorf("expected\n%s\nfound\n%s", expected, found)
+	}
+}
+
+func TestPrintBuiltSchema(t *testing.T) {
+	sdl := `directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+
+"Marks the field as cached."
+directive @cached(ttl: Int = 60) on FIELD_DEFINITION
+
+directive @log(
+  "The level."
+  level: Int
+  """
+  The logger,
+  the root if null.
+  """
+  logger: String
+) on FIELD
+
+input Filter {
+  name: String
+  tag: String @deprecated
+}
+
+enum Kind {
+  A
+  B @deprecated
+}
+
+type Mutation {
+  like(id: ID!, times: Int = 1 @deprecated(reason: "Like once.")): Int
+}
+
+type Query {
+  hello(name: String = "world", kind: Kind = B): String
+  old: String @deprecated(reason: "Gone.")
+  search(filter: Filter): URL
+}
+
+scalar URL @specifiedBy(url: "https://url.spec.whatwg.org/")
+`
+	found := PrintSchema(buildSchema(t, sdl))
+	if found != sdl {
+		t.Errorf("expected\n%s\nfound\n%s", sdl, found)
+	}
+}
@@ -0,0 +1,920 @@
+/*
+Package printer implements printing of AST nodes back into GraphQL source.
+*/
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// Mode controls the output of printer.
+type Mode uint
+
+// Printer modes
+const (
+	Compact Mode = 1 << iota // print on a single line
+	Minify                   // print on a single line without insignificant whitespace
+)
+
+// Config controls the output of Fprint.
+type Config struct {
+	Mode   Mode
+	Indent string // indentation of each nesting level, two spaces if empty
+}
+
+// Fprint "pretty-prints" an AST node to output, indenting with two spaces.
+func Fprint(output io.Writer, fset *token.FileSet, node ast.Node) error {
+	return (&Config{}).Fprint(output, fset, node)
+}
+
+// Fprint "pretty-prints" an AST node to output for a given configuration cfg.
+// If fset is not nil, position information is used to keep blank lines which
+// separate the selections, fields and values in source. Comments attached to
+// nodes by ast.ParseComments are printed unless output is compact, other
+// comments are not.
+func (cfg *Config) Fprint(output io.Writer, fset *token.FileSet, node ast.Node) error {
+	p := &printer{
+		Config: *cfg,
+		fset:   fset,
+	}
+	if p.Indent == "" {
+		p.Indent = "  "
+	}
+	if p.Mode&Minify != 0 {
+		p.Mode |= Compact
+	}
+
+	if err := p.node(node); err != nil {
+		return err
+	}
+	_, err := output.Write(p.out.Bytes())
+	return err
+}
+
+// whitespace pending to be written before the next token.
+type whitespace int
+
+const (
+	none whitespace = iota
+	blank
+	newline
+	blankLine
+)
+
+type printer struct {
+	Config
+	fset    *token.FileSet
+	out     bytes.Buffer
+	level   int
+	pending whitespace
+}
+
+// token writes text, preceded by the pending whitespace.
+func (p *printer) token(text string) {
+	switch p.pending {
+	case blankLine:
+		p.out.WriteString("\n\n")
+		p.out.WriteString(strings.Repeat(p.Indent, p.level))
+	case newline:
+		p.out.WriteString("\n")
+		p.out.WriteString(strings.Repeat(p.Indent, p.level))
+	case blank:
+		if p.Mode&Minify == 0 || (isNameByte(p.lastByte()) && len(text) > 0 && isNameByte(text[0])) {
+			p.out.WriteByte(' ')
+		}
+	}
+	p.pending = none
+	p.out.WriteString(text)
+}
+
+func (p *printer) lastByte() byte {
+	if p.out.Len() == 0 {
+		return 0
+	}
+	return p.out.Bytes()[p.out.Len()-1]
+}
+
+func isNameByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// space requests a blank before the next token.
+func (p *printer) space() {
+	if p.pending < blank {
+		p.pending = blank
+	}
+}
+
+// linebreak requests a line break before the next token, or a blank if output
+// is compact. The blank line between prev and next in source is kept if any.
+func (p *printer) linebreak(prev, next ast.Node) {
+	if p.Mode&Compact != 0 {
+		p.space()
+		return
+	}
+
+	ws := newline
+	if prev != nil && next != nil && p.fset != nil && prev.End().IsValid() && next.Pos().IsValid() {
+		pos := next.Pos()
+		if doc := docOf(next); doc != nil {
+			pos = doc.Pos()
+		}
+		if p.fset.Position(pos).Line-p.fset.Position(prev.End()).Line > 1 {
+			ws = blankLine
+		}
+	}
+	if p.pending < ws {
+		p.pending = ws
+	}
+}
+
+// separator between two top-level definitions.
+func (p *printer) separator() {
+	if p.Mode&Compact != 0 {
+		p.space()
+		return
+	}
+	p.pending = blankLine
+}
+
+// colon writes ":" followed by a blank.
+func (p *printer) colon() {
+	p.token(":")
+	p.space()
+}
+
+// comma writes "," followed by a blank.
+func (p *printer) comma() {
+	p.token(",")
+	p.space()
+}
+
+// open begins a block of indented lines.
+func (p *printer) open() {
+	p.token("{")
+	p.level++
+}
+
+// close ends a block of indented lines.
+func (p *printer) close() {
+	p.level--
+	p.linebreak(nil, nil)
+	p.token("}")
+}
+
+func (p *printer) node(node ast.Node) error {
+	switch n := node.(type) {
+	case *ast.Document:
+		for i, def := range n.Defs {
+			if i > 0 {
+				p.separator()
+			}
+			if err := p.node(def); err != nil {
+				return err
+			}
+		}
+	case *ast.OperationDefinition:
+		p.operationDefinition(n)
+	case *ast.FragmentDefinition:
+		p.fragmentDefinition(n)
+	case *ast.SelectionSet:
+		p.selectionSet(n)
+	case *ast.Field:
+		p.field(n)
+	case *ast.FragmentSpread:
+		p.fragmentSpread(n)
+	case *ast.InlineFragment:
+		p.inlineFragment(n)
+	case *ast.Alias:
+		p.token(n.Name.Text)
+		p.colon()
+	case *ast.Arguments:
+		p.arguments(n)
+	case *ast.Argument:
+		p.argument(n)
+	case *ast.TypeCondition:
+		p.typeCondition(n)
+	case *ast.VariableDefinitions:
+		p.variableDefinitions(n)
+	case *ast.VariableDefinition:
+		p.variableDefinition(n)
+	case *ast.DefaultValue:
+		p.defaultValue(n)
+	case *ast.ObjectField:
+		p.objectField(n)
+	case *ast.Directives:
+		p.directives(n)
+	case *ast.Directive:
+		p.directive(n)
+	case ast.Value:
+		p.value(n)
+	case ast.Type:
+		p.types(n)
+	case *ast.Schema:
+		for i, def := range schemaDefinitions(n) {
+			if i > 0 {
+				p.separator()
+			}
+			if err := p.node(def); err != nil {
+				return err
+			}
+		}
+	case *ast.SchemaDefinition:
+		p.schemaDefinition(n)
+	case *ast.OperationTypeDefinition:
+		p.operationTypeDefinition(n)
+	case *ast.ScalarDefinition:
+		p.scalarDefinition(n)
+	case *ast.TypeDefinition:
+		p.typeDefinition(n)
+	case *ast.ImplementsInterfaces:
+		p.implementsInterfaces(n)
+	case *ast.FieldDefinition:
+		p.fieldDefinition(n)
+	case *ast.ArgumentsDefinition:
+		p.argumentsDefinition(n)
+	case *ast.InputValueDefinition:
+		p.inputValueDefinition(n)
+	case *ast.InterfaceDefinition:
+		p.interfaceDefinition(n)
+	case *ast.UnionDefinition:
+		p.unionDefinition(n)
+	case *ast.UnionMembers:
+		p.unionMembers(n)
+	case *ast.UnionMember:
+		p.token("|")
+		p.space()
+		p.types(n.NamedTyp)
+	case *ast.EnumDefinition:
+		p.enumDefinition(n)
+	case *ast.EnumValue:
+		p.enumValue(n)
+	case *ast.InputObjectDefinition:
+		p.inputObjectDefinition(n)
+	case *ast.ExtendDefinition:
+		p.extension(n.Doc, n.Comment, n.TypDefn)
+	case *ast.TypeSystemExtension:
+		p.extension(n.Doc, n.Comment, n.Defn)
+	case *ast.DirectiveDefinition:
+		p.directiveDefinition(n)
+	case *ast.DirectiveLocations:
+		p.directiveLocations(n)
+	case *ast.DirectiveLocation:
+		p.token(n.Name.Text)
+	case *ast.Description:
+		p.description(n)
+	case *ast.CommentGroup:
+		p.doc(n)
+	default:
+		return fmt.Errorf("printer: unsupported node type %T", node)
+	}
+	return nil
+}
+
+// schemaDefinitions returns all definitions in schema in the order of their
+// positions in source.
+func schemaDefinitions(s *ast.Schema) []ast.Node {
+	var defs []ast.Node
+	for _, d := range s.Schemas {
+		defs = append(defs, d)
+	}
+	for _, d := range s.Scalars {
+		defs = append(defs, d)
+	}
+	for _, d := range s.Types {
+		defs = append(defs, d)
+	}
+	for _, d := range s.Interfaces {
+		defs = append(defs, d)
+	}
+	for _, d := range s.Unions {
+		defs = append(defs, d)
+	}
+	for _, d := range s.Enums {
+		defs = append(defs, d)
+	}
+	for _, d := range s.InputObjects {
+		defs = append(defs, d)
+	}
+	for _, d := range s.Extends {
+		defs = append(defs, d)
+	}
+	for _, d := range s.Directives {
+		defs = append(defs, d)
+	}
+	for _, d := range s.TypeSystemExtends {
+		defs = append(defs, d)
+	}
+	sort.SliceStable(defs, func(i, j int) bool {
+		return defs[i].Pos() < defs[j].Pos()
+	})
+	return defs
+}
+
+func (p *printer) operationDefinition(n *ast.OperationDefinition) {
+	p.doc(n.Doc)
+	if n.OperType.Text == "" && n.Name.Text == "" && n.VarDefns == nil && n.Directs == nil {
+		p.selectionSet(n.SelSet)
+		p.comment(n.Comment)
+		return
+	}
+
+	if n.OperType.Text == "" {
+		p.token(ast.Stringify(ast.QUERY))
+	} else {
+		p.token(n.OperType.Text)
+	}
+	if n.Name.Text != "" {
+		p.space()
+		p.token(n.Name.Text)
+	}
+	if n.VarDefns != nil {
+		p.variableDefinitions(n.VarDefns)
+	}
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	p.space()
+	p.selectionSet(n.SelSet)
+	p.comment(n.Comment)
+}
+
+func (p *printer) fragmentDefinition(n *ast.FragmentDefinition) {
+	p.doc(n.Doc)
+	p.token(ast.Stringify(ast.FRAGMENT))
+	p.space()
+	p.token(n.Name.Text)
+	p.space()
+	p.typeCondition(n.TypeCond)
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	p.space()
+	p.selectionSet(n.SelSet)
+	p.comment(n.Comment)
+}
+
+func (p *printer) selectionSet(n *ast.SelectionSet) {
+	p.open()
+	var prev ast.Node
+	for _, sel := range n.Sels {
+		p.linebreak(prev, sel)
+		p.node(sel)
+		prev = sel
+	}
+	p.close()
+}
+
+func (p *printer) field(n *ast.Field) {
+	p.doc(n.Doc)
+	if n.Als != nil {
+		p.token(n.Als.Name.Text)
+		p.colon()
+	}
+	p.token(n.Name.Text)
+	if n.Args != nil {
+		p.arguments(n.Args)
+	}
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	if n.SelSet != nil {
+		p.space()
+		p.selectionSet(n.SelSet)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) fragmentSpread(n *ast.FragmentSpread) {
+	p.doc(n.Doc)
+	p.token(ast.Stringify(ast.SPREAD))
+	p.token(n.Name.Text)
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) inlineFragment(n *ast.InlineFragment) {
+	p.doc(n.Doc)
+	p.token(ast.Stringify(ast.SPREAD))
+	if n.TypeCond != nil {
+		p.space()
+		p.typeCondition(n.TypeCond)
+	}
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	p.space()
+	p.selectionSet(n.SelSet)
+	p.comment(n.Comment)
+}
+
+func (p *printer) typeCondition(n *ast.TypeCondition) {
+	p.token(ast.Stringify(ast.ON))
+	p.space()
+	p.types(n.NamedTyp)
+}
+
+func (p *printer) arguments(n *ast.Arguments) {
+	p.token("(")
+	for i, arg := range n.Args {
+		if i > 0 {
+			p.comma()
+		}
+		p.argument(arg)
+	}
+	p.token(")")
+}
+
+func (p *printer) argument(n *ast.Argument) {
+	p.token(n.Name.Text)
+	p.colon()
+	p.value(n.Val)
+}
+
+func (p *printer) variableDefinitions(n *ast.VariableDefinitions) {
+	p.token("(")
+	for i, varDefn := range n.VarDefns {
+		if i > 0 {
+			p.comma()
+		}
+		p.variableDefinition(varDefn)
+	}
+	p.token(")")
+}
+
+func (p *printer) variableDefinition(n *ast.VariableDefinition) {
+	p.value(n.Var)
+	p.colon()
+	p.types(n.Typ)
+	if n.DeflVal != nil {
+		p.space()
+		p.defaultValue(n.DeflVal)
+	}
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+}
+
+func (p *printer) defaultValue(n *ast.DefaultValue) {
+	p.token("=")
+	p.space()
+	p.value(n.Val)
+}
+
+func (p *printer) value(n ast.Value) {
+	switch n := n.(type) {
+	case *ast.Variable:
+		p.token("$" + n.Name.Text)
+	case *ast.LiteralValue:
+		if n.Val.Kind == ast.STRING {
+			p.token(ast.QuoteString(n.Val.Text))
+		} else {
+			p.token(n.Val.Text)
+		}
+	case *ast.NameValue:
+		p.token(n.Val.Text)
+	case *ast.ListValue:
+		p.token("[")
+		for i, val := range n.Vals {
+			if i > 0 {
+				p.comma()
+			}
+			p.value(val)
+		}
+		p.token("]")
+	case *ast.ObjectValue:
+		p.token("{")
+		for i, f := range n.ObjFields {
+			if i > 0 {
+				p.comma()
+			}
+			p.objectField(f)
+		}
+		p.token("}")
+	}
+}
+
+func (p *printer) objectField(n *ast.ObjectField) {
+	p.token(n.Name.Text)
+	p.colon()
+	p.value(n.Val)
+}
+
+func (p *printer) types(n ast.Type) {
+	switch n := n.(type) {
+	case *ast.NamedType:
+		p.token(n.Name.Text)
+		if n.NonNull {
+			p.token("!")
+		}
+	case *ast.ListType:
+		p.token("[")
+		p.types(n.Typ)
+		p.token("]")
+		if n.NonNull {
+			p.token("!")
+		}
+	}
+}
+
+func (p *printer) directives(n *ast.Directives) {
+	for i, d := range n.Directs {
+		if i > 0 {
+			p.space()
+		}
+		p.directive(d)
+	}
+}
+
+func (p *printer) directive(n *ast.Directive) {
+	p.token("@" + n.Name.Text)
+	if n.Args != nil {
+		p.arguments(n.Args)
+	}
+}
+
+func (p *printer) schemaDefinition(n *ast.SchemaDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(ast.Stringify(ast.SCHEMA))
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	if len(n.OperDefns) == 0 { // extension adding directives
+		p.comment(n.Comment)
+		return
+	}
+	p.space()
+	p.open()
+	var prev ast.Node
+	for _, oper := range n.OperDefns {
+		p.linebreak(prev, oper)
+		p.operationTypeDefinition(oper)
+		prev = oper
+	}
+	p.close()
+	p.comment(n.Comment)
+}
+
+// extension writes the extension of definition defn, with the comments of the
+// extension.
+func (p *printer) extension(doc, comment *ast.CommentGroup, defn ast.Node) {
+	p.doc(doc)
+	p.token(ast.Stringify(ast.EXTEND))
+	p.space()
+	p.node(defn)
+	p.comment(comment)
+}
+
+func (p *printer) operationTypeDefinition(n *ast.OperationTypeDefinition) {
+	p.token(n.OperType.Text)
+	p.colon()
+	p.types(n.NamedTyp)
+}
+
+func (p *printer) scalarDefinition(n *ast.ScalarDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(ast.Stringify(ast.SCALAR))
+	p.space()
+	p.token(n.Name.Text)
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) typeDefinition(n *ast.TypeDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(ast.Stringify(ast.TYPE))
+	p.space()
+	p.token(n.Name.Text)
+	if n.Implements != nil {
+		p.space()
+		p.implementsInterfaces(n.Implements)
+	}
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	if len(n.FieldDefns) > 0 || n.Lbrace.IsValid() || (n.Implements == nil && n.Directs == nil) {
+		p.space()
+		p.fieldDefinitions(n.FieldDefns)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) implementsInterfaces(n *ast.ImplementsInterfaces) {
+	p.token(ast.Stringify(ast.IMPLEMENTS))
+	for i, named := range n.NamedTyps {
+		if i > 0 && len(n.Amps) > 0 {
+			p.space()
+			p.token(ast.Stringify(ast.AMP))
+		}
+		p.space()
+		p.types(named)
+	}
+}
+
+func (p *printer) fieldDefinitions(fieldDefns []*ast.FieldDefinition) {
+	if len(fieldDefns) == 0 {
+		p.token("{}")
+		return
+	}
+
+	p.open()
+	var prev ast.Node
+	for _, fd := range fieldDefns {
+		p.linebreak(prev, fd)
+		p.fieldDefinition(fd)
+		prev = fd
+	}
+	p.close()
+}
+
+func (p *printer) fieldDefinition(n *ast.FieldDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(n.Name.Text)
+	if n.ArgDefns != nil {
+		p.argumentsDefinition(n.ArgDefns)
+	}
+	p.colon()
+	p.types(n.Typ)
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) argumentsDefinition(n *ast.ArgumentsDefinition) {
+	if p.Mode&Compact == 0 && documented(n.InputValDefns) {
+		// one argument per line, below its description and comments
+		p.token("(")
+		p.level++
+		for _, input := range n.InputValDefns {
+			p.linebreak(nil, nil)
+			p.inputValueDefinition(input)
+		}
+		p.level--
+		p.linebreak(nil, nil)
+		p.token(")")
+		return
+	}
+
+	p.token("(")
+	for i, input := range n.InputValDefns {
+		if i > 0 {
+			p.comma()
+		}
+		p.inputValueDefinition(input)
+	}
+	p.token(")")
+}
+
+func (p *printer) inputValueDefinition(n *ast.InputValueDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(n.Name.Text)
+	p.colon()
+	p.types(n.Typ)
+	if n.DeflVal != nil {
+		p.space()
+		p.defaultValue(n.DeflVal)
+	}
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) interfaceDefinition(n *ast.InterfaceDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(ast.Stringify(ast.INTERFACE))
+	p.space()
+	p.token(n.Name.Text)
+	if n.Implements != nil {
+		p.space()
+		p.implementsInterfaces(n.Implements)
+	}
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	if len(n.FieldDefns) > 0 || n.Implements == nil && n.Directs == nil {
+		p.space()
+		p.fieldDefinitions(n.FieldDefns)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) unionDefinition(n *ast.UnionDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(ast.Stringify(ast.UNION))
+	p.space()
+	p.token(n.Name.Text)
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	if n.Members != nil {
+		p.space()
+		p.token("=")
+		p.space()
+		p.unionMembers(n.Members)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) unionMembers(n *ast.UnionMembers) {
+	p.types(n.NamedTyp)
+	for _, m := range n.Members {
+		p.space()
+		p.token("|")
+		p.space()
+		p.types(m.NamedTyp)
+	}
+}
+
+func (p *printer) enumDefinition(n *ast.EnumDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(ast.Stringify(ast.ENUM))
+	p.space()
+	p.token(n.Name.Text)
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	if len(n.EnumVals) == 0 { // extension adding directives
+		p.comment(n.Comment)
+		return
+	}
+	p.space()
+	p.open()
+	var prev ast.Node
+	for _, ev := range n.EnumVals {
+		p.linebreak(prev, ev)
+		p.enumValue(ev)
+		prev = ev
+	}
+	p.close()
+	p.comment(n.Comment)
+}
+
+func (p *printer) enumValue(n *ast.EnumValue) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(n.Name.Text)
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	p.comment(n.Comment)
+}
+
+func (p *printer) inputObjectDefinition(n *ast.InputObjectDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(ast.Stringify(ast.INPUT))
+	p.space()
+	p.token(n.Name.Text)
+	if n.Directs != nil {
+		p.space()
+		p.directives(n.Directs)
+	}
+	if len(n.InputValDefns) == 0 { // extension adding directives
+		p.comment(n.Comment)
+		return
+	}
+	p.space()
+	p.open()
+	var prev ast.Node
+	for _, input := range n.InputValDefns {
+		p.linebreak(prev, input)
+		p.inputValueDefinition(input)
+		prev = input
+	}
+	p.close()
+	p.comment(n.Comment)
+}
+
+func (p *printer) directiveDefinition(n *ast.DirectiveDefinition) {
+	p.doc(n.Doc)
+	p.description(n.Desc)
+	p.token(ast.Stringify(ast.DIRECTIVE))
+	p.space()
+	p.token("@" + n.Name.Text)
+	if n.Args != nil {
+		p.argumentsDefinition(n.Args)
+	}
+	if n.Repeat.IsValid() {
+		p.space()
+		p.token(ast.Stringify(ast.REPEATABLE))
+	}
+	p.space()
+	p.token(ast.Stringify(ast.ON))
+	p.space()
+	p.directiveLocations(n.Locs)
+	p.comment(n.Comment)
+}
+
+func (p *printer) directiveLocations(n *ast.DirectiveLocations) {
+	p.token(n.Name.Text)
+	for _, l := range n.Locs {
+		p.space()
+		p.token("|")
+		p.space()
+		p.token(l.Name.Text)
+	}
+}
+
+// documented reports if any of inputs has a description or comments.
+func documented(inputs []*ast.InputValueDefinition) bool {
+	for _, input := range inputs {
+		if input.Desc != nil || input.Doc != nil || input.Comment != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// description writes the description of a definition on lines of its own, as a
+// block string if it spans multiple lines.
+func (p *printer) description(n *ast.Description) {
+	if n == nil {
+		return
+	}
+
+	if p.Mode&Compact != 0 || !strings.Contains(n.Val.Text, "\n") {
+		p.token(ast.QuoteString(n.Val.Text))
+	} else {
+		p.token(ast.QuoteBlockString(n.Val.Text, strings.Repeat(p.Indent, p.level)))
+	}
+	p.linebreak(nil, nil)
+}
+
+// doc writes the lead comments of a node on lines of their own, comments are
+// dropped if output is compact.
+func (p *printer) doc(n *ast.CommentGroup) {
+	if n == nil || p.Mode&Compact != 0 {
+		return
+	}
+	for _, c := range n.List {
+		p.token(c.Text)
+		p.linebreak(nil, nil)
+	}
+}
+
+// comment writes the trailing comment of a node, ending the line.
+func (p *printer) comment(n *ast.CommentGroup) {
+	if n == nil || p.Mode&Compact != 0 {
+		return
+	}
+	for _, c := range n.List {
+		p.space()
+		p.token(c.Text)
+	}
+	p.linebreak(nil, nil)
+}
+
+// docOf returns the lead comments of the nodes printed on lines of their own.
+func docOf(n ast.Node) *ast.CommentGroup {
+	switch n := n.(type) {
+	case *ast.Field:
+		return n.Doc
+	case *ast.FragmentSpread:
+		return n.Doc
+	case *ast.InlineFragment:
+		return n.Doc
+	case *ast.FieldDefinition:
+		return n.Doc
+	case *ast.InputValueDefinition:
+		return n.Doc
+	case *ast.EnumValue:
+		return n.Doc
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package printer
+
+import (
+	"bytes"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+const query = `query queryName($foo: ComplexType, $site: Site = MOBILE) @dir {
+  whoever123is: node(id: [123, 456]) {
+    id
+    ... on User @defer {
+      field2 {
+        id
+        alias: field1(first: 10, after: $foo) @include(if: $foo) {
+          id
+          ...frag
+        }
+      }
+    }
+
+    ... @skip(unless: $foo) {
+      id
+    }
+    ... {
+      id
+    }
+  }
+}
+
+mutation likeStory {
+  like(story: 123) @defer {
+    story {
+      id
+    }
+  }
+}
+
+fragment frag on Friend {
+  foo(size: $size, bar: $b, obj: {key: "va\"lue", list: [1.5, true, null]})
+}
+
+{
+  unnamed(truthy: true, falsey: false, nullish: null)
+  query
+}
+`
+
+const schema = `schema {
+  query: QueryType
+  mutation: MutationType
+}
+
+type Foo implements Bar {
+  one: Type
+  two(argument: InputType!): Type
+
+  three(argument: InputType, other: String): Int
+  four(argument: String = "string"): String
+  five(argument: [String] = ["string", "string"]): String @deprecated
+}
+
+interface Bar @onInterface {
+  one: Type
+}
+
+union Feed = Story | Article | Advert
+
+scalar CustomScalar
+
+enum Site {
+  DESKTOP
+  MOBILE
+}
+
+input InputType {
+  key: String!
+  answer: Int = 42
+}
+
+extend type Foo {
+  seven(argument: [String]): Type
+}
+
+directive @include2(if: Boolean!) on FIELD | FRAGMENT_SPREAD | INLINE_FRAGMENT
+
+directive @key(fields: String!) repeatable on OBJECT | INTERFACE
+`
+
+func sprint(t *testing.T, cfg *Config, fset *token.FileSet, node ast.Node) string {
+	var b bytes.Buffer
+	if err := cfg.Fprint(&b, fset, node); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestPrintDocument(t *testing.T) {
+	fset := token.NewFileSet()
+	doc, err := ast.ParseDocument([]byte(query), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := sprint(t, &Config{}, fset, doc) + "\n"
+	if found != query {
+		t.Errorf("expected\n%s\nfound\n%s", query, found)
+	}
+}
+
+func TestPrintSchema(t *testing.T) {
+	fset := token.NewFileSet()
+	s, err := ast.ParseSchema([]byte(schema), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := sprint(t, &Config{}, fset, s) + "\n"
+	if found != schema {
+		t.Errorf("expected\n%s\nfound\n%s", schema, found)
+	}
+}
+
+func TestPrintIndent(t *testing.T) {
+	fset := token.NewFileSet()
+	doc, err := ast.ParseDocument([]byte(`query Q { a { b
+	c }
+
+	d }`), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "query Q {\n\ta {\n\t\tb\n\t\tc\n\t}\n\n\td\n}"
+	if found := sprint(t, &Config{Indent: "\t"}, fset, doc); found != expected {
+		t.Errorf("expected %q, found %q", expected, found)
+	}
+
+	// no blank lines kept without position information
+	expected = "query Q {\n  a {\n    b\n    c\n  }\n  d\n}"
+	if found := sprint(t, &Config{}, nil, doc); found != expected {
+		t.Errorf("expected %q, found %q", expected, found)
+	}
+}
+
+func TestPrintCompact(t *testing.T) {
+	fset := token.NewFileSet()
+	doc, err := ast.ParseDocument([]byte(`
+query Q($a: Int = 1 @tag, $b: [String!]!) {
+	x: a(b: $a, c: {d: [1, 2]}) @skip(if: false) {
+		... on T { e }
+		...F
+	}
+}
+
+fragment F on T { f }`), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `query Q($a: Int = 1 @tag, $b: [String!]!) { x: a(b: $a, c: {d: [1, 2]}) @skip(if: false) { ... on T { e } ...F } } fragment F on T { f }`
+	if found := sprint(t, &Config{Mode: Compact}, fset, doc); found != expected {
+		t.Errorf("expected %s, found %s", expected, found)
+	}
+
+	expected = `query Q($a:Int=1@tag,$b:[String!]!){x:a(b:$a,c:{d:[1,2]})@skip(if:false){...on T{e}...F}}fragment F on T{f}`
+	found := sprint(t, &Config{Mode: Minify}, fset, doc)
+	if found != expected {
+		t.Errorf("expected %s, found %s", expected, found)
+	}
+
+	// minified output parses back into the same document
+	reparsed, err := ast.ParseDocument([]byte(found), "", token.NewFileSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := sprint(t, &Config{Mode: Minify}, nil, reparsed); again != found {
+		t.Errorf("expected %s, found %s", found, again)
+	}
+}
+
+func TestPrintNode(t *testing.T) {
+	fset := token.NewFileSet()
+	doc, err := ast.ParseDocument([]byte(`{ a(b: "é\n") }`), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := doc.Defs[0].(*ast.OperationDefinition).SelSet.Sels[0]
+
+	expected := `a(b: "é\n")`
+	if found := sprint(t, &Config{}, fset, field); found != expected {
+		t.Errorf("expected %s, found %s", expected, found)
+	}
+
+	var b bytes.Buffer
+	if err := Fprint(&b, fset, nil); err == nil {
+		t.Error("expecting error for nil node")
+	}
+}
+
+func TestPrintDescriptions(t *testing.T) {
+	const described = `"""
+The root query.
+
+Spans "multiple" lines.
+"""
+type Query {
+  "A friend of the viewer."
+  friend(
+    "The id of the friend."
+    id: ID!
+    first: Int
+  ): String
+  """
+  Indented \""" block
+  on two lines.
+  """
+  other(a: Int): Int
+}
+
+"Episodes of the saga."
+enum Episode {
+  "Released in 1977."
+  NEWHOPE
+}
+`
+
+	fset := token.NewFileSet()
+	s, err := ast.ParseSchema([]byte(described), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := sprint(t, &Config{}, fset, s) + "\n"; found != described {
+		t.Errorf("expected\n%s\nfound\n%s", described, found)
+	}
+
+	expected := `"The root query.\n\nSpans \"multiple\" lines." type Query { "A friend of the viewer." friend("The id of the friend." id: ID!, first: Int): String "Indented \"\"\" block\non two lines." other(a: Int): Int } "Episodes of the saga." enum Episode { "Released in 1977." NEWHOPE }`
+	if found := sprint(t, &Config{Mode: Compact}, fset, s); found != expected {
+		t.Errorf("expected %s, found %s", expected, found)
+	}
+}
+
+func TestPrintComments(t *testing.T) {
+	const commented = `# The root query.
+type Query {
+  # A friend.
+  friend(
+    # The id.
+    id: ID! # required
+    first: Int
+  ): String # may be null
+
+  # Separated by a blank line.
+  other: Int
+} # end of Query
+
+query {
+  # the friend
+  friend(id: 1) # trailing
+}
+`
+
+	fset := token.NewFileSet()
+	s, err := ast.ParseSchema([]byte(commented[:strings.Index(commented, "\nquery")]), "", fset, ast.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ast.ParseDocument([]byte(commented[strings.Index(commented, "\nquery")+1:]), "", fset, ast.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := sprint(t, &Config{}, fset, s) + "\n\n" + sprint(t, &Config{}, fset, doc) + "\n"; found != commented {
+		t.Errorf("expected\n%s\nfound\n%s", commented, found)
+	}
+
+	expected := `type Query { friend(id: ID!, first: Int): String other: Int }`
+	if found := sprint(t, &Config{Mode: Compact}, fset, s); found != expected {
+		t.Errorf("expected %s, found %s", expected, found)
+	}
+}
+
+func TestPrintExtensions(t *testing.T) {
+	const extensions = `extend schema @dir {
+  subscription: Subscription
+}
+
+extend scalar Time @dir
+
+extend type User implements Node @dir
+
+extend type User {
+  name: String
+}
+
+extend interface Node @dir
+
+interface Resource implements Node & Entity {
+  id: ID
+}
+
+extend union Result = Photo | Video
+
+extend enum Kind {
+  C
+}
+
+extend input Filter @dir
+`
+
+	fset := token.NewFileSet()
+	s, err := ast.ParseSchema([]byte(extensions), "", fset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := sprint(t, &Config{}, fset, s) + "\n"; found != extensions {
+		t.Errorf("expected\n%s\nfound\n%s", extensions, found)
+	}
+}
@@ -3,6 +3,8 @@ package ql
 import (
 	"fmt"
 	"strings"
+
+	"github.com/leesper/pureql/ql/ast"
 )
 
 // Type interface for all types.
@@ -10,15 +12,37 @@ type Type interface {
 	Type() string
 }
 
-// Schema is the entry point of GraphQL service.
+// Schema is the entry point of GraphQL service. Typs are types not reachable from
+// the root types, such as objects only referenced through their interfaces.
+// Directives are the directives defined besides the built-in ones, and Directs
+// are the directives applied to the schema.
 type Schema struct {
-	Qry *Object
-	Mut *Object
+	Qry        *Object
+	Mut        *Object
+	Sub        *Object
+	Typs       []Type
+	Directives []*Directive
+	Directs    []*AppliedDirective
 }
 
-// Scalar represents primitive value.
+// Scalar represents primitive value. SpecifiedBy is the URL of the
+// specification of a custom scalar, given by @specifiedBy.
 type Scalar struct {
-	Name string
+	Name        string
+	Desc        string
+	SpecifiedBy string
+	Directs     []*AppliedDirective
+	// Serialize converts a resolved value into the result of this scalar, the
+	// value is returned as is if Serialize is nil.
+	Serialize func(value interface{}) (interface{}, error)
+	// ParseValue converts an input value such as a variable value decoded from
+	// JSON into the internal value of this scalar, the value is used as is if
+	// ParseValue is nil.
+	ParseValue func(value interface{}) (interface{}, error)
+	// ParseLiteral converts a literal in a document into the internal value of
+	// this scalar. If ParseLiteral is nil, the literal is converted as if it
+	// was decoded from JSON and then passed to ParseValue.
+	ParseLiteral func(value ast.Value) (interface{}, error)
 }
 
 // Type returns basic type info.
@@ -28,7 +52,10 @@ func (scalar *Scalar) Type() string {
 
 // Enum represents limited enumerable values.
 type Enum struct {
-	Name string
+	Name    string
+	Desc    string
+	Values  []*EnumValue
+	Directs []*AppliedDirective
 }
 
 // Type returns basic type info.
@@ -36,48 +63,77 @@ func (enum *Enum) Type() string {
 	return fmt.Sprintf("enum %s", enum.Name)
 }
 
+// EnumValue represents one of the values of Enum. Value is the internal value
+// resolvers use, the Name is used if Value is nil. A non-empty Deprecated is
+// the reason why the value is deprecated.
+type EnumValue struct {
+	Name       string
+	Desc       string
+	Value      interface{}
+	Deprecated string
+	Directs    []*AppliedDirective
+}
+
+func (ev *EnumValue) value() interface{} {
+	if ev.Value == nil {
+		return ev.Name
+	}
+	return ev.Value
+}
+
 // Object defines a set of fields of another type in the type system.
 type Object struct {
-	Name   string
-	Ifaces []*Interface
-	Fields []*Field
+	Name    string
+	Desc    string
+	Ifaces  []*Interface
+	Fields  []*Field
+	Directs []*AppliedDirective
 }
 
 // Type returns basic type info.
 func (obj *Object) Type() string {
 	var fieldInfos []string
 	for _, f := range obj.Fields {
-		fieldInfos = append(fieldInfos, f.Typ.Type())
+		fieldInfos = append(fieldInfos, fmt.Sprintf("%s: %s", f.Name, typeName(f.Typ)))
 	}
 	return fmt.Sprintf("object %s { %s }", obj.Name, strings.Join(fieldInfos, " "))
 }
 
 // Interface defines an abstract type for Object to implement.
 type Interface struct {
-	Name   string
-	Fields []*Field
+	Name    string
+	Desc    string
+	Ifaces  []*Interface // interfaces implemented, including the ones they implement
+	Fields  []*Field
+	Directs []*AppliedDirective
+	// ResolveType determines the concrete Object type of a resolved value.
+	ResolveType ResolveTypeFunc
 }
 
 // Type returns basic type info.
 func (iface *Interface) Type() string {
 	var fieldInfos []string
 	for _, f := range iface.Fields {
-		fieldInfos = append(fieldInfos, f.Typ.Type())
+		fieldInfos = append(fieldInfos, fmt.Sprintf("%s: %s", f.Name, typeName(f.Typ)))
 	}
 	return fmt.Sprintf("interface %s { %s }", iface.Name, strings.Join(fieldInfos, " "))
 }
 
 // Union defines a list of possible Object types.
 type Union struct {
-	Name string
-	Typs []Type
+	Name    string
+	Desc    string
+	Typs    []Type
+	Directs []*AppliedDirective
+	// ResolveType determines the concrete Object type of a resolved value.
+	ResolveType ResolveTypeFunc
 }
 
 // Type returns basic type info.
 func (union *Union) Type() string {
 	var typeInfos []string
 	for _, t := range union.Typs {
-		typeInfos = append(typeInfos, t.Type())
+		typeInfos = append(typeInfos, typeName(t))
 	}
 	return fmt.Sprintf("union %s %s", union.Name, strings.Join(typeInfos, "|"))
 }
@@ -104,18 +160,116 @@ func (nn *NonNull) Type() string {
 
 // InputObject is a struct for complex input.
 type InputObject struct {
-	Name   string
-	Fields []*Field
+	Name    string
+	Desc    string
+	Fields  []*Field
+	Directs []*AppliedDirective
 }
 
 // Type returns basic type info.
 func (io *InputObject) Type() string {
 	var fieldInfos []string
 	for _, f := range io.Fields {
-		fieldInfos = append(fieldInfos, f.Typ.Type())
+		fieldInfos = append(fieldInfos, fmt.Sprintf("%s: %s", f.Name, typeName(f.Typ)))
 	}
 	return fmt.Sprintf("input %s { %s }", io.Name, strings.Join(fieldInfos, " "))
 }
 
-// Directive represents directives the execution engine supports.
-type Directive struct{}
+// Directive is the definition of a directive, Locs are the names of the
+// locations it can be used at, such as FIELD or OBJECT. A Repeatable directive
+// can be used more than once at a location.
+type Directive struct {
+	Name       string
+	Desc       string
+	Defs       []*ArgDef
+	Locs       []string
+	Repeatable bool
+}
+
+// AppliedDirective is a directive used in the schema, Args are the coerced
+// values of its arguments, including the defaults of the ones not given.
+type AppliedDirective struct {
+	Name string
+	Args map[string]interface{}
+}
+
+// typeName returns the name referencing typ, such as Int, [String!] and Human!.
+func typeName(typ Type) string {
+	switch typ := typ.(type) {
+	case *Scalar:
+		return typ.Name
+	case *Enum:
+		return typ.Name
+	case *Object:
+		return typ.Name
+	case *Interface:
+		return typ.Name
+	case *Union:
+		return typ.Name
+	case *InputObject:
+		return typ.Name
+	case *List:
+		return fmt.Sprintf("[%s]", typeName(typ.OfType))
+	case *NonNull:
+		return typeName(typ.OfType) + "!"
+	default:
+		panic(fmt.Errorf("unexpected type %T", typ))
+	}
+}
+
+// namedType returns the named type wrapped by List and NonNull.
+func namedType(typ Type) Type {
+	for {
+		switch t := typ.(type) {
+		case *List:
+			typ = t.OfType
+		case *NonNull:
+			typ = t.OfType
+		default:
+			return typ
+		}
+	}
+}
+
+func isInputType(typ Type) bool {
+	switch namedType(typ).(type) {
+	case *Scalar, *Enum, *InputObject:
+		return true
+	}
+	return false
+}
+
+func isOutputType(typ Type) bool {
+	switch namedType(typ).(type) {
+	case *Scalar, *Enum, *Object, *Interface, *Union:
+		return true
+	}
+	return false
+}
+
+func isLeafType(typ Type) bool {
+	switch namedType(typ).(type) {
+	case *Scalar, *Enum:
+		return true
+	}
+	return false
+}
+
+func isCompositeType(typ Type) bool {
+	switch typ.(type) {
+	case *Object, *Interface, *Union:
+		return true
+	}
+	return false
+}
+
+// fieldsOf returns the fields of Object and Interface.
+func fieldsOf(typ Type) []*Field {
+	switch typ := typ.(type) {
+	case *Object:
+		return typ.Fields
+	case *Interface:
+		return typ.Fields
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package ql
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+
+	"github.com/leesper/pureql/ql/ast"
+)
+
+// Subscribe executes the subscription operation defined by document with optional
+// variable values. Errors of validating document, coercing variable values or
+// creating the source event stream are returned as *Error if there are any,
+// fset is used to locate them as with Execute. Otherwise a
+// Response is delivered on the returned channel for each event. The channel is
+// closed after the source event stream is closed, or ctx is done.
+func (runtime *Runtime) Subscribe(ctx context.Context, fset *token.FileSet, document *ast.Document, operationName string, variableValues map[string]interface{}) (<-chan *Response, []error) {
+	if errs := validateDocument(runtime, fset, document); len(errs) > 0 {
+		return nil, errs
+	}
+
+	operation, err := runtime.getOperation(document, operationName)
+	if err != nil {
+		return nil, []error{newError(err, fset, nil)}
+	}
+	if operType := operation.OperType.Text; operType != ast.Stringify(ast.SUBSCRIPTION) {
+		if operType == "" {
+			operType = ast.Stringify(ast.QUERY)
+		}
+		return nil, []error{newError(fmt.Errorf("query error: %s operations must be executed with Execute", operType), fset, nil, operation)}
+	}
+
+	coercedVarVals, err := runtime.coerceVariableValues(operation, variableValues)
+	if err != nil {
+		return nil, []error{inputError(err, fset, operation)}
+	}
+
+	fragments := fragmentsOf(document)
+	events, err := runtime.createSourceEventStream(ctx, fset, operation, fragments, coercedVarVals)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	responses := make(chan *Response)
+	go func() {
+		defer close(responses)
+		for {
+			var event interface{}
+			var ok bool
+			select {
+			case event, ok = <-events:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			rsp := runtime.executeSubscriptionEvent(ctx, fset, operation, fragments, coercedVarVals, event)
+			select {
+			case responses <- rsp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return responses, nil
+}
+
+// createSourceEventStream calls Subscribe of the root field of subscription,
+// errors are returned as *Error.
+func (runtime *Runtime) createSourceEventStream(ctx context.Context, fset *token.FileSet, subscription *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition, variableValues map[string]interface{}) (<-chan interface{}, error) {
+	subType := runtime.Schema.Sub
+	ec := newExecutionContext(ctx, fset, runtime, subscription, fragments, variableValues)
+	groupedFieldSet := ec.collectFields(subType, subscription.SelSet)
+	if len(groupedFieldSet) != 1 {
+		return nil, newError(fmt.Errorf("query error: subscription must select only one top level field"), fset, nil, subscription)
+	}
+
+	group := groupedFieldSet[0]
+	path := []interface{}{group.Key}
+	fieldDef := findField(subType, group.Fields[0].Name.Text)
+	if fieldDef == nil || fieldDef.Subscribe == nil {
+		return nil, newError(fmt.Errorf("query error: field %s is not subscribable", group.Fields[0].Name.Text), fset, path, group.Fields[0])
+	}
+
+	argumentValues, err := coerceArgumentValues(fieldDef.Defs, group.Fields[0].Args, variableValues)
+	if err != nil {
+		return nil, newError(err, fset, path, group.Fields[0])
+	}
+	info := &ResolveInfo{
+		FieldName:      fieldDef.Name,
+		FieldNodes:     group.Fields,
+		ReturnType:     fieldDef.Typ,
+		ParentType:     subType,
+		Path:           path,
+		Operation:      subscription,
+		Fragments:      fragments,
+		VariableValues: variableValues,
+		Runtime:        runtime,
+	}
+	events, err := fieldDef.Subscribe(ctx, nil, argumentValues, info)
+	if err != nil {
+		return nil, newError(err, fset, path, group.Fields[0])
+	}
+	return events, nil
+}
+
+func (runtime *Runtime) executeSubscriptionEvent(ctx context.Context, fset *token.FileSet, subscription *ast.OperationDefinition, fragments map[string]*ast.FragmentDefinition, variableValues map[string]interface{}, event interface{}) *Response {
+	if runtime.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runtime.Timeout)
+		defer cancel()
+	}
+	ec := newExecutionContext(ctx, fset, runtime, subscription, fragments, variableValues)
+	data, _ := ec.executeSelectionSet(subscription.SelSet, runtime.Schema.Sub, event, nil)
+	return &Response{Data: data, Errors: sortErrors(ec.errs), Executed: true}
+}
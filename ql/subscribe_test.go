@@ -0,0 +1,144 @@
+package ql
+
+import (
+	"context"
+	"errors"
+	"go/token"
+	"testing"
+)
+
+func subscriptionSchema(events func(ctx context.Context, from int) <-chan interface{}) *Schema {
+	return &Schema{
+		Qry: &Object{Name: "Query", Fields: []*Field{{Name: "hello", Typ: String}}},
+		Sub: &Object{
+			Name: "Subscription",
+			Fields: []*Field{
+				{
+					Name: "counter",
+					Typ:  &NonNull{OfType: Int},
+					Defs: []*ArgDef{{Name: "from", Typ: Int, Defl: 0}},
+					Subscribe: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (<-chan interface{}, error) {
+						return events(ctx, args["from"].(int)), nil
+					},
+				},
+				{
+					Name: "label",
+					Typ:  String,
+					Subscribe: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (<-chan interface{}, error) {
+						return nil, errors.New("label is not available")
+					},
+					Resolve: func(ctx context.Context, source interface{}, args map[string]interface{}, info *ResolveInfo) (interface{}, error) {
+						return "", nil
+					},
+				},
+			},
+		},
+	}
+}
+
+func subscribe(t *testing.T, ctx context.Context, runtime *Runtime, document string, variableValues map[string]interface{}) (<-chan *Response, []error) {
+	fset := token.NewFileSet()
+	return runtime.Subscribe(ctx, fset, parseDocument(t, fset, document), "", variableValues)
+}
+
+func TestSubscribe(t *testing.T) {
+	runtime := newRuntime(subscriptionSchema(func(ctx context.Context, from int) <-chan interface{} {
+		events := make(chan interface{})
+		go func() {
+			defer close(events)
+			for i := from; i < from+3; i++ {
+				select {
+				case events <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return events
+	}))
+
+	responses, errs := subscribe(t, context.Background(), runtime, `subscription S($from: Int) { n: counter(from: $from) }`, map[string]interface{}{"from": 5})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	var found []interface{}
+	for rsp := range responses {
+		if len(rsp.Errors) > 0 {
+			t.Fatal(rsp.Errors)
+		}
+		found = append(found, rsp.Data.Get("n"))
+	}
+	assertEqual(t, []interface{}{5, 6, 7}, found)
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	done := make(chan struct{})
+	runtime := newRuntime(subscriptionSchema(func(ctx context.Context, from int) <-chan interface{} {
+		events := make(chan interface{})
+		go func() {
+			defer close(done)
+			for i := from; ; i++ {
+				select {
+				case events <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return events
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	responses, errs := subscribe(t, ctx, runtime, `subscription { counter }`, nil)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	rsp := <-responses
+	assertEqual(t, map[string]interface{}{"counter": 0}, rsp.Data)
+	cancel()
+	for range responses {
+	}
+	<-done
+}
+
+func TestSubscribeErrors(t *testing.T) {
+	runtime := newRuntime(subscriptionSchema(nil))
+
+	tests := []struct {
+		doc      string
+		expected string
+	}{
+		{
+			`subscription S { counter label }`,
+			`1:1: subscription "S" must select only one top level field`,
+		},
+		{
+			`subscription { __typename }`,
+			`1:16: anonymous subscription must not select an introspection top level field`,
+		},
+		{
+			`{ hello }`,
+			`1:1: query error: query operations must be executed with Execute`,
+		},
+		{
+			`subscription { label }`,
+			`1:16: label is not available`,
+		},
+	}
+	for _, test := range tests {
+		responses, errs := subscribe(t, context.Background(), runtime, test.doc, nil)
+		if responses != nil || len(errs) != 1 {
+			t.Errorf("%s: expecting 1 error, found %v", test.doc, errs)
+			continue
+		}
+		assertEqual(t, test.expected, errs[0].Error())
+	}
+
+	rsp := execute(t, runtime, `subscription { counter }`, "", nil)
+	if len(rsp.Errors) != 1 {
+		t.Fatalf("expecting 1 error, found %v", rsp.Errors)
+	}
+	assertEqual(t, "1:1: query error: subscription operations must be executed with Subscribe", rsp.Errors[0].Error())
+}
@@ -2,78 +2,301 @@ package ql
 
 import (
 	"fmt"
-	"reflect"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/leesper/pureql/ql/ast"
 )
 
-// TODO
-func validateSchema(schema *Schema) error {
-	return fmt.Errorf("not implemented")
-}
+// ErrBadSchema for schema failing validation, holding all the errors found.
+type ErrBadSchema []error
 
-// TODO
-func validateDocument(doc *ast.Document) error {
-	return fmt.Errorf("not implemented")
+func (e ErrBadSchema) Error() string {
+	switch len(e) {
+	case 0:
+		return "no errors"
+	case 1:
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0], len(e)-1)
 }
 
-func validateObject(obj *Object) error {
-	err := ruleMustDefineOneOrMoreFields(obj)
-	if err != nil {
-		return err
+// validateSchema validates every type reachable from schema, all errors found
+// are reported together as ErrBadSchema.
+func validateSchema(schema *Schema) error {
+	if schema == nil {
+		return ErrBadSchema{fmt.Errorf("schema is nil")}
 	}
 
-	if err = ruleFieldsMustHaveUniqueNamesWithin(obj); err != nil {
-		return err
+	var errs ErrBadSchema
+	if schema.Qry == nil {
+		errs = append(errs, fmt.Errorf("schema must define a query root type"))
+	}
+
+	typs, err := collectNamedTypes(schema)
+	errs = append(errs, err...)
+
+	for _, typ := range typs {
+		if err := ruleMustHaveValidName(typeName(typ), typeName(typ)); err != nil {
+			errs = append(errs, err)
+		}
+		switch typ := typ.(type) {
+		case *Object:
+			errs = append(errs, validateObject(typ)...)
+		case *Interface:
+			errs = append(errs, validateIface(typ)...)
+		case *Union:
+			errs = append(errs, validateUnion(typ)...)
+		case *Enum:
+			errs = append(errs, validateEnum(typ)...)
+		case *InputObject:
+			errs = append(errs, validateInputObj(typ)...)
+		}
 	}
 
-	if err = ruleMustBeSuperSetOfAllIfaces(obj); err != nil {
-		return err
+	names := map[string]bool{}
+	for _, direct := range builtinDirectives {
+		names[direct.Name] = true
+	}
+	for _, direct := range schema.Directives {
+		if names[direct.Name] {
+			errs = append(errs, fmt.Errorf("schema must contain unique directives but contains multiple directives named @%s", direct.Name))
+		}
+		names[direct.Name] = true
+		errs = append(errs, validateDirective(direct)...)
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
-func validateIface(iface *Interface) error {
-	err := ruleMustDefineOneOrMoreFields(iface)
-	if err != nil {
-		return err
+// collectNamedTypes returns named types reachable from the roots and extra
+// types of schema, sorted by name.
+func collectNamedTypes(schema *Schema) ([]Type, []error) {
+	var errs []error
+	seen := map[string]Type{}
+	var collect func(typ Type)
+	collect = func(typ Type) {
+		if typ == nil {
+			return
+		}
+		typ = namedType(typ)
+		name := typeName(typ)
+		if prev, ok := seen[name]; ok {
+			if prev != typ {
+				errs = append(errs, fmt.Errorf("schema must contain unique named types but contains multiple types named %s", name))
+			}
+			return
+		}
+		seen[name] = typ
+
+		switch typ := typ.(type) {
+		case *Object:
+			for _, iface := range typ.Ifaces {
+				collect(iface)
+			}
+			collectFields(typ.Fields, collect)
+		case *Interface:
+			for _, iface := range typ.Ifaces {
+				collect(iface)
+			}
+			collectFields(typ.Fields, collect)
+		case *Union:
+			for _, t := range typ.Typs {
+				collect(t)
+			}
+		case *InputObject:
+			collectFields(typ.Fields, collect)
+		}
 	}
 
-	if err = ruleFieldsMustHaveUniqueNamesWithin(iface); err != nil {
-		return err
+	if schema.Qry != nil {
+		collect(schema.Qry)
+	}
+	if schema.Mut != nil {
+		collect(schema.Mut)
+	}
+	if schema.Sub != nil {
+		collect(schema.Sub)
+	}
+	for _, typ := range schema.Typs {
+		collect(typ)
+	}
+	for _, direct := range schema.Directives {
+		for _, def := range direct.Defs {
+			if def != nil {
+				collect(def.Typ)
+			}
+		}
 	}
 
-	return nil
+	typs := make([]Type, 0, len(seen))
+	for _, typ := range seen {
+		typs = append(typs, typ)
+	}
+	sort.Slice(typs, func(i, j int) bool {
+		return typeName(typs[i]) < typeName(typs[j])
+	})
+	return typs, errs
 }
 
-func validateUnion(union *Union) error {
-	err := ruleMustDefineOneOrMoreMemberTypes(union)
-	if err != nil {
-		return err
+func collectFields(fields []*Field, collect func(Type)) {
+	for _, f := range fields {
+		if f == nil {
+			continue
+		}
+		collect(f.Typ)
+		for _, def := range f.Defs {
+			if def != nil {
+				collect(def.Typ)
+			}
+		}
 	}
+}
 
-	if err = ruleMustBeAllObjectTypes(union); err != nil {
-		return err
+func validateObject(obj *Object) []error {
+	var errs []error
+	if err := ruleMustDefineOneOrMoreFields(obj); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ruleFieldsMustHaveUniqueNamesWithin(obj); err != nil {
+		errs = append(errs, err)
 	}
+	errs = append(errs, ruleFieldsMustBeValid(obj.Name, obj.Fields)...)
+	errs = append(errs, ruleMustBeSuperSetOfAllIfaces(obj)...)
+	return errs
+}
 
-	return nil
+func validateIface(iface *Interface) []error {
+	var errs []error
+	if err := ruleMustDefineOneOrMoreFields(iface); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ruleFieldsMustHaveUniqueNamesWithin(iface); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, ruleFieldsMustBeValid(iface.Name, iface.Fields)...)
+	if err := ruleMustNotImplementItself(iface); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, ruleMustBeSuperSetOfAllIfaces(iface)...)
+	return errs
 }
 
-func validateInputObj(iobj *InputObject) error {
-	err := ruleMustDefineOneOrMoreFields(iobj)
-	if err != nil {
-		return err
+func validateUnion(union *Union) []error {
+	var errs []error
+	if err := ruleMustDefineOneOrMoreMemberTypes(union); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ruleMustBeAllObjectTypes(union); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func validateEnum(enum *Enum) []error {
+	var errs []error
+	if len(enum.Values) == 0 {
+		errs = append(errs, fmt.Errorf("enum %s must define one or more values", enum.Name))
+	}
+
+	valueCount := map[string]int{}
+	for _, ev := range enum.Values {
+		if err := ruleMustHaveValidName(ev.Name, enum.Name+"."+ev.Name); err != nil {
+			errs = append(errs, err)
+		}
+		switch ev.Name {
+		case "true", "false", "null":
+			errs = append(errs, fmt.Errorf("enum %s cannot include value %s", enum.Name, ev.Name))
+		}
+		valueCount[ev.Name]++
+		if valueCount[ev.Name] == 2 {
+			errs = append(errs, fmt.Errorf("enum %s has multiple values named %s", enum.Name, ev.Name))
+		}
+	}
+	return errs
+}
+
+func validateInputObj(iobj *InputObject) []error {
+	var errs []error
+	if err := ruleMustDefineOneOrMoreFields(iobj); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ruleFieldsMustHaveUniqueNamesWithin(iobj); err != nil {
+		errs = append(errs, err)
+	}
+	for _, f := range iobj.Fields {
+		if err := ruleMustHaveValidName(f.Name, iobj.Name+"."+f.Name); err != nil {
+			errs = append(errs, err)
+		}
+		if f.Deprecated != "" && isNonNull(f.Typ) && f.Defl == nil {
+			errs = append(errs, fmt.Errorf("required input field %s.%s cannot be deprecated", iobj.Name, f.Name))
+		}
 	}
+	if err := ruleFieldOfInputObjectMustBeInputType(iobj); err != nil {
+		errs = append(errs, err)
+	}
+	if err := ruleMustNotReferenceItselfByRequiredFields(iobj); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
 
-	if err = ruleFieldsMustHaveUniqueNamesWithin(iobj); err != nil {
-		return err
+func validateDirective(direct *Directive) []error {
+	var errs []error
+	name := "@" + direct.Name
+	if err := ruleMustHaveValidName(direct.Name, name); err != nil {
+		errs = append(errs, err)
+	}
+	if len(direct.Locs) == 0 {
+		errs = append(errs, fmt.Errorf("directive %s must define one or more locations", name))
+	}
+	for _, loc := range direct.Locs {
+		if indexOf(len(directiveLocations), func(i int) bool { return directiveLocations[i] == loc }) < 0 {
+			errs = append(errs, fmt.Errorf("directive %s has unknown location %s", name, loc))
+		}
 	}
 
-	if err = ruleFieldOfInputObjectMustBeInputType(iobj); err != nil {
-		return err
+	argCount := map[string]int{}
+	for _, def := range direct.Defs {
+		if err := ruleMustHaveValidName(def.Name, name+"("+def.Name+":)"); err != nil {
+			errs = append(errs, err)
+		}
+		argCount[def.Name]++
+		if argCount[def.Name] == 2 {
+			errs = append(errs, fmt.Errorf("directive %s has multiple arguments named %s", name, def.Name))
+		}
+		if def.Typ == nil {
+			errs = append(errs, fmt.Errorf("argument %s(%s:) must have a type", name, def.Name))
+		} else if !isInputType(def.Typ) {
+			errs = append(errs, fmt.Errorf("argument %s(%s:) must be input type, found %s", name, def.Name, typeName(def.Typ)))
+		}
+		if def.Deprecated != "" && isNonNull(def.Typ) && def.Defl == nil {
+			errs = append(errs, fmt.Errorf("required argument %s(%s:) cannot be deprecated", name, def.Name))
+		}
 	}
+	return errs
+}
 
+// ruleMustHaveValidName checks name matches /[_A-Za-z][_0-9A-Za-z]*/ and is
+// not reserved by introspection, qualified is name with its owner for errors.
+func ruleMustHaveValidName(name, qualified string) error {
+	if strings.HasPrefix(name, "__") {
+		return fmt.Errorf("name %s must not begin with \"__\", which is reserved by introspection", qualified)
+	}
+	for i, r := range name {
+		if r == '_' || 'A' <= r && r <= 'Z' || 'a' <= r && r <= 'z' || i > 0 && '0' <= r && r <= '9' {
+			continue
+		}
+		return fmt.Errorf("name %s must match /^[_a-zA-Z][_a-zA-Z0-9]*$/", qualified)
+	}
+	if name == "" {
+		return fmt.Errorf("name %s must not be empty", qualified)
+	}
 	return nil
 }
 
@@ -91,7 +314,7 @@ func ruleMustDefineOneOrMoreFields(typ Type) error {
 	}
 
 	if numOfFields <= 0 {
-		return fmt.Errorf("type %T must define one or more fields", typ)
+		return fmt.Errorf("type %s must define one or more fields", typeName(typ))
 	}
 	return nil
 }
@@ -113,99 +336,241 @@ func ruleFieldsMustHaveUniqueNamesWithin(typ Type) error {
 	for _, f := range fields {
 		fieldCount[f.Name]++
 		if fieldCount[f.Name] > 1 {
-			return fmt.Errorf("type %T has multiple fields named %s", typ, f.Name)
+			return fmt.Errorf("type %s has multiple fields named %s", typeName(typ), f.Name)
 		}
 	}
 	return nil
 }
 
-func ruleMustBeSuperSetOfAllIfaces(obj *Object) error {
-	for _, iface := range obj.Ifaces {
-		if err := ruleMustIncludeFieldOfSameName(obj, iface); err != nil {
-			return err
+// ruleFieldsMustBeValid checks fields of an object or interface have valid
+// names and output types, and their arguments have valid names, unique within
+// the field, and input types.
+func ruleFieldsMustBeValid(typName string, fields []*Field) []error {
+	var errs []error
+	for _, f := range fields {
+		fieldName := typName + "." + f.Name
+		if err := ruleMustHaveValidName(f.Name, fieldName); err != nil {
+			errs = append(errs, err)
+		}
+		if f.Typ == nil {
+			errs = append(errs, fmt.Errorf("field %s must have a type", fieldName))
+		} else if !isOutputType(f.Typ) {
+			errs = append(errs, fmt.Errorf("field %s must be output type, found %s", fieldName, typeName(f.Typ)))
+		}
+
+		argCount := map[string]int{}
+		for _, def := range f.Defs {
+			if err := ruleMustHaveValidName(def.Name, fieldName+"("+def.Name+":)"); err != nil {
+				errs = append(errs, err)
+			}
+			argCount[def.Name]++
+			if argCount[def.Name] == 2 {
+				errs = append(errs, fmt.Errorf("field %s has multiple arguments named %s", fieldName, def.Name))
+			}
+			if def.Typ == nil {
+				errs = append(errs, fmt.Errorf("argument %s(%s:) must have a type", fieldName, def.Name))
+			} else if !isInputType(def.Typ) {
+				errs = append(errs, fmt.Errorf("argument %s(%s:) must be input type, found %s", fieldName, def.Name, typeName(def.Typ)))
+			}
+			if def.Deprecated != "" && isNonNull(def.Typ) && def.Defl == nil {
+				errs = append(errs, fmt.Errorf("required argument %s(%s:) cannot be deprecated", fieldName, def.Name))
+			}
 		}
 	}
-	return nil
+	return errs
 }
 
-func ruleMustIncludeFieldOfSameName(obj *Object, iface *Interface) error {
-	fieldMap := map[string]*Field{}
-	for _, f := range obj.Fields {
-		fieldMap[f.Name] = f
+// ruleMustBeSuperSetOfAllIfaces checks an object or interface implements each
+// of its interfaces once, with their fields, and also implements the
+// interfaces they implement.
+func ruleMustBeSuperSetOfAllIfaces(typ Type) []error {
+	kind, name, ifaces, fields := "object", "", []*Interface(nil), []*Field(nil)
+	switch typ := typ.(type) {
+	case *Object:
+		name, ifaces, fields = typ.Name, typ.Ifaces, typ.Fields
+	case *Interface:
+		kind, name, ifaces, fields = "interface", typ.Name, typ.Ifaces, typ.Fields
+	default:
+		return []error{fmt.Errorf("type %T not applied to this rule", typ)}
 	}
 
-	for _, f := range iface.Fields {
-		if fieldMap[f.Name] == nil {
-			return fmt.Errorf("object %s has no field %s of interface %s", obj.Name, f.Name, iface.Name)
+	implemented := map[string]bool{}
+	for _, iface := range ifaces {
+		implemented[iface.Name] = true
+	}
+
+	var errs []error
+	ifaceCount := map[string]int{}
+	for _, iface := range ifaces {
+		ifaceCount[iface.Name]++
+		if ifaceCount[iface.Name] == 2 {
+			errs = append(errs, fmt.Errorf("%s %s can only implement interface %s once", kind, name, iface.Name))
+			continue
 		}
-		err := ruleMustBeEqualOrSubTypeOf(fieldMap[f.Name].Typ, f.Typ)
-		if err != nil {
-			return err
+		if iface.Name == name { // reported by ruleMustNotImplementItself
+			continue
 		}
-		err = ruleMustIncludeAgrumentOfSameName(fieldMap[f.Name].Defs, f.Defs)
-		if err != nil {
-			return err
+		for _, super := range iface.Ifaces {
+			if super.Name != name && !implemented[super.Name] {
+				errs = append(errs, fmt.Errorf("%s %s must implement interface %s, which is implemented by %s", kind, name, super.Name, iface.Name))
+			}
 		}
+		errs = append(errs, ruleMustIncludeFieldOfSameName(kind, name, fields, iface)...)
 	}
-	return nil
+	return errs
 }
 
-func ruleMustBeEqualOrSubTypeOf(typ, super Type) error {
-	if reflect.DeepEqual(typ, super) {
+// ruleMustNotImplementItself checks iface is not implemented by itself,
+// directly or through the interfaces it implements.
+func ruleMustNotImplementItself(iface *Interface) error {
+	seen := map[*Interface]bool{}
+	var path []string
+	var implements func(typ *Interface) bool
+	implements = func(typ *Interface) bool {
+		for _, super := range typ.Ifaces {
+			if super == iface {
+				return true
+			}
+			if seen[super] {
+				continue
+			}
+			seen[super] = true
+			path = append(path, super.Name)
+			if implements(super) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+
+	if !implements(iface) {
 		return nil
 	}
+	if len(path) == 0 {
+		return fmt.Errorf("interface %s cannot implement itself", iface.Name)
+	}
+	return fmt.Errorf("interface %s cannot implement itself through %s", iface.Name, strings.Join(path, ", "))
+}
 
-	obj, isObject := typ.(*Object)
-	iface, isIface := super.(*Interface)
-	if isObject && isIface {
-		return ruleMustIncludeFieldOfSameName(obj, iface)
+func ruleMustIncludeFieldOfSameName(kind, name string, fields []*Field, iface *Interface) []error {
+	fieldMap := map[string]*Field{}
+	for _, f := range fields {
+		fieldMap[f.Name] = f
 	}
 
-	union, isUnion := super.(*Union)
-	if isObject && isUnion {
-		for _, typ := range union.Typs {
-			if err := ruleMustBeEqualOrSubTypeOf(obj, typ); err == nil {
-				return nil
-			}
+	var errs []error
+	for _, f := range iface.Fields {
+		field := fieldMap[f.Name]
+		if field == nil {
+			errs = append(errs, fmt.Errorf("%s %s has no field %s of interface %s", kind, name, f.Name, iface.Name))
+			continue
+		}
+		if field.Typ == nil || f.Typ == nil {
+			continue
+		}
+		if !ruleMustBeEqualOrSubTypeOf(field.Typ, f.Typ) {
+			errs = append(errs, fmt.Errorf("field %s.%s of interface %s expects type %s, found %s",
+				name, f.Name, iface.Name, typeName(f.Typ), typeName(field.Typ)))
+		}
+		if err := ruleMustIncludeAgrumentOfSameName(name+"."+f.Name, field.Defs, f.Defs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// ruleMustBeEqualOrSubTypeOf reports if a field of typ can implement an
+// interface field of super.
+func ruleMustBeEqualOrSubTypeOf(typ, super Type) bool {
+	if nt, ok := super.(*NonNull); ok {
+		if t, ok := typ.(*NonNull); ok {
+			return ruleMustBeEqualOrSubTypeOf(t.OfType, nt.OfType)
 		}
-		return fmt.Errorf("type %T is not a sub-type of %T", typ, super)
+		return false
+	}
+	if t, ok := typ.(*NonNull); ok {
+		return ruleMustBeEqualOrSubTypeOf(t.OfType, super)
 	}
 
-	lto, isListObject := typ.(*List)
-	lti, isListIface := super.(*List)
-	if isListObject && isListIface {
-		return ruleMustBeEqualOrSubTypeOf(lto.OfType, lti.OfType)
+	if lt, ok := super.(*List); ok {
+		if t, ok := typ.(*List); ok {
+			return ruleMustBeEqualOrSubTypeOf(t.OfType, lt.OfType)
+		}
+		return false
+	}
+	if _, ok := typ.(*List); ok {
+		return false
 	}
 
-	nt, isNonNull := typ.(*NonNull)
-	if isNonNull {
-		return ruleMustBeEqualOrSubTypeOf(nt.OfType, super)
+	if typ == super {
+		return true
 	}
 
-	return nil
+	obj, isObject := typ.(*Object)
+	switch super := super.(type) {
+	case *Interface:
+		var ifaces []*Interface
+		switch typ := typ.(type) {
+		case *Object:
+			ifaces = typ.Ifaces
+		case *Interface:
+			ifaces = typ.Ifaces
+		}
+		for _, iface := range ifaces {
+			if iface == super {
+				return true
+			}
+		}
+	case *Union:
+		if isObject {
+			for _, t := range super.Typs {
+				if t == obj {
+					return true
+				}
+			}
+		}
+	}
+	return false
 }
 
-func ruleMustIncludeAgrumentOfSameName(args []*ArgDef, iargs []*ArgDef) error {
+func ruleMustIncludeAgrumentOfSameName(fieldName string, args []*ArgDef, iargs []*ArgDef) error {
 	argMap := map[string]*ArgDef{}
 	for _, a := range args {
 		argMap[a.Name] = a
 	}
 
+	ifaceArgs := map[string]bool{}
 	for _, a := range iargs {
+		ifaceArgs[a.Name] = true
 		if argMap[a.Name] == nil {
-			return fmt.Errorf("no argument definition found for %s", a.Name)
+			return fmt.Errorf("field %s has no argument %s of interface field", fieldName, a.Name)
+		}
+		if argMap[a.Name].Typ == nil || a.Typ == nil {
+			continue
 		}
-		if !reflect.DeepEqual(argMap[a.Name].Typ, a.Typ) {
-			return fmt.Errorf("expected argument type %T, found %T", a.Typ, argMap[a.Name].Typ)
+		if typeName(argMap[a.Name].Typ) != typeName(a.Typ) {
+			return fmt.Errorf("argument %s(%s:) expects type %s, found %s", fieldName, a.Name, typeName(a.Typ), typeName(argMap[a.Name].Typ))
+		}
+	}
+
+	for _, a := range args {
+		if !ifaceArgs[a.Name] && isNonNull(a.Typ) && a.Defl == nil {
+			return fmt.Errorf("argument %s(%s:) must not be required as it is not defined by interface field", fieldName, a.Name)
 		}
 	}
 	return nil
 }
 
 func ruleMustBeAllObjectTypes(uni *Union) error {
+	typCount := map[Type]int{}
 	for _, typ := range uni.Typs {
 		if _, ok := typ.(*Object); !ok {
-			return fmt.Errorf("type %T not an object type", typ)
+			return fmt.Errorf("union %s can only include object types, found %s", uni.Name, typeName(typ))
+		}
+		typCount[typ]++
+		if typCount[typ] > 1 {
+			return fmt.Errorf("union %s can only include type %s once", uni.Name, typeName(typ))
 		}
 	}
 	return nil
@@ -213,19 +578,980 @@ func ruleMustBeAllObjectTypes(uni *Union) error {
 
 func ruleMustDefineOneOrMoreMemberTypes(uni *Union) error {
 	if len(uni.Typs) <= 0 {
-		return fmt.Errorf("union must define at least one type")
+		return fmt.Errorf("union %s must define at least one type", uni.Name)
 	}
 	return nil
 }
 
 func ruleFieldOfInputObjectMustBeInputType(io *InputObject) error {
 	for _, f := range io.Fields {
-		switch f.Typ.(type) {
-		case *Scalar, *Enum, *InputObject:
-			// do nothing
-		default:
-			return fmt.Errorf("unexpected type %T, input type wanted", f.Typ)
+		if f.Typ == nil || !isInputType(f.Typ) {
+			var found = "nothing"
+			if f.Typ != nil {
+				found = typeName(f.Typ)
+			}
+			return fmt.Errorf("field %s.%s must be input type, found %s", io.Name, f.Name, found)
+		}
+	}
+	return nil
+}
+
+// ruleMustNotReferenceItselfByRequiredFields checks io cannot be referenced by
+// a chain of required non-list fields without defaults, which no finite value
+// could satisfy.
+func ruleMustNotReferenceItselfByRequiredFields(io *InputObject) error {
+	var path []string
+	visited := map[*InputObject]bool{}
+	var visit func(curr *InputObject) bool
+	visit = func(curr *InputObject) bool {
+		visited[curr] = true
+		for _, f := range curr.Fields {
+			nt, ok := f.Typ.(*NonNull)
+			if !ok || f.Defl != nil {
+				continue
+			}
+			next, ok := nt.OfType.(*InputObject)
+			if !ok {
+				continue
+			}
+			path = append(path, f.Name)
+			if next == io {
+				return true
+			}
+			if !visited[next] && visit(next) {
+				return true
+			}
+			path = path[:len(path)-1]
+		}
+		return false
+	}
+
+	if visit(io) {
+		return fmt.Errorf("input object %s cannot reference itself through required fields %s", io.Name, strings.Join(path, "."))
+	}
+	return nil
+}
+
+// validateDocument validates doc against the types in runtime, all violations
+// found are returned as *Error.
+func validateDocument(runtime *Runtime, fset *token.FileSet, doc *ast.Document) []error {
+	v := &validator{
+		runtime:   runtime,
+		fset:      fset,
+		fragments: map[string]*ast.FragmentDefinition{},
+		scopes:    map[ast.Definition]*scope{},
+		reported:  map[string]bool{},
+		compared:  map[fieldPair]bool{},
+		skipped:   map[*ast.OperationDefinition]bool{},
+	}
+	v.validate(doc)
+	return v.errs
+}
+
+// scope records variable usages and fragment spreads in an operation or a
+// fragment definition.
+type scope struct {
+	usages  []*varUsage
+	spreads []*ast.FragmentSpread
+}
+
+// varUsage is a variable used in a position of type typ.
+type varUsage struct {
+	node       *ast.Variable
+	typ        Type
+	hasDefault bool
+}
+
+type validator struct {
+	runtime   *Runtime
+	fset      *token.FileSet
+	fragments map[string]*ast.FragmentDefinition
+	scopes    map[ast.Definition]*scope
+	curr      *scope
+	reported  map[string]bool
+	compared  map[fieldPair]bool
+	skipped   map[*ast.OperationDefinition]bool // operations without root type
+	errs      []error
+}
+
+func (v *validator) errorf(node ast.Node, format string, args ...interface{}) {
+	err := &Error{
+		Message:    fmt.Sprintf(format, args...),
+		Locations:  locations(v.fset, node),
+		Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED"},
+	}
+	if v.reported[err.Error()] {
+		return
+	}
+	v.reported[err.Error()] = true
+	v.errs = append(v.errs, err)
+}
+
+func (v *validator) validate(doc *ast.Document) {
+	if doc == nil || len(doc.Defs) == 0 {
+		v.errorf(nil, "document must contain at least one operation")
+		return
+	}
+
+	var opers []*ast.OperationDefinition
+	operNames := map[string]bool{}
+	for _, def := range doc.Defs {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			opers = append(opers, def)
+			name := def.Name.Text
+			if name == "" {
+				continue
+			}
+			if operNames[name] {
+				v.errorf(def, "there can be only one operation named %q", name)
+			}
+			operNames[name] = true
+		case *ast.FragmentDefinition:
+			name := def.Name.Text
+			if _, ok := v.fragments[name]; ok {
+				v.errorf(def, "there can be only one fragment named %q", name)
+				continue
+			}
+			v.fragments[name] = def
+		}
+	}
+
+	for _, oper := range opers {
+		if oper.Name.Text == "" && len(opers) > 1 {
+			v.errorf(oper, "this anonymous operation must be the only defined operation")
+		}
+	}
+
+	for _, def := range doc.Defs {
+		v.curr = &scope{}
+		v.scopes[def] = v.curr
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			v.validateOperation(def)
+		case *ast.FragmentDefinition:
+			v.validateFragmentDefinition(def)
+		}
+	}
+
+	v.validateFragmentCycles(doc)
+
+	used := map[string]bool{}
+	for _, oper := range opers {
+		fragments := v.recursivelyReferencedFragments(oper)
+		for _, frag := range fragments {
+			used[frag.Name.Text] = true
+		}
+		v.validateVariableUsages(oper, fragments)
+	}
+	for _, def := range doc.Defs {
+		if frag, ok := def.(*ast.FragmentDefinition); ok && !used[frag.Name.Text] {
+			v.errorf(frag, "fragment %q is never used", frag.Name.Text)
+		}
+	}
+}
+
+func (v *validator) validateOperation(oper *ast.OperationDefinition) {
+	var root *Object
+	if v.runtime.Schema != nil {
+		switch oper.OperType.Text {
+		case "", ast.Stringify(ast.QUERY):
+			root = v.runtime.Schema.Qry
+		case ast.Stringify(ast.MUTATION):
+			root = v.runtime.Schema.Mut
+		case ast.Stringify(ast.SUBSCRIPTION):
+			root = v.runtime.Schema.Sub
+		}
+	}
+	if root == nil {
+		operType := oper.OperType.Text
+		if operType == "" {
+			operType = ast.Stringify(ast.QUERY)
+		}
+		v.errorf(oper, "schema is not configured for %s operations", operType)
+		v.skipped[oper] = true
+	}
+
+	if oper.VarDefns != nil {
+		varNames := map[string]bool{}
+		for _, varDefn := range oper.VarDefns.VarDefns {
+			name := varDefn.Var.Name.Text
+			if varNames[name] {
+				v.errorf(varDefn, "there can be only one variable named %q", name)
+			}
+			varNames[name] = true
+			if varDefn.Directs != nil {
+				v.validateDirectives(varDefn.Directs, "VARIABLE_DEFINITION")
+			}
+
+			typ := v.runtime.resolveASTType(varDefn.Typ)
+			if typ == nil {
+				v.errorf(varDefn.Typ, "unknown type %q", formatASTType(varDefn.Typ))
+				continue
+			}
+			if !isInputType(typ) {
+				v.errorf(varDefn.Typ, "variable %q cannot be non-input type %q", "$"+name, typeName(typ))
+				continue
+			}
+			if varDefn.DeflVal != nil {
+				v.validateValue(varDefn.DeflVal.Val, typ, false)
+			}
+		}
+	}
+
+	if oper.Directs != nil {
+		v.validateDirectives(oper.Directs, operationLocation(oper))
+	}
+
+	if root == nil {
+		// the fields are unknown, only the fragments spread are recorded so
+		// that they are not reported unused, and neither are the variables
+		ast.Inspect(oper.SelSet, func(node ast.Node) bool {
+			if spread, ok := node.(*ast.FragmentSpread); ok {
+				v.curr.spreads = append(v.curr.spreads, spread)
+			}
+			return true
+		})
+		return
+	}
+	v.validateSelectionSet(root, oper.SelSet)
+	if oper.OperType.Text == ast.Stringify(ast.SUBSCRIPTION) {
+		v.validateSingleRootField(root, oper)
+	}
+}
+
+// validateSingleRootField checks subscription oper selects exactly one root
+// field, which is not an introspection field.
+func (v *validator) validateSingleRootField(root *Object, oper *ast.OperationDefinition) {
+	name := "anonymous subscription"
+	if oper.Name.Text != "" {
+		name = fmt.Sprintf("subscription %q", oper.Name.Text)
+	}
+
+	fields := map[string][]fieldAndParent{}
+	var keys []string
+	v.collectFieldsAndParents(root, oper.SelSet, fields, &keys, map[string]bool{})
+	if len(keys) != 1 {
+		v.errorf(oper, "%s must select only one top level field", name)
+	}
+	for _, key := range keys {
+		for _, f := range fields[key] {
+			if strings.HasPrefix(f.field.Name.Text, "__") {
+				v.errorf(f.field, "%s must not select an introspection top level field", name)
+			}
+		}
+	}
+}
+
+func operationLocation(oper *ast.OperationDefinition) string {
+	switch oper.OperType.Text {
+	case ast.Stringify(ast.MUTATION):
+		return "MUTATION"
+	case ast.Stringify(ast.SUBSCRIPTION):
+		return "SUBSCRIPTION"
+	default:
+		return "QUERY"
+	}
+}
+
+func (v *validator) validateFragmentDefinition(frag *ast.FragmentDefinition) {
+	typ := v.typeCondition(frag.TypeCond, "fragment %q", frag.Name.Text)
+	if frag.Directs != nil {
+		v.validateDirectives(frag.Directs, "FRAGMENT_DEFINITION")
+	}
+	if typ != nil {
+		v.validateSelectionSet(typ, frag.SelSet)
+	}
+}
+
+// typeCondition returns the composite type of typCond, or nil if it is not
+// found or not composite.
+func (v *validator) typeCondition(typCond *ast.TypeCondition, format string, args ...interface{}) Type {
+	name := typCond.NamedTyp.Name.Text
+	typ := v.runtime.findType(name)
+	if typ == nil {
+		v.errorf(typCond.NamedTyp, "unknown type %q", name)
+		return nil
+	}
+	if !isCompositeType(typ) {
+		v.errorf(typCond.NamedTyp, "%s cannot condition on non composite type %q", fmt.Sprintf(format, args...), name)
+		return nil
+	}
+	return typ
+}
+
+func (v *validator) validateSelectionSet(parent Type, selSet *ast.SelectionSet) {
+	for _, sel := range selSet.Sels {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			v.validateField(parent, sel)
+		case *ast.FragmentSpread:
+			v.validateFragmentSpread(parent, sel)
+		case *ast.InlineFragment:
+			v.validateInlineFragment(parent, sel)
+		}
+	}
+	v.validateFieldsCanMerge(parent, selSet)
+}
+
+func (v *validator) validateField(parent Type, field *ast.Field) {
+	if field.Directs != nil {
+		v.validateDirectives(field.Directs, "FIELD")
+	}
+
+	fieldDef := v.fieldDef(parent, field.Name.Text)
+	if fieldDef == nil {
+		v.errorf(field, "cannot query field %q on type %q", field.Name.Text, typeName(parent))
+		return
+	}
+
+	v.validateArguments(field.Args, fieldDef.Defs, fmt.Sprintf("field %q", field.Name.Text), field)
+
+	typ := namedType(fieldDef.Typ)
+	if isLeafType(typ) {
+		if field.SelSet != nil {
+			v.errorf(field.SelSet, "field %q must not have a selection since type %q has no subfields", field.Name.Text, typeName(fieldDef.Typ))
+		}
+		return
+	}
+	if field.SelSet == nil {
+		v.errorf(field, "field %q of type %q must have a selection of subfields", field.Name.Text, typeName(fieldDef.Typ))
+		return
+	}
+	v.validateSelectionSet(typ, field.SelSet)
+}
+
+// fieldDef returns the definition of field name in parent, or nil if not found.
+func (v *validator) fieldDef(parent Type, name string) *Field {
+	for _, f := range fieldsOf(parent) {
+		if f.Name == name {
+			return f
+		}
+	}
+	return v.runtime.metaField(parent, name)
+}
+
+func (v *validator) validateFragmentSpread(parent Type, spread *ast.FragmentSpread) {
+	v.curr.spreads = append(v.curr.spreads, spread)
+	if spread.Directs != nil {
+		v.validateDirectives(spread.Directs, "FRAGMENT_SPREAD")
+	}
+
+	frag, ok := v.fragments[spread.Name.Text]
+	if !ok {
+		v.errorf(spread, "unknown fragment %q", spread.Name.Text)
+		return
+	}
+
+	fragType := v.runtime.findType(frag.TypeCond.NamedTyp.Name.Text)
+	if fragType == nil || !isCompositeType(fragType) {
+		return // reported in fragment definition
+	}
+	if !v.typesOverlap(parent, fragType) {
+		v.errorf(spread, "fragment %q cannot be spread here as objects of type %q can never be of type %q",
+			spread.Name.Text, typeName(parent), typeName(fragType))
+	}
+}
+
+func (v *validator) validateInlineFragment(parent Type, frag *ast.InlineFragment) {
+	if frag.Directs != nil {
+		v.validateDirectives(frag.Directs, "INLINE_FRAGMENT")
+	}
+
+	typ := parent
+	if frag.TypeCond != nil {
+		typ = v.typeCondition(frag.TypeCond, "fragment")
+		if typ == nil {
+			return
+		}
+		if !v.typesOverlap(parent, typ) {
+			v.errorf(frag, "fragment cannot be spread here as objects of type %q can never be of type %q",
+				typeName(parent), typeName(typ))
+		}
+	}
+	v.validateSelectionSet(typ, frag.SelSet)
+}
+
+func (v *validator) typesOverlap(a, b Type) bool {
+	if a == b {
+		return true
+	}
+	possible := map[*Object]bool{}
+	for _, obj := range v.runtime.possibleTypes(a) {
+		possible[obj] = true
+	}
+	for _, obj := range v.runtime.possibleTypes(b) {
+		if possible[obj] {
+			return true
+		}
+	}
+	return false
+}
+
+// validateArguments checks arguments are defined in defs, unique, of correct
+// types, and all the required arguments are provided.
+func (v *validator) validateArguments(args *ast.Arguments, defs []*ArgDef, owner string, node ast.Node) {
+	provided := map[string]*ast.Argument{}
+	if args != nil {
+		for _, arg := range args.Args {
+			name := arg.Name.Text
+			if _, ok := provided[name]; ok {
+				v.errorf(arg, "there can be only one argument named %q", name)
+				continue
+			}
+			provided[name] = arg
+
+			def := findArgDef(defs, name)
+			if def == nil {
+				v.errorf(arg, "unknown argument %q on %s", name, owner)
+				continue
+			}
+			v.validateValue(arg.Val, def.Typ, def.Defl != nil)
+		}
+	}
+
+	for _, def := range defs {
+		if _, ok := provided[def.Name]; ok {
+			continue
+		}
+		if isNonNull(def.Typ) && def.Defl == nil {
+			v.errorf(node, "%s argument %q of type %q is required, but it was not provided", owner, def.Name, typeName(def.Typ))
+		}
+	}
+}
+
+func findArgDef(defs []*ArgDef, name string) *ArgDef {
+	for _, def := range defs {
+		if def.Name == name {
+			return def
 		}
 	}
 	return nil
 }
+
+func (v *validator) validateDirectives(directs *ast.Directives, loc string) {
+	seen := map[string]bool{}
+	for _, direct := range directs.Directs {
+		name := direct.Name.Text
+		def := v.runtime.Directives[name]
+		if def == nil {
+			v.errorf(direct, "unknown directive %q", "@"+name)
+			continue
+		}
+		if seen[name] && !def.Repeatable {
+			v.errorf(direct, "the directive %q can only be used once at this location", "@"+name)
+		}
+		seen[name] = true
+
+		allowed := false
+		for _, l := range def.Locs {
+			if l == loc {
+				allowed = true
+			}
+		}
+		if !allowed {
+			v.errorf(direct, "directive %q may not be used on %s", "@"+name, loc)
+		}
+		v.validateArguments(direct.Args, def.Defs, fmt.Sprintf("directive %q", "@"+name), direct)
+	}
+}
+
+// validateValue checks val can be coerced into typ. hasDefault reports if the
+// position val is used in has a default value.
+func (v *validator) validateValue(val ast.Value, typ Type, hasDefault bool) {
+	if variable, ok := val.(*ast.Variable); ok {
+		v.curr.usages = append(v.curr.usages, &varUsage{node: variable, typ: typ, hasDefault: hasDefault})
+		return
+	}
+
+	if nn, ok := typ.(*NonNull); ok {
+		if isNullValue(val) {
+			v.errorf(val, "expected value of type %q, found null", typeName(typ))
+			return
+		}
+		v.validateValue(val, nn.OfType, false)
+		return
+	}
+
+	if isNullValue(val) {
+		return
+	}
+
+	switch typ := typ.(type) {
+	case *List:
+		if list, ok := val.(*ast.ListValue); ok {
+			for _, item := range list.Vals {
+				v.validateValue(item, typ.OfType, false)
+			}
+			return
+		}
+		v.validateValue(val, typ.OfType, false)
+	case *InputObject:
+		obj, ok := val.(*ast.ObjectValue)
+		if !ok {
+			v.errorf(val, "expected value of type %q, found %s", typ.Name, formatASTValue(val))
+			return
+		}
+		provided := map[string]bool{}
+		for _, f := range obj.ObjFields {
+			name := f.Name.Text
+			if provided[name] {
+				v.errorf(f, "there can be only one input field named %q", name)
+				continue
+			}
+			provided[name] = true
+
+			var fieldDef *Field
+			for _, fd := range typ.Fields {
+				if fd.Name == name {
+					fieldDef = fd
+				}
+			}
+			if fieldDef == nil {
+				v.errorf(f, "field %q is not defined by type %q", name, typ.Name)
+				continue
+			}
+			v.validateValue(f.Val, fieldDef.Typ, fieldDef.Defl != nil)
+		}
+		for _, fd := range typ.Fields {
+			if !provided[fd.Name] && isNonNull(fd.Typ) && fd.Defl == nil {
+				v.errorf(val, "field %q of required type %q was not provided", typ.Name+"."+fd.Name, typeName(fd.Typ))
+			}
+		}
+	case *Enum:
+		if name, ok := val.(*ast.NameValue); ok && !isBooleanValue(name) {
+			for _, ev := range typ.Values {
+				if ev.Name == name.Val.Text {
+					return
+				}
+			}
+			v.errorf(val, "value %q does not exist in %q enum", name.Val.Text, typ.Name)
+			return
+		}
+		v.errorf(val, "enum %q cannot represent non-enum value: %s", typ.Name, formatASTValue(val))
+	case *Scalar:
+		if !isValidScalarLiteral(typ, val) {
+			v.errorf(val, "expected value of type %q, found %s", typ.Name, formatASTValue(val))
+		}
+	}
+}
+
+func isNullValue(val ast.Value) bool {
+	name, ok := val.(*ast.NameValue)
+	return ok && name.Val.Text == "null"
+}
+
+func isBooleanValue(name *ast.NameValue) bool {
+	return name.Val.Text == "true" || name.Val.Text == "false" || name.Val.Text == "null"
+}
+
+// isValidScalarLiteral reports if val is a literal of built-in scalars, values
+// of custom scalars are always valid.
+func isValidScalarLiteral(scalar *Scalar, val ast.Value) bool {
+	switch val.(type) {
+	case *ast.ListValue, *ast.ObjectValue:
+		return scalar != Int && scalar != Float && scalar != String && scalar != Boolean && scalar != ID
+	}
+
+	var kind ast.Kind
+	var text string
+	switch val := val.(type) {
+	case *ast.LiteralValue:
+		kind, text = val.Val.Kind, val.Val.Text
+	case *ast.NameValue:
+		kind, text = val.Val.Kind, val.Val.Text
+	}
+
+	switch scalar {
+	case Int:
+		if kind != ast.INT {
+			return false
+		}
+		_, err := strconv.ParseInt(text, 10, 32)
+		return err == nil
+	case Float:
+		return kind == ast.INT || kind == ast.FLOAT
+	case String:
+		return kind == ast.STRING
+	case Boolean:
+		return kind == ast.NAME && (text == "true" || text == "false")
+	case ID:
+		return kind == ast.STRING || kind == ast.INT
+	default:
+		return true
+	}
+}
+
+func (v *validator) validateFragmentCycles(doc *ast.Document) {
+	visited := map[string]bool{}
+	for _, def := range doc.Defs {
+		frag, ok := def.(*ast.FragmentDefinition)
+		if !ok || visited[frag.Name.Text] || v.fragments[frag.Name.Text] != frag {
+			continue
+		}
+		v.detectCycle(frag, visited, nil, map[string]int{})
+	}
+}
+
+// detectCycle walks fragment spreads depth-first, path holds the spreads which
+// lead to frag and pathIndex the index of each fragment on path.
+func (v *validator) detectCycle(frag *ast.FragmentDefinition, visited map[string]bool, path []*ast.FragmentSpread, pathIndex map[string]int) {
+	name := frag.Name.Text
+	visited[name] = true
+	pathIndex[name] = len(path)
+
+	for _, spread := range v.scopes[frag].spreads {
+		spreadName := spread.Name.Text
+		if idx, ok := pathIndex[spreadName]; ok {
+			var via []string
+			for _, s := range path[idx:] {
+				via = append(via, strconv.Quote(s.Name.Text))
+			}
+			msg := fmt.Sprintf("cannot spread fragment %q within itself", spreadName)
+			if len(via) > 0 {
+				msg = fmt.Sprintf("cannot spread fragment %q within itself via %s", spreadName, strings.Join(via, ", "))
+			}
+			v.errorf(spread, "%s", msg)
+			continue
+		}
+		if visited[spreadName] {
+			continue
+		}
+		if next, ok := v.fragments[spreadName]; ok {
+			v.detectCycle(next, visited, append(path, spread), pathIndex)
+		}
+	}
+
+	delete(pathIndex, name)
+}
+
+// recursivelyReferencedFragments returns the fragments spread in oper, directly
+// or through other fragments.
+func (v *validator) recursivelyReferencedFragments(oper *ast.OperationDefinition) []*ast.FragmentDefinition {
+	var frags []*ast.FragmentDefinition
+	collected := map[string]bool{}
+	toVisit := []*scope{v.scopes[oper]}
+	for len(toVisit) > 0 {
+		s := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		for _, spread := range s.spreads {
+			name := spread.Name.Text
+			if collected[name] {
+				continue
+			}
+			collected[name] = true
+			if frag, ok := v.fragments[name]; ok {
+				frags = append(frags, frag)
+				toVisit = append(toVisit, v.scopes[frag])
+			}
+		}
+	}
+	return frags
+}
+
+func (v *validator) validateVariableUsages(oper *ast.OperationDefinition, frags []*ast.FragmentDefinition) {
+	varDefns := map[string]*ast.VariableDefinition{}
+	if oper.VarDefns != nil {
+		for _, varDefn := range oper.VarDefns.VarDefns {
+			varDefns[varDefn.Var.Name.Text] = varDefn
+		}
+	}
+
+	usages := v.scopes[oper].usages
+	for _, frag := range frags {
+		usages = append(usages, v.scopes[frag].usages...)
+	}
+
+	used := map[string]bool{}
+	for _, usage := range usages {
+		name := usage.node.Name.Text
+		used[name] = true
+
+		varDefn, ok := varDefns[name]
+		if !ok {
+			if oper.Name.Text != "" {
+				v.errorf(usage.node, "variable %q is not defined by operation %q", "$"+name, oper.Name.Text)
+			} else {
+				v.errorf(usage.node, "variable %q is not defined", "$"+name)
+			}
+			continue
+		}
+
+		varType := v.runtime.resolveASTType(varDefn.Typ)
+		if varType == nil || usage.typ == nil {
+			continue
+		}
+		hasNonNullDefault := varDefn.DeflVal != nil && !isNullValue(varDefn.DeflVal.Val)
+		if !isVariableUsageAllowed(varType, hasNonNullDefault, usage.typ, usage.hasDefault) {
+			v.errorf(usage.node, "variable %q of type %q used in position expecting type %q",
+				"$"+name, typeName(varType), typeName(usage.typ))
+		}
+	}
+
+	if oper.VarDefns == nil || v.skipped[oper] {
+		return
+	}
+	for _, varDefn := range oper.VarDefns.VarDefns {
+		name := varDefn.Var.Name.Text
+		if used[name] {
+			continue
+		}
+		if oper.Name.Text != "" {
+			v.errorf(varDefn, "variable %q is never used in operation %q", "$"+name, oper.Name.Text)
+		} else {
+			v.errorf(varDefn, "variable %q is never used", "$"+name)
+		}
+	}
+}
+
+func isVariableUsageAllowed(varType Type, hasNonNullDefault bool, locType Type, locHasDefault bool) bool {
+	if nn, ok := locType.(*NonNull); ok && !isNonNull(varType) {
+		if !hasNonNullDefault && !locHasDefault {
+			return false
+		}
+		return isTypeCompatible(varType, nn.OfType)
+	}
+	return isTypeCompatible(varType, locType)
+}
+
+// isTypeCompatible reports if a variable of varType can be used where locType
+// is expected.
+func isTypeCompatible(varType, locType Type) bool {
+	if locNN, ok := locType.(*NonNull); ok {
+		varNN, ok := varType.(*NonNull)
+		if !ok {
+			return false
+		}
+		return isTypeCompatible(varNN.OfType, locNN.OfType)
+	}
+	if varNN, ok := varType.(*NonNull); ok {
+		return isTypeCompatible(varNN.OfType, locType)
+	}
+	if locList, ok := locType.(*List); ok {
+		varList, ok := varType.(*List)
+		if !ok {
+			return false
+		}
+		return isTypeCompatible(varList.OfType, locList.OfType)
+	}
+	if _, ok := varType.(*List); ok {
+		return false
+	}
+	return varType == locType
+}
+
+// fieldAndParent is a field node selected on parent type, with its definition
+// if there is one.
+type fieldAndParent struct {
+	parent Type
+	field  *ast.Field
+	def    *Field
+}
+
+// fieldPair is a pair of fields compared for merging, the selection sets of
+// nested fragments are compared through the same pairs of fields many times.
+type fieldPair struct {
+	a, b *ast.Field
+}
+
+// alreadyCompared reports if fields a and b have been compared, and records
+// them as compared otherwise. Comparing them not exclusively covers comparing
+// them exclusively, but not the other way around.
+func (v *validator) alreadyCompared(a, b *ast.Field, exclusive bool) bool {
+	for _, pair := range []fieldPair{{a, b}, {b, a}} {
+		if excl, ok := v.compared[pair]; ok && (!excl || exclusive) {
+			return true
+		}
+	}
+	v.compared[fieldPair{a, b}] = exclusive
+	return false
+}
+
+// validateFieldsCanMerge checks fields sharing the same response key in selSet
+// can be merged without ambiguity.
+func (v *validator) validateFieldsCanMerge(parent Type, selSet *ast.SelectionSet) {
+	fields := map[string][]fieldAndParent{}
+	var keys []string
+	v.collectFieldsAndParents(parent, selSet, fields, &keys, map[string]bool{})
+	v.findConflicts(fields, keys, false)
+}
+
+func (v *validator) collectFieldsAndParents(parent Type, selSet *ast.SelectionSet, fields map[string][]fieldAndParent, keys *[]string, visited map[string]bool) {
+	for _, sel := range selSet.Sels {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			key := responseKey(sel)
+			if _, ok := fields[key]; !ok {
+				*keys = append(*keys, key)
+			}
+			fields[key] = append(fields[key], fieldAndParent{parent, sel, v.fieldDef(parent, sel.Name.Text)})
+		case *ast.InlineFragment:
+			typ := parent
+			if sel.TypeCond != nil {
+				typ = v.runtime.findType(sel.TypeCond.NamedTyp.Name.Text)
+			}
+			if typ != nil {
+				v.collectFieldsAndParents(typ, sel.SelSet, fields, keys, visited)
+			}
+		case *ast.FragmentSpread:
+			name := sel.Name.Text
+			frag, ok := v.fragments[name]
+			if !ok || visited[name] {
+				continue
+			}
+			visited[name] = true
+			if typ := v.runtime.findType(frag.TypeCond.NamedTyp.Name.Text); typ != nil {
+				v.collectFieldsAndParents(typ, frag.SelSet, fields, keys, visited)
+			}
+		}
+	}
+}
+
+func (v *validator) findConflicts(fields map[string][]fieldAndParent, keys []string, exclusive bool) {
+	for _, key := range keys {
+		fs := fields[key]
+		for i := 0; i < len(fs); i++ {
+			for j := i + 1; j < len(fs); j++ {
+				v.findConflict(key, fs[i], fs[j], exclusive)
+			}
+		}
+	}
+}
+
+func (v *validator) findConflict(key string, a, b fieldAndParent, exclusive bool) {
+	_, aIsObj := a.parent.(*Object)
+	_, bIsObj := b.parent.(*Object)
+	exclusive = exclusive || (a.parent != b.parent && aIsObj && bIsObj)
+	if v.alreadyCompared(a.field, b.field, exclusive) {
+		return
+	}
+
+	if !exclusive {
+		if a.field.Name.Text != b.field.Name.Text {
+			v.errorf(b.field, "fields %q conflict because %q and %q are different fields",
+				key, a.field.Name.Text, b.field.Name.Text)
+			return
+		}
+		if !sameArguments(a.field.Args, b.field.Args) {
+			v.errorf(b.field, "fields %q conflict because they have differing arguments", key)
+			return
+		}
+	}
+
+	if a.def != nil && b.def != nil && !sameResponseShape(a.def.Typ, b.def.Typ) {
+		v.errorf(b.field, "fields %q conflict because they return conflicting types %q and %q",
+			key, typeName(a.def.Typ), typeName(b.def.Typ))
+		return
+	}
+
+	if a.field.SelSet != nil && b.field.SelSet != nil && a.def != nil && b.def != nil {
+		fields := map[string][]fieldAndParent{}
+		var keys []string
+		visited := map[string]bool{}
+		v.collectFieldsAndParents(namedType(a.def.Typ), a.field.SelSet, fields, &keys, visited)
+		v.collectFieldsAndParents(namedType(b.def.Typ), b.field.SelSet, fields, &keys, visited)
+		v.findConflicts(fields, keys, exclusive)
+	}
+}
+
+func sameResponseShape(a, b Type) bool {
+	aNN, aIsNN := a.(*NonNull)
+	bNN, bIsNN := b.(*NonNull)
+	if aIsNN != bIsNN {
+		return false
+	}
+	if aIsNN {
+		return sameResponseShape(aNN.OfType, bNN.OfType)
+	}
+
+	aList, aIsList := a.(*List)
+	bList, bIsList := b.(*List)
+	if aIsList != bIsList {
+		return false
+	}
+	if aIsList {
+		return sameResponseShape(aList.OfType, bList.OfType)
+	}
+
+	if isLeafType(a) || isLeafType(b) {
+		return a == b
+	}
+	return true
+}
+
+func sameArguments(a, b *ast.Arguments) bool {
+	var aArgs, bArgs []*ast.Argument
+	if a != nil {
+		aArgs = a.Args
+	}
+	if b != nil {
+		bArgs = b.Args
+	}
+	if len(aArgs) != len(bArgs) {
+		return false
+	}
+	for _, aArg := range aArgs {
+		found := false
+		for _, bArg := range bArgs {
+			if aArg.Name.Text == bArg.Name.Text {
+				found = sameValue(aArg.Val, bArg.Val)
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func sameValue(a, b ast.Value) bool {
+	return formatASTValue(a) == formatASTValue(b)
+}
+
+// formatASTValue returns val in GraphQL syntax.
+func formatASTValue(val ast.Value) string {
+	switch val := val.(type) {
+	case *ast.Variable:
+		return "$" + val.Name.Text
+	case *ast.LiteralValue:
+		if val.Val.Kind == ast.STRING {
+			return ast.QuoteString(val.Val.Text)
+		}
+		return val.Val.Text
+	case *ast.NameValue:
+		return val.Val.Text
+	case *ast.ListValue:
+		var items []string
+		for _, item := range val.Vals {
+			items = append(items, formatASTValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *ast.ObjectValue:
+		var fields []string
+		for _, f := range val.ObjFields {
+			fields = append(fields, f.Name.Text+": "+formatASTValue(f.Val))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	default:
+		return ""
+	}
+}
+
+// formatASTType returns typ in GraphQL syntax.
+func formatASTType(typ ast.Type) string {
+	switch typ := typ.(type) {
+	case *ast.NamedType:
+		if typ.NonNull {
+			return typ.Name.Text + "!"
+		}
+		return typ.Name.Text
+	case *ast.ListType:
+		if typ.NonNull {
+			return "[" + formatASTType(typ.Typ) + "]!"
+		}
+		return "[" + formatASTType(typ.Typ) + "]"
+	default:
+		return ""
+	}
+}
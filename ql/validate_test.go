@@ -0,0 +1,432 @@
+package ql
+
+import (
+	"fmt"
+	"go/token"
+	"testing"
+)
+
+const validateSDL = `
+interface Pet {
+  name: String
+}
+
+type Dog implements Pet {
+  name: String
+  barks: Boolean
+  owner: Human
+  doesKnowCommand(command: DogCommand!): Boolean
+}
+
+type Cat implements Pet {
+  name: String
+  meows: Boolean
+}
+
+type Human {
+  name(surname: Boolean): String
+  pets: [Pet]
+}
+
+union CatOrDog = Cat | Dog
+
+enum DogCommand {
+  SIT
+  HEEL
+}
+
+input Filter {
+  name: String!
+  limit: Int = 10
+}
+
+type Query {
+  dog: Dog
+  pet: Pet
+  catOrDog: CatOrDog
+  human(id: ID!): Human
+  pets(filter: Filter, ids: [ID!]): [Pet]
+  score(ratio: Float = 1): Int
+}
+
+directive @trace(level: Int = 0) repeatable on QUERY | FIELD | VARIABLE_DEFINITION
+`
+
+func validate(t *testing.T, document string) []error {
+	fset := token.NewFileSet()
+	runtime := newRuntime(buildSchema(t, validateSDL))
+	return validateDocument(runtime, fset, parseDocument(t, fset, document))
+}
+
+func TestValidateDocument(t *testing.T) {
+	valid := []string{
+		`{ dog { name barks } }`,
+		`query Q($cmd: DogCommand!) { dog { doesKnowCommand(command: $cmd) } }`,
+		`{ pet { __typename name ... on Dog { barks } ...catFields } } fragment catFields on Cat { meows }`,
+		`{ catOrDog { __typename ... on Dog { name } ... on Cat { name } } }`,
+		`{ pets(filter: {name: "a"}, ids: 1) { name } }`,
+		`query Q($id: ID = 1) { human(id: $id) { name } }`,
+		`{ dog { name @skip(if: true) barks @include(if: false) } }`,
+		`{ pet { ... on Dog { name: barks } ... on Cat { name: meows } } }`,
+		`{ score(ratio: 2) }`,
+		`query A { dog { name } } query B { pet { name } }`,
+		`query @trace { dog @trace @trace(level: 1) { name } }`,
+		`query Q($id: ID = 1 @trace) { human(id: $id) { name } }`,
+	}
+	for _, doc := range valid {
+		if errs := validate(t, doc); len(errs) > 0 {
+			t.Errorf("%s: unexpected errors %v", doc, errs)
+		}
+	}
+}
+
+func TestValidateDocumentErrors(t *testing.T) {
+	tests := []struct {
+		doc      string
+		expected []string
+	}{
+		{
+			`query Q { dog { name } } query Q { pet { name } }`,
+			[]string{`1:26: there can be only one operation named "Q"`},
+		},
+		{
+			`{ dog { name } } query Q { pet { name } }`,
+			[]string{`1:1: this anonymous operation must be the only defined operation`},
+		},
+		{
+			`mutation { dog { name } }`,
+			[]string{`1:1: schema is not configured for mutation operations`},
+		},
+		{
+			`mutation M($a: Int, $b: Unknown) { dog { ...f } } fragment f on Dog { name }`,
+			[]string{
+				`1:1: schema is not configured for mutation operations`,
+				`1:25: unknown type "Unknown"`,
+			},
+		},
+		{
+			`query Q($id: ID! @skip(if: true) @unknown) { human(id: $id) { name } }`,
+			[]string{
+				`1:18: directive "@skip" may not be used on VARIABLE_DEFINITION`,
+				`1:34: unknown directive "@unknown"`,
+			},
+		},
+		{
+			`{ dog { name meows } }`,
+			[]string{`1:14: cannot query field "meows" on type "Dog"`},
+		},
+		{
+			`{ catOrDog { name } }`,
+			[]string{`1:14: cannot query field "name" on type "CatOrDog"`},
+		},
+		{
+			`{ dog { name { length } } }`,
+			[]string{`1:14: field "name" must not have a selection since type "String" has no subfields`},
+		},
+		{
+			`{ dog }`,
+			[]string{`1:3: field "dog" of type "Dog" must have a selection of subfields`},
+		},
+		{
+			`{ dog { doesKnowCommand } }`,
+			[]string{`1:9: field "doesKnowCommand" argument "command" of type "DogCommand!" is required, but it was not provided`},
+		},
+		{
+			`{ dog { doesKnowCommand(command: SIT, command: HEEL, times: 1) } }`,
+			[]string{
+				`1:39: there can be only one argument named "command"`,
+				`1:54: unknown argument "times" on field "doesKnowCommand"`,
+			},
+		},
+		{
+			`{ dog { doesKnowCommand(command: JUMP) } human(id: 1.5) { name } score(ratio: "1") }`,
+			[]string{
+				`1:34: value "JUMP" does not exist in "DogCommand" enum`,
+				`1:52: expected value of type "ID", found 1.5`,
+				`1:79: expected value of type "Float", found "1"`,
+			},
+		},
+		{
+			`{ pets(filter: {limit: 2147483648, other: 1}) { name } }`,
+			[]string{
+				`1:24: expected value of type "Int", found 2147483648`,
+				`1:36: field "other" is not defined by type "Filter"`,
+				`1:16: field "Filter.name" of required type "String!" was not provided`,
+			},
+		},
+		{
+			`{ human(id: null) { name } }`,
+			[]string{`1:13: expected value of type "ID!", found null`},
+		},
+		{
+			`{ dog { name @skip(if: true) @skip(if: false) @defer } }`,
+			[]string{
+				`1:30: the directive "@skip" can only be used once at this location`,
+				`1:47: unknown directive "@defer"`,
+			},
+		},
+		{
+			`{ dog { ... @trace(level: "1") { name } } }`,
+			[]string{
+				`1:13: directive "@trace" may not be used on INLINE_FRAGMENT`,
+				`1:27: expected value of type "Int", found "1"`,
+			},
+		},
+		{
+			`{ dog { ...undefined } }`,
+			[]string{`1:9: unknown fragment "undefined"`},
+		},
+		{
+			`{ dog { name } } fragment unused on Dog { name }`,
+			[]string{`1:18: fragment "unused" is never used`},
+		},
+		{
+			`{ dog { ...f } } fragment f on Dog { name } fragment f on Dog { barks }`,
+			[]string{`1:45: there can be only one fragment named "f"`},
+		},
+		{
+			`{ dog { ...f } } fragment f on Missing { name } fragment g on DogCommand { name }`,
+			[]string{
+				`1:32: unknown type "Missing"`,
+				`1:63: fragment "g" cannot condition on non composite type "DogCommand"`,
+				`1:49: fragment "g" is never used`,
+			},
+		},
+		{
+			`{ dog { ...a } } fragment a on Dog { ...b } fragment b on Dog { ...a }`,
+			[]string{`1:65: cannot spread fragment "a" within itself via "b"`},
+		},
+		{
+			`{ dog { ...catFields ... on Cat { meows } } } fragment catFields on Cat { meows }`,
+			[]string{
+				`1:9: fragment "catFields" cannot be spread here as objects of type "Dog" can never be of type "Cat"`,
+				`1:22: fragment cannot be spread here as objects of type "Dog" can never be of type "Cat"`,
+			},
+		},
+		{
+			`{ dog { name: barks name } }`,
+			[]string{`1:21: fields "name" conflict because "barks" and "name" are different fields`},
+		},
+		{
+			`{ human(id: 1) { name(surname: true) name } }`,
+			[]string{`1:38: fields "name" conflict because they have differing arguments`},
+		},
+		{
+			`{ pet { ... on Dog { name: barks } ... on Cat { name } } }`,
+			[]string{`1:49: fields "name" conflict because they return conflicting types "Boolean" and "String"`},
+		},
+		{
+			`query Q($a: Int, $a: Int, $b: Dog, $c: Unknown) { score(ratio: $a) }`,
+			[]string{
+				`1:18: there can be only one variable named "a"`,
+				`1:31: variable "$b" cannot be non-input type "Dog"`,
+				`1:40: unknown type "Unknown"`,
+				`1:64: variable "$a" of type "Int" used in position expecting type "Float"`,
+				`1:27: variable "$b" is never used in operation "Q"`,
+				`1:36: variable "$c" is never used in operation "Q"`,
+			},
+		},
+		{
+			`query Q($f: Float = "a") { score(ratio: $f) }`,
+			[]string{`1:21: expected value of type "Float", found "a"`},
+		},
+		{
+			`query Q { dog { ...f } } fragment f on Dog { doesKnowCommand(command: $cmd) }`,
+			[]string{`1:71: variable "$cmd" is not defined by operation "Q"`},
+		},
+		{
+			`query Q($cmd: DogCommand, $id: String) { dog { doesKnowCommand(command: $cmd) } human(id: $id) { name } }`,
+			[]string{
+				`1:73: variable "$cmd" of type "DogCommand" used in position expecting type "DogCommand!"`,
+				`1:91: variable "$id" of type "String" used in position expecting type "ID!"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var found []string
+		for _, err := range validate(t, test.doc) {
+			found = append(found, err.Error())
+		}
+		if len(found) != len(test.expected) {
+			t.Errorf("%s: expected %q, found %q", test.doc, test.expected, found)
+			continue
+		}
+		for i := range found {
+			if found[i] != test.expected[i] {
+				t.Errorf("%s: expected %q, found %q", test.doc, test.expected[i], found[i])
+			}
+		}
+	}
+}
+
+func TestValidateNestedFragmentsCanMerge(t *testing.T) {
+	// comparing the fields of every level again for each pair of fields above
+	// it would take exponential time in the depth of the fragments
+	doc := `{ dog { ...F0 } }`
+	for i := 0; i < 30; i++ {
+		next := fmt.Sprintf("...F%d", i+1)
+		if i == 29 {
+			next = "name"
+		}
+		doc += fmt.Sprintf(` fragment F%d on Dog { owner { pets { %s } pets { %s } } owner { pets { %s } pets { %s } } }`,
+			i, next, next, next, next)
+	}
+	if errs := validate(t, doc); len(errs) > 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+
+	doc = `{ dog { ...F0 } } fragment F0 on Dog { owner { pets { ...F1 } pets { ...F1 } } }
+fragment F1 on Dog { owner { name } owner { name: pets { name } } }`
+	errs := validate(t, doc)
+	if len(errs) != 1 {
+		t.Fatalf("expecting 1 error, found %v", errs)
+	}
+	assertEqual(t, `2:45: fields "name" conflict because "name" and "pets" are different fields`, errs[0].Error())
+}
+
+func TestExecuteInvalidDocument(t *testing.T) {
+	runtime := newRuntime(humanSchema())
+	rsp := execute(t, runtime, `{ hero { name age } }`, "", nil)
+
+	if rsp.Data != nil {
+		t.Errorf("expecting no data, found %v", rsp.Data)
+	}
+	if len(rsp.Errors) != 1 {
+		t.Fatalf("expecting 1 error, found %v", rsp.Errors)
+	}
+	assertEqual(t, `1:15: cannot query field "age" on type "Human"`, rsp.Errors[0].Error())
+}
+
+func TestValidateSchema(t *testing.T) {
+	if _, err := NewRuntime(humanSchema()); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewRuntime(buildSchema(t, validateSDL)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateSchemaErrors(t *testing.T) {
+	node := &Interface{
+		Name: "Node",
+		Fields: []*Field{
+			{Name: "id", Typ: &NonNull{OfType: ID}, Defs: []*ArgDef{{Name: "format", Typ: String}}},
+			{Name: "parent", Typ: &List{OfType: String}},
+		},
+	}
+	filter := &InputObject{Name: "Filter"}
+	filter.Fields = []*Field{
+		{Name: "self", Typ: &NonNull{OfType: filter}, Deprecated: DefaultDeprecationReason},
+		{Name: "node", Typ: node},
+	}
+	item := &Object{
+		Name:   "Item",
+		Ifaces: []*Interface{node},
+		Fields: []*Field{
+			{Name: "id", Typ: ID, Defs: []*ArgDef{{Name: "strict", Typ: &NonNull{OfType: Boolean}}}},
+			{Name: "__secret", Typ: String},
+			{Name: "search", Typ: filter, Defs: []*ArgDef{{Name: "by", Typ: node}, {Name: "filter", Typ: filter}}},
+		},
+	}
+	color := &Enum{Name: "Color", Values: []*EnumValue{{Name: "RED"}, {Name: "RED"}, {Name: "null"}}}
+	union := &Union{Name: "Any", Typs: []Type{item, color}}
+	query := &Object{
+		Name: "Query",
+		Fields: []*Field{
+			{Name: "item", Typ: item},
+			{Name: "any", Typ: union},
+			{Name: "color", Typ: &Object{Name: "Color"}},
+		},
+	}
+
+	_, err := NewRuntime(&Schema{Qry: query, Typs: []Type{color}})
+	errs, ok := err.(ErrBadSchema)
+	if !ok {
+		t.Fatalf("expecting ErrBadSchema, found %v", err)
+	}
+
+	expected := []string{
+		"schema must contain unique named types but contains multiple types named Color",
+		"union Any can only include object types, found Color",
+		"enum Color has multiple values named RED",
+		"enum Color cannot include value null",
+		"required input field Filter.self cannot be deprecated",
+		"field Filter.node must be input type, found Node",
+		"input object Filter cannot reference itself through required fields self",
+		"name Item.__secret must not begin with \"__\", which is reserved by introspection",
+		"field Item.search must be output type, found Filter",
+		"argument Item.search(by:) must be input type, found Node",
+		"field Item.id of interface Node expects type ID!, found ID",
+		"field Item.id has no argument format of interface field",
+		"object Item has no field parent of interface Node",
+	}
+	var found []string
+	for _, err := range errs {
+		found = append(found, err.Error())
+	}
+	assertEqual(t, expected, found)
+	assertEqual(t, expected[0]+" (and 12 more errors)", err.Error())
+
+	if _, err := NewRuntime(&Schema{}); err == nil {
+		t.Error("expecting error for schema without query type")
+	}
+
+	_, err = NewRuntime(&Schema{Qry: query, Typs: []Type{color}, Directives: []*Directive{
+		{Name: "include"},
+		{Name: "auth", Locs: []string{"FIELD", "NOWHERE"}, Defs: []*ArgDef{{Name: "role", Typ: item}, {Name: "role", Typ: String}}},
+	}})
+	found = nil
+	for _, err := range err.(ErrBadSchema)[len(expected):] {
+		found = append(found, err.Error())
+	}
+	assertEqual(t, []string{
+		"schema must contain unique directives but contains multiple directives named @include",
+		"directive @include must define one or more locations",
+		"directive @auth has unknown location NOWHERE",
+		"argument @auth(role:) must be input type, found Item",
+		"directive @auth has multiple arguments named role",
+	}, found)
+}
+
+func TestValidateInterfaceImplementations(t *testing.T) {
+	schema := buildSchema(t, `
+interface Node { id: ID! }
+interface Connection implements Node { id: ID! edges: [Edge] }
+interface Edge { node: Node }
+type FriendEdge implements Edge { node: User }
+type FriendConnection implements Connection & Node { id: ID! edges: [FriendEdge] }
+type User implements Node { id: ID! friends: FriendConnection }
+type Query { user: User connection: Connection }
+`)
+	runtime, err := NewRuntime(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection := runtime.Ifaces["Connection"]
+	assertEqual(t, "Node", connection.Ifaces[0].Name)
+	assertEqual(t, []*Object{runtime.Objects["FriendConnection"]}, runtime.possibleTypes(connection))
+
+	node := &Interface{Name: "Node", Fields: []*Field{{Name: "id", Typ: ID}}}
+	resource := &Interface{Name: "Resource", Ifaces: []*Interface{node}}
+	node.Ifaces = []*Interface{resource}
+	resource.Fields = []*Field{{Name: "id", Typ: ID}}
+	image := &Object{Name: "Image", Ifaces: []*Interface{resource}, Fields: []*Field{{Name: "id", Typ: ID}}}
+	query := &Object{Name: "Query", Fields: []*Field{{Name: "image", Typ: image}}}
+
+	_, err = NewRuntime(&Schema{Qry: query})
+	errs, ok := err.(ErrBadSchema)
+	if !ok {
+		t.Fatalf("expecting ErrBadSchema, found %v", err)
+	}
+	expected := []string{
+		"object Image must implement interface Node, which is implemented by Resource",
+		"interface Node cannot implement itself through Resource",
+		"interface Resource cannot implement itself through Node",
+	}
+	var found []string
+	for _, err := range errs {
+		found = append(found, err.Error())
+	}
+	assertEqual(t, expected, found)
+}